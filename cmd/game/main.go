@@ -12,11 +12,13 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/config"
 	"github.com/mquan1409/game-api/internal/handlers"
+	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
 	"github.com/mquan1409/game-api/internal/services"
 )
 
 var gameHandler handlers.GameHandler
+var seasonHandler handlers.SeasonHandler
 
 func init() {
 	// Load configuration based on environment
@@ -37,10 +39,18 @@ func init() {
 	// Initialize repository
 	gameRepository := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
 	leaderboardRepository := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
-	gameService := services.NewGameServiceImpl(gameRepository, leaderboardRepository)
+	userRepository := repositories.NewDynamoDBUserRepository(db, cfg.TableName)
+	gameStatRepository := repositories.NewDynamoDBGameStatRepository(db, cfg.TableName)
+	seasonRepository := repositories.NewDynamoDBSeasonRepository(db, cfg.TableName)
+	matchRepository := repositories.NewDynamoDBMatchRepository(db, cfg.TableName)
+	ratingRepository := repositories.NewDynamoDBRatingRepository(db, cfg.TableName)
+	transactionRepository := repositories.NewDynamoDBTransactionRepository(db)
+	gameService := services.NewGameServiceImpl(gameRepository, leaderboardRepository, userRepository, gameStatRepository, matchRepository, ratingRepository, transactionRepository)
+	seasonService := services.NewSeasonServiceImpl(seasonRepository, gameRepository, gameStatRepository, leaderboardRepository, matchRepository)
 
 	// Initialize handler
 	gameHandler = handlers.NewGameHandlerImpl(gameService)
+	seasonHandler = handlers.NewSeasonHandlerImpl(seasonService)
 }
 
 func router(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -49,39 +59,61 @@ func router(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 
 	switch req.HTTPMethod {
 	case "GET":
-		if len(pathParts) == 2 && pathParts[0] == "games" {
+		if len(pathParts) == 1 && pathParts[0] == "games" {
+			// GET /games?description={description}&rankedAttribute={attribute}&limit={limit}&cursor={cursor}
+			return gameHandler.GetGames(req)
+		} else if len(pathParts) == 2 && pathParts[0] == "games" {
 			// GET /games/{id}
 			return gameHandler.GetGame(req)
 		} else if len(pathParts) == 4 && pathParts[0] == "games" && pathParts[2] == "leaderboard" {
-			// GET /games/{gameId}/leaderboard/{attribute}
-			if limit, ok := req.QueryStringParameters["limit"]; ok {
-				// GET /games/{gameId}/leaderboard/{attribute}?limit={limit}
-				req.QueryStringParameters["limit"] = limit
-				return gameHandler.GetBoundedLeaderboard(req)
-			}
-			return gameHandler.GetLeaderboard(req)
+			// GET /games/{gameId}/leaderboard/{attribute}?limit={limit}&cursor={cursor}
+			return gameHandler.GetBoundedLeaderboard(req)
+		} else if len(pathParts) == 6 && pathParts[0] == "games" && pathParts[2] == "leaderboard" && pathParts[4] == "users" {
+			// GET /games/{gameId}/leaderboard/{attribute}/users/{userId}?window={window}
+			return gameHandler.GetLeaderboardAroundUser(req)
+		} else if len(pathParts) == 3 && pathParts[0] == "games" && pathParts[2] == "seasons" {
+			// GET /games/{gameId}/seasons
+			return seasonHandler.GetSeasonsByGame(req)
+		} else if len(pathParts) == 4 && pathParts[0] == "games" && pathParts[2] == "seasons" {
+			// GET /games/{gameId}/seasons/{seasonId}
+			return seasonHandler.GetSeason(req)
 		}
 	case "POST":
 		if len(pathParts) == 1 && pathParts[0] == "games" {
 			// POST /games
 			return gameHandler.CreateGame(req)
+		} else if len(pathParts) == 3 && pathParts[0] == "games" && pathParts[2] == "seasons" {
+			// POST /games/{gameId}/seasons
+			return seasonHandler.CreateSeason(req)
+		} else if len(pathParts) == 3 && pathParts[0] == "games" && pathParts[2] == "archive" {
+			// POST /games/{gameId}/archive
+			return gameHandler.ArchiveGame(req)
+		} else if len(pathParts) == 3 && pathParts[0] == "games" && pathParts[2] == "unarchive" {
+			// POST /games/{gameId}/unarchive
+			return gameHandler.UnarchiveGame(req)
+		} else if len(pathParts) == 5 && pathParts[0] == "games" && pathParts[2] == "seasons" && pathParts[4] == "close" {
+			// POST /games/{gameId}/seasons/{seasonId}/close
+			return seasonHandler.CloseSeason(req)
 		}
 	case "PUT":
 		if len(pathParts) == 2 && pathParts[0] == "games" {
 			// PUT /games/{id}
 			return gameHandler.UpdateGame(req)
+		} else if len(pathParts) == 4 && pathParts[0] == "games" && pathParts[2] == "seasons" {
+			// PUT /games/{gameId}/seasons/{seasonId}
+			return seasonHandler.UpdateSeason(req)
 		}
 	case "DELETE":
 		if len(pathParts) == 2 && pathParts[0] == "games" {
 			// DELETE /games/{id}
 			return gameHandler.DeleteGame(req)
+		} else if len(pathParts) == 4 && pathParts[0] == "games" && pathParts[2] == "seasons" {
+			// DELETE /games/{gameId}/seasons/{seasonId}
+			return seasonHandler.DeleteSeason(req)
 		}
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 404,
-		Body:       "Not Found",
-	}, nil
+	return handlers.ErrorResponse(models.NewNotFoundError("no route for %s %s", req.HTTPMethod, req.Path)), nil
 }
 
 func main() {
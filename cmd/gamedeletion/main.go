@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/config"
+	"github.com/mquan1409/game-api/internal/repositories"
+	"github.com/mquan1409/game-api/internal/services"
+)
+
+// deadlineMargin is the time left before the Lambda timeout when the worker
+// stops starting new pages of work, enough to finish the page under way.
+const deadlineMargin = 30 * time.Second
+
+// defaultRunTime bounds a run invoked without a deadline.
+const defaultRunTime = 5 * time.Minute
+
+var gameDeletionService services.GameDeletionService
+
+func init() {
+	// Load configuration based on environment
+	env := os.Getenv("APP_ENV")
+	cfg := config.LoadConfig(env)
+
+	// Create the session
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint: aws.String(cfg.DynamoDBEndpoint),
+		Region:   aws.String(cfg.DynamoDBRegion),
+	})
+	if err != nil {
+		fmt.Println("Error creating session:", err)
+		return
+	}
+	db := dynamodb.New(sess)
+
+	// Initialize repository
+	gameRepository := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
+	matchRepository := repositories.NewDynamoDBMatchRepository(db, cfg.TableName)
+	userRepository := repositories.NewDynamoDBUserRepository(db, cfg.TableName)
+	gameStatRepository := repositories.NewDynamoDBGameStatRepository(db, cfg.TableName)
+	leaderboardRepository := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
+	seasonRepository := repositories.NewDynamoDBSeasonRepository(db, cfg.TableName)
+	ratingRepository := repositories.NewDynamoDBRatingRepository(db, cfg.TableName)
+	transactionRepository := repositories.NewDynamoDBTransactionRepository(db)
+	gameDeletionService = services.NewGameDeletionServiceImpl(gameRepository, matchRepository, userRepository, gameStatRepository, leaderboardRepository, seasonRepository, ratingRepository, transactionRepository)
+}
+
+// handler runs on a schedule and moves the cascade delete of every game being
+// deleted forward until shortly before the invocation times out. Each run
+// resumes where the previous one stopped.
+func handler(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultRunTime)
+	}
+
+	deleted, err := gameDeletionService.DeletePendingGames(deadline.Add(-deadlineMargin))
+	for _, gameID := range deleted {
+		fmt.Println("Deleted game:", gameID)
+	}
+	return err
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/config"
 	"github.com/mquan1409/game-api/internal/handlers"
+	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
 	"github.com/mquan1409/game-api/internal/services"
 )
@@ -37,9 +38,13 @@ func init() {
 	// Initialize repository
 	matchRepository := repositories.NewDynamoDBMatchRepository(db, cfg.TableName)
 	gameRepository := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
+	userRepository := repositories.NewDynamoDBUserRepository(db, cfg.TableName)
 	gameStatRepository := repositories.NewDynamoDBGameStatRepository(db, cfg.TableName)
 	leaderboardRepository := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
-	matchService := services.NewMatchServiceImpl(matchRepository, gameRepository, gameStatRepository, leaderboardRepository)
+	seasonRepository := repositories.NewDynamoDBSeasonRepository(db, cfg.TableName)
+	ratingRepository := repositories.NewDynamoDBRatingRepository(db, cfg.TableName)
+	transactionRepository := repositories.NewDynamoDBTransactionRepository(db)
+	matchService := services.NewMatchServiceImpl(matchRepository, gameRepository, userRepository, gameStatRepository, leaderboardRepository, seasonRepository, ratingRepository, transactionRepository)
 
 	// Initialize handler
 	matchHandler = handlers.NewMatchHandlerImpl(matchService)
@@ -55,7 +60,11 @@ func router(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 			// GET /matches/{gameId}/{matchId}/{dateId}
 			return matchHandler.GetMatch(req)
 		} else if len(pathParts) == 1 && pathParts[0] == "matches" {
-			// GET /matches?game={gameId}&date={dateId}
+			if _, hasDate := req.QueryStringParameters["dateId"]; !hasDate {
+				// GET /matches?gameId={gameId}&from={from}&to={to}&limit={limit}&cursor={cursor}
+				return matchHandler.GetMatchesByGameAndDateRange(req)
+			}
+			// GET /matches?gameId={gameId}&dateId={dateId}
 			return matchHandler.GetMatchesByGameAndDate(req)
 		}
 	case "POST":
@@ -75,10 +84,7 @@ func router(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 		}
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 404,
-		Body:       "Not Found",
-	}, nil
+	return handlers.ErrorResponse(models.NewNotFoundError("no route for %s %s", req.HTTPMethod, req.Path)), nil
 }
 
 func main() {
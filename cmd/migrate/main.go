@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/config"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+// migrate rewrites leaderboard items stored with the legacy zero-padded sort
+// key to the order-preserving encoding used by the repositories, writes the
+// match history entries of matches stored before the history was kept,
+// rebuilds every user's GamesPlayed from their history, stores the
+// lower-cased description of games written before the description filter
+// ignored case, and folds the legacy Attributes map of GameStats into their
+// top-level totals.
+func main() {
+	// Load configuration based on environment
+	env := os.Getenv("APP_ENV")
+	cfg := config.LoadConfig(env)
+
+	// Create the session
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint: aws.String(cfg.DynamoDBEndpoint),
+		Region:   aws.String(cfg.DynamoDBRegion),
+	})
+	if err != nil {
+		fmt.Println("Error creating session:", err)
+		os.Exit(1)
+	}
+	db := dynamodb.New(sess)
+
+	migrated, err := repositories.MigrateLeaderboardRangeKeys(db, cfg.TableName)
+	if err != nil {
+		fmt.Println("Error migrating leaderboard keys:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Migrated leaderboard items:", migrated)
+
+	backfilled, err := repositories.BackfillUserMatches(db, cfg.TableName)
+	if err != nil {
+		fmt.Println("Error backfilling match history:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Backfilled match history entries:", backfilled)
+
+	rebuilt, err := repositories.RebuildGamesPlayed(db, cfg.TableName)
+	if err != nil {
+		fmt.Println("Error rebuilding games played:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Rebuilt games played of users:", rebuilt)
+
+	described, err := repositories.BackfillGameDescriptions(db, cfg.TableName)
+	if err != nil {
+		fmt.Println("Error backfilling game descriptions:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Backfilled game descriptions:", described)
+
+	folded, err := repositories.FoldLegacyGameStatAttributes(db, cfg.TableName)
+	if err != nil {
+		fmt.Println("Error folding legacy game stat attributes:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Folded legacy game stats:", folded)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/config"
+	"github.com/mquan1409/game-api/internal/repositories"
+	"github.com/mquan1409/game-api/internal/services"
+)
+
+// deadlineMargin is the time left before the Lambda timeout when the worker
+// stops starting new recomputes, enough to finish the one under way.
+const deadlineMargin = 30 * time.Second
+
+// defaultRunTime bounds a run invoked without a deadline.
+const defaultRunTime = 5 * time.Minute
+
+var ratingRecomputeService services.RatingRecomputeService
+
+func init() {
+	// Load configuration based on environment
+	env := os.Getenv("APP_ENV")
+	cfg := config.LoadConfig(env)
+
+	// Create the session
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint: aws.String(cfg.DynamoDBEndpoint),
+		Region:   aws.String(cfg.DynamoDBRegion),
+	})
+	if err != nil {
+		fmt.Println("Error creating session:", err)
+		return
+	}
+	db := dynamodb.New(sess)
+
+	// Initialize repository
+	gameRepository := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
+	matchRepository := repositories.NewDynamoDBMatchRepository(db, cfg.TableName)
+	leaderboardRepository := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
+	ratingRepository := repositories.NewDynamoDBRatingRepository(db, cfg.TableName)
+	transactionRepository := repositories.NewDynamoDBTransactionRepository(db)
+	ratingRecomputeService = services.NewRatingRecomputeServiceImpl(gameRepository, matchRepository, leaderboardRepository, ratingRepository, transactionRepository)
+}
+
+// handler runs on a schedule and recomputes the ratings of every game with a
+// pending recompute until shortly before the invocation times out. Games it
+// doesn't reach are left to the next run.
+func handler(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultRunTime)
+	}
+
+	recomputed, err := ratingRecomputeService.RecomputePendingRatings(deadline.Add(-deadlineMargin))
+	for _, gameID := range recomputed {
+		fmt.Println("Recomputed ratings of game:", gameID)
+	}
+	return err
+}
+
+func main() {
+	lambda.Start(handler)
+}
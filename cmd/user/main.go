@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/config"
 	"github.com/mquan1409/game-api/internal/handlers"
+	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
 	"github.com/mquan1409/game-api/internal/services"
 )
@@ -40,7 +41,13 @@ func init() {
 	// Initialize repository
 	userRepository := repositories.NewDynamoDBUserRepository(db, cfg.TableName)
 	gameStatRepository := repositories.NewDynamoDBGameStatRepository(db, cfg.TableName)
-	userService := services.NewUserServiceImpl(userRepository, gameStatRepository)
+	matchRepository := repositories.NewDynamoDBMatchRepository(db, cfg.TableName)
+	gameRepository := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
+	leaderboardRepository := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
+	seasonRepository := repositories.NewDynamoDBSeasonRepository(db, cfg.TableName)
+	ratingRepository := repositories.NewDynamoDBRatingRepository(db, cfg.TableName)
+	transactionRepository := repositories.NewDynamoDBTransactionRepository(db)
+	userService := services.NewUserServiceImpl(userRepository, gameStatRepository, matchRepository, gameRepository, leaderboardRepository, seasonRepository, ratingRepository, transactionRepository)
 
 	// Initialize handler
 	userHandler = handlers.NewUserHandlerImpl(userService)
@@ -61,6 +68,12 @@ func router(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 		} else if len(pathParts) == 5 && pathParts[0] == "users" && pathParts[2] == "games" && pathParts[4] == "stats" {
 			// GET /users/{userId}/games/{gameId}/stats
 			return userHandler.GetGameStat(req)
+		} else if len(pathParts) == 3 && pathParts[0] == "users" && pathParts[2] == "stats" {
+			// GET /users/{userId}/stats?attributes={attr},{attr}
+			return userHandler.GetGameStats(req)
+		} else if len(pathParts) == 3 && pathParts[0] == "users" && pathParts[2] == "matches" {
+			// GET /users/{userId}/matches?gameId={gameId}&from={from}&to={to}&cursor={cursor}
+			return userHandler.GetUserMatches(req)
 		}
 	case "POST":
 		if len(pathParts) == 1 && pathParts[0] == "users" {
@@ -79,10 +92,7 @@ func router(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 		}
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: 404,
-		Body:       "Not Found",
-	}, nil
+	return handlers.ErrorResponse(models.NewNotFoundError("no route for %s %s", req.HTTPMethod, req.Path)), nil
 }
 
 func main() {
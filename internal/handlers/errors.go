@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// errorBody is the JSON body of every error response.
+type errorBody struct {
+	Code     string   `json:"Code"`
+	Message  string   `json:"Message"`
+	Problems []string `json:"Problems,omitempty"`
+}
+
+// errorStatuses maps each kind of models error to its HTTP status and code.
+var errorStatuses = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{models.ErrNotFound, http.StatusNotFound, "not_found"},
+	{models.ErrConflict, http.StatusConflict, "conflict"},
+	{models.ErrValidation, http.StatusBadRequest, "validation"},
+	{models.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{models.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+}
+
+// ErrorResponse answers with the status matching the kind of err: 404, 409,
+// 400, 403 or 412 for not found, conflict, validation, forbidden and
+// precondition failed errors, and 500 for any other error. The problems of a
+// validation error are listed in the body as well.
+func ErrorResponse(err error) events.APIGatewayProxyResponse {
+	status, code := http.StatusInternalServerError, "internal"
+	for _, errorStatus := range errorStatuses {
+		if errors.Is(err, errorStatus.kind) {
+			status, code = errorStatus.status, errorStatus.code
+			break
+		}
+	}
+
+	body, _ := json.Marshal(errorBody{Code: code, Message: err.Error(), Problems: models.ErrorProblems(err)})
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
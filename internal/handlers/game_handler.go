@@ -6,9 +6,12 @@ import (
 
 type GameHandler interface {
 	GetGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
-	GetLeaderboard(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	GetGames(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	GetBoundedLeaderboard(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	GetLeaderboardAroundUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	CreateGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	UpdateGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	ArchiveGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	UnarchiveGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	DeleteGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 }
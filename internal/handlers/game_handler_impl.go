@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -24,45 +25,90 @@ func (h *GameHandlerImpl) GetGame(event events.APIGatewayProxyRequest) (events.A
 	gameID := models.GameID(event.PathParameters["gameId"])
 	game, err := h.gameService.GetGame(gameID)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	gameJSON, err := json.Marshal(game)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal game data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal game data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
+		Headers:    etagHeaders(game.Version),
 		Body:       string(gameJSON),
 	}, nil
 }
 
-func (h *GameHandlerImpl) GetLeaderboard(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+const defaultGamePageSize = 20
+
+func (h *GameHandlerImpl) GetGames(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	cursor := event.QueryStringParameters["cursor"]
+	filter := models.GameFilter{
+		Description:     event.QueryStringParameters["description"],
+		RankedAttribute: models.AttributeName(event.QueryStringParameters["rankedAttribute"]),
+		Status:          models.GameStatus(event.QueryStringParameters["status"]),
+	}
+	if filter.Status != models.GameStatusActive && filter.Status != models.GameStatusArchived && filter.Status != models.GameStatusDeleting {
+		return ErrorResponse(models.NewValidationError("status must be archived or deleting")), nil
+	}
+
+	limit := defaultGamePageSize
+	if limitParam, ok := event.QueryStringParameters["limit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return ErrorResponse(models.NewValidationError("limit must be a positive integer")), nil
+		}
+	}
+
+	page, err := h.gameService.GetGames(filter, limit, cursor)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	pageJSON, err := json.Marshal(page)
+	if err != nil {
+		return ErrorResponse(fmt.Errorf("failed to marshal game data: %w", err)), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(pageJSON),
+	}, nil
+}
+
+// defaultLeaderboardPageSize is the page size when no limit is given.
+const defaultLeaderboardPageSize = 100
+
+func (h *GameHandlerImpl) GetBoundedLeaderboard(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	gameID := models.GameID(event.PathParameters["gameId"])
 	attribute := models.AttributeName(event.PathParameters["attribute"])
-	var leaderboard *models.LeaderBoard
-	var err error
-	leaderboard, err = h.gameService.GetGameLeaderboard(gameID, attribute)
+	cursor := event.QueryStringParameters["cursor"]
+
+	limit := defaultLeaderboardPageSize
+	if limitParam, ok := event.QueryStringParameters["limit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return ErrorResponse(models.NewValidationError("limit must be a positive integer")), nil
+		}
+	}
+
+	scope, err := leaderboardScope(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
+	}
+
+	var leaderboard *models.BoundedLeaderboard
+	leaderboard, err = h.gameService.GetBoundedGameLeaderboard(gameID, attribute, scope, limit, cursor)
+	if err != nil {
+		return ErrorResponse(err), nil
 	}
 
 	leaderboardJSON, err := json.Marshal(leaderboard)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal leaderboard data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal leaderboard data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
@@ -71,26 +117,40 @@ func (h *GameHandlerImpl) GetLeaderboard(event events.APIGatewayProxyRequest) (e
 	}, nil
 }
 
-func (h *GameHandlerImpl) GetBoundedLeaderboard(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// defaultLeaderboardWindow is the number of neighbours shown on each side of
+// the user when no window is given; maxLeaderboardWindow caps larger requests.
+const (
+	defaultLeaderboardWindow = 5
+	maxLeaderboardWindow     = 50
+)
+
+func (h *GameHandlerImpl) GetLeaderboardAroundUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	gameID := models.GameID(event.PathParameters["gameId"])
 	attribute := models.AttributeName(event.PathParameters["attribute"])
-	limit, _ := strconv.Atoi(event.QueryStringParameters["limit"])
-	var leaderboard *models.BoundedLeaderboard
-	var err error
-	leaderboard, err = h.gameService.GetBoundedGameLeaderboard(gameID, attribute, limit)
+	userID := models.UserID(event.PathParameters["userId"])
+
+	window := defaultLeaderboardWindow
+	if windowParam, ok := event.QueryStringParameters["window"]; ok {
+		var err error
+		window, err = strconv.Atoi(windowParam)
+		if err != nil || window < 0 || window > maxLeaderboardWindow {
+			return ErrorResponse(models.NewValidationError("window must be an integer between 0 and %d", maxLeaderboardWindow)), nil
+		}
+	}
+
+	scope, err := leaderboardScope(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
+	}
+
+	leaderboard, err := h.gameService.GetLeaderboardAroundUser(gameID, attribute, scope, userID, window)
+	if err != nil {
+		return ErrorResponse(err), nil
 	}
 
 	leaderboardJSON, err := json.Marshal(leaderboard)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal leaderboard data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal leaderboard data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
@@ -98,84 +158,122 @@ func (h *GameHandlerImpl) GetBoundedLeaderboard(event events.APIGatewayProxyRequ
 		Body:       string(leaderboardJSON),
 	}, nil
 }
+
 func (h *GameHandlerImpl) CreateGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var game models.Game
-	err := json.Unmarshal([]byte(event.Body), &game)
+	game, err := parseGame(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       "Invalid request body",
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
-	createdGame, err := h.gameService.CreateGame(&game)
+	createdGame, err := h.gameService.CreateGame(game)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	createdGameJSON, err := json.Marshal(createdGame)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal created game data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal created game data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusCreated,
+		Headers:    etagHeaders(createdGame.Version),
 		Body:       string(createdGameJSON),
 	}, nil
 }
 
 func (h *GameHandlerImpl) UpdateGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var game models.Game
-	err := json.Unmarshal([]byte(event.Body), &game)
+	game, err := parseGame(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       "Invalid request body",
-		}, nil
+		return ErrorResponse(err), nil
+	}
+	if game.Version, err = ifMatchVersion(event); err != nil {
+		return ErrorResponse(err), nil
 	}
 
-	game.GameID = models.GameID(event.PathParameters["gameId"])
-
-	updatedGame, err := h.gameService.UpdateGame(&game)
+	updatedGame, err := h.gameService.UpdateGame(game)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	updatedGameJSON, err := json.Marshal(updatedGame)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal updated game data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal updated game data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
+		Headers:    etagHeaders(updatedGame.Version),
 		Body:       string(updatedGameJSON),
 	}, nil
 }
 
+func (h *GameHandlerImpl) ArchiveGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	gameID := models.GameID(event.PathParameters["gameId"])
+
+	archivedGame, err := h.gameService.ArchiveGame(gameID)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	return gameResponse(archivedGame, http.StatusOK)
+}
+
+func (h *GameHandlerImpl) UnarchiveGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	gameID := models.GameID(event.PathParameters["gameId"])
+
+	unarchivedGame, err := h.gameService.UnarchiveGame(gameID)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	return gameResponse(unarchivedGame, http.StatusOK)
+}
+
+// DeleteGame answers 202 Accepted with the game being deleted, since the
+// cascade delete runs in the background.
 func (h *GameHandlerImpl) DeleteGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	gameID := models.GameID(event.PathParameters["gameId"])
 
-	err := h.gameService.DeleteGame(gameID)
+	deletingGame, err := h.gameService.DeleteGame(gameID)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
+	}
+
+	return gameResponse(deletingGame, http.StatusAccepted)
+}
+
+func gameResponse(game *models.Game, statusCode int) (events.APIGatewayProxyResponse, error) {
+	gameJSON, err := json.Marshal(game)
+	if err != nil {
+		return ErrorResponse(fmt.Errorf("failed to marshal game data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
+		StatusCode: statusCode,
+		Headers:    etagHeaders(game.Version),
+		Body:       string(gameJSON),
 	}, nil
-}
\ No newline at end of file
+}
+
+// parseGame builds a game from the request body, taking the game ID from the
+// path when present.
+func parseGame(event events.APIGatewayProxyRequest) (*models.Game, error) {
+	var body models.Game
+	if err := json.Unmarshal([]byte(event.Body), &body); err != nil {
+		return nil, models.NewValidationError("invalid request body: %v", err)
+	}
+
+	if gameID, ok := event.PathParameters["gameId"]; ok {
+		body.GameID = models.GameID(gameID)
+	}
+
+	game, err := models.NewGame(body.GameID, body.Description, body.Attributes, body.RankedAttributes)
+	if err != nil {
+		return nil, err
+	}
+	game.RatingSystem = body.RatingSystem
+	game.Aggregations = body.Aggregations
+	game.RankDirections = body.RankDirections
+	return game, nil
+}
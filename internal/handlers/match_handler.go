@@ -7,6 +7,7 @@ import (
 type MatchHandler interface {
 	GetMatch(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	GetMatchesByGameAndDate(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	GetMatchesByGameAndDateRange(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	CreateMatch(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	UpdateMatch(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	DeleteMatch(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/mquan1409/game-api/internal/models"
@@ -26,22 +28,17 @@ func (h *MatchHandlerImpl) GetMatch(event events.APIGatewayProxyRequest) (events
 
 	match, err := h.matchService.GetMatch(gameID, matchID, dateID)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	matchJSON, err := json.Marshal(match)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal match data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal match data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
+		Headers:    etagHeaders(match.Version),
 		Body:       string(matchJSON),
 	}, nil
 }
@@ -52,18 +49,12 @@ func (h *MatchHandlerImpl) GetMatchesByGameAndDate(event events.APIGatewayProxyR
 
 	matches, err := h.matchService.GetMatchesByGameAndDate(gameID, dateID)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	matchesJSON, err := json.Marshal(matches)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal matches data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal matches data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
@@ -72,66 +63,90 @@ func (h *MatchHandlerImpl) GetMatchesByGameAndDate(event events.APIGatewayProxyR
 	}, nil
 }
 
+const defaultMatchPageSize = 100
+
+func (h *MatchHandlerImpl) GetMatchesByGameAndDateRange(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	gameID := models.GameID(event.QueryStringParameters["gameId"])
+	cursor := event.QueryStringParameters["cursor"]
+	if gameID == "" {
+		return ErrorResponse(models.NewValidationError("gameId is required")), nil
+	}
+
+	limit := defaultMatchPageSize
+	if limitParam, ok := event.QueryStringParameters["limit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return ErrorResponse(models.NewValidationError("limit must be a positive integer")), nil
+		}
+	}
+
+	dates, err := models.NewDateRange(models.DateID(event.QueryStringParameters["from"]), models.DateID(event.QueryStringParameters["to"]))
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	page, err := h.matchService.GetMatchesByGameAndDateRange(gameID, dates, limit, cursor)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	pageJSON, err := json.Marshal(page)
+	if err != nil {
+		return ErrorResponse(fmt.Errorf("failed to marshal matches data: %w", err)), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(pageJSON),
+	}, nil
+}
+
 func (h *MatchHandlerImpl) CreateMatch(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var match models.Match
-	err := json.Unmarshal([]byte(event.Body), &match)
+	match, err := parseMatch(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       "Invalid request body",
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
-	createdMatch, err := h.matchService.CreateMatch(&match)
+	createdMatch, err := h.matchService.CreateMatch(match)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	createdMatchJSON, err := json.Marshal(createdMatch)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal created match data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal created match data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusCreated,
+		Headers:    etagHeaders(createdMatch.Version),
 		Body:       string(createdMatchJSON),
 	}, nil
 }
 
 func (h *MatchHandlerImpl) UpdateMatch(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var match models.Match
-	err := json.Unmarshal([]byte(event.Body), &match)
+	match, err := parseMatch(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       "Invalid request body",
-		}, nil
+		return ErrorResponse(err), nil
+	}
+	if match.Version, err = ifMatchVersion(event); err != nil {
+		return ErrorResponse(err), nil
 	}
 
-	updatedMatch, err := h.matchService.UpdateMatch(&match)
+	updatedMatch, err := h.matchService.UpdateMatch(match)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	updatedMatchJSON, err := json.Marshal(updatedMatch)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal updated match data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal updated match data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
+		Headers:    etagHeaders(updatedMatch.Version),
 		Body:       string(updatedMatchJSON),
 	}, nil
 }
@@ -143,13 +158,31 @@ func (h *MatchHandlerImpl) DeleteMatch(event events.APIGatewayProxyRequest) (eve
 
 	err := h.matchService.DeleteMatch(gameID, matchID, dateID)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusNoContent,
 	}, nil
-}
\ No newline at end of file
+}
+
+// parseMatch builds a match from the request body, taking its game, ID and
+// date from the path when present.
+func parseMatch(event events.APIGatewayProxyRequest) (*models.Match, error) {
+	var body models.Match
+	if err := json.Unmarshal([]byte(event.Body), &body); err != nil {
+		return nil, models.NewValidationError("invalid request body: %v", err)
+	}
+
+	if gameID, ok := event.PathParameters["gameId"]; ok {
+		body.GameID = models.GameID(gameID)
+	}
+	if matchID, ok := event.PathParameters["matchId"]; ok {
+		body.MatchID = models.MatchID(matchID)
+	}
+	if dateID, ok := event.PathParameters["dateId"]; ok {
+		body.DateID = models.DateID(dateID)
+	}
+
+	return models.NewMatch(body.MatchID, body.DateID, body.GameID, body.TeamNames, body.TeamScores, body.TeamMembers, body.PlayerAttributesMap)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// leaderboardScope reads the optional season, or period and at, query
+// parameters. Without either the all-time scope is used; at defaults to
+// today (UTC).
+func leaderboardScope(event events.APIGatewayProxyRequest) (models.LeaderboardScope, error) {
+	season, hasSeason := event.QueryStringParameters["season"]
+	periodParam, hasPeriod := event.QueryStringParameters["period"]
+	at, hasAt := event.QueryStringParameters["at"]
+
+	if hasSeason {
+		if hasPeriod || hasAt {
+			return "", models.NewValidationError("season cannot be combined with period or at")
+		}
+		if season == "" {
+			return "", models.NewValidationError("season cannot be empty")
+		}
+		return models.SeasonScope(models.SeasonID(season)), nil
+	}
+
+	if !hasPeriod {
+		if hasAt {
+			return "", models.NewValidationError("at requires a period")
+		}
+		return models.AllTimeScope, nil
+	}
+
+	period, err := models.ParsePeriod(periodParam)
+	if err != nil {
+		return "", err
+	}
+	if !hasAt {
+		at = time.Now().UTC().Format("2006-01-02")
+	}
+	return models.NewPeriodScope(period, models.DateID(at))
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type SeasonHandler interface {
+	GetSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	GetSeasonsByGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	CreateSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	UpdateSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	CloseSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	DeleteSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/services"
+)
+
+type SeasonHandlerImpl struct {
+	seasonService services.SeasonService
+}
+
+func NewSeasonHandlerImpl(seasonService services.SeasonService) SeasonHandler {
+	return &SeasonHandlerImpl{seasonService: seasonService}
+}
+
+func (h *SeasonHandlerImpl) GetSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	gameID := models.GameID(event.PathParameters["gameId"])
+	seasonID := models.SeasonID(event.PathParameters["seasonId"])
+
+	season, err := h.seasonService.GetSeason(gameID, seasonID)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	return seasonResponse(season, http.StatusOK)
+}
+
+func (h *SeasonHandlerImpl) GetSeasonsByGame(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	gameID := models.GameID(event.PathParameters["gameId"])
+
+	seasons, err := h.seasonService.GetSeasonsByGame(gameID)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	seasonsJSON, err := json.Marshal(seasons)
+	if err != nil {
+		return ErrorResponse(fmt.Errorf("failed to marshal seasons data: %w", err)), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(seasonsJSON),
+	}, nil
+}
+
+func (h *SeasonHandlerImpl) CreateSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	season, err := parseSeason(event)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	createdSeason, err := h.seasonService.CreateSeason(season)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	return seasonResponse(createdSeason, http.StatusCreated)
+}
+
+func (h *SeasonHandlerImpl) UpdateSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	season, err := parseSeason(event)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	updatedSeason, err := h.seasonService.UpdateSeason(season)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	return seasonResponse(updatedSeason, http.StatusOK)
+}
+
+func (h *SeasonHandlerImpl) CloseSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	gameID := models.GameID(event.PathParameters["gameId"])
+	seasonID := models.SeasonID(event.PathParameters["seasonId"])
+
+	closedSeason, err := h.seasonService.CloseSeason(gameID, seasonID)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	return seasonResponse(closedSeason, http.StatusOK)
+}
+
+func (h *SeasonHandlerImpl) DeleteSeason(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	gameID := models.GameID(event.PathParameters["gameId"])
+	seasonID := models.SeasonID(event.PathParameters["seasonId"])
+
+	err := h.seasonService.DeleteSeason(gameID, seasonID)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}, nil
+}
+
+// parseSeason builds a season from the request body, taking the game and,
+// when present, the season ID from the path.
+func parseSeason(event events.APIGatewayProxyRequest) (*models.Season, error) {
+	var body models.Season
+	if err := json.Unmarshal([]byte(event.Body), &body); err != nil {
+		return nil, models.NewValidationError("invalid request body: %v", err)
+	}
+
+	seasonID := body.SeasonID
+	if pathSeasonID, ok := event.PathParameters["seasonId"]; ok {
+		seasonID = models.SeasonID(pathSeasonID)
+	}
+
+	return models.NewSeason(models.GameID(event.PathParameters["gameId"]), seasonID, body.Name, body.StartDate, body.EndDate)
+}
+
+func seasonResponse(season *models.Season, statusCode int) (events.APIGatewayProxyResponse, error) {
+	seasonJSON, err := json.Marshal(season)
+	if err != nil {
+		return ErrorResponse(fmt.Errorf("failed to marshal season data: %w", err)), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(seasonJSON),
+	}, nil
+}
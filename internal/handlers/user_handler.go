@@ -8,6 +8,8 @@ type UserHandler interface {
 	GetUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	GetUserBasicsByPrefix(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	GetGameStat(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	GetGameStats(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	GetUserMatches(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	CreateUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	UpdateUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 	DeleteUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/mquan1409/game-api/internal/models"	
@@ -21,22 +24,17 @@ func (h *UserHandlerImpl) GetUser(event events.APIGatewayProxyRequest) (events.A
 	userID := models.UserID(event.PathParameters["userId"])
 	user, err := h.userService.GetUser(userID)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 	
 	userJSON, err := json.Marshal(user)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal user data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal user data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
+		Headers:    etagHeaders(user.Version),
 		Body:       string(userJSON),
 	}, nil
 }
@@ -45,18 +43,12 @@ func (h *UserHandlerImpl) GetUserBasicsByPrefix(event events.APIGatewayProxyRequ
 	prefix := event.QueryStringParameters["prefix"]
 	users, err := h.userService.GetUserBasicsByPrefix(prefix)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	usersJSON, err := json.Marshal(users)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal users data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal users data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
@@ -68,89 +60,150 @@ func (h *UserHandlerImpl) GetUserBasicsByPrefix(event events.APIGatewayProxyRequ
 func (h *UserHandlerImpl) GetGameStat(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	userID := models.UserID(event.PathParameters["userId"])
 	gameID := models.GameID(event.PathParameters["gameId"])
-	
-	gameStat, err := h.userService.GetGameStat(userID, gameID)
+
+	scope, err := leaderboardScope(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
+	}
+
+	gameStat, err := h.userService.GetGameStat(userID, gameID, scope)
+	if err != nil {
+		return ErrorResponse(err), nil
 	}
 
 	gameStatJSON, err := json.Marshal(gameStat)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal game stat data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal game stat data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
+		Headers:    etagHeaders(gameStat.Version),
 		Body:       string(gameStatJSON),
 	}, nil
 }
 
+// GetGameStats returns every GameStat of the user within the scope, narrowed
+// to the comma-separated attributes query parameter when it is given.
+func (h *UserHandlerImpl) GetGameStats(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	userID := models.UserID(event.PathParameters["userId"])
+
+	scope, err := leaderboardScope(event)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	var attrs []models.AttributeName
+	if attributesParam, ok := event.QueryStringParameters["attributes"]; ok {
+		for _, attr := range strings.Split(attributesParam, ",") {
+			if attr == "" {
+				return ErrorResponse(models.NewValidationError("attributes must be a comma-separated list of attribute names")), nil
+			}
+			attrs = append(attrs, models.AttributeName(attr))
+		}
+	}
+
+	gameStats, err := h.userService.GetGameStats(userID, scope, attrs)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	gameStatsJSON, err := json.Marshal(gameStats)
+	if err != nil {
+		return ErrorResponse(fmt.Errorf("failed to marshal game stats data: %w", err)), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(gameStatsJSON),
+	}, nil
+}
+
+const defaultUserMatchPageSize = 20
+
+func (h *UserHandlerImpl) GetUserMatches(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	userID := models.UserID(event.PathParameters["userId"])
+	cursor := event.QueryStringParameters["cursor"]
+
+	limit := defaultUserMatchPageSize
+	if limitParam, ok := event.QueryStringParameters["limit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return ErrorResponse(models.NewValidationError("limit must be a positive integer")), nil
+		}
+	}
+
+	filter, err := models.NewUserMatchFilter(
+		models.GameID(event.QueryStringParameters["gameId"]),
+		models.DateID(event.QueryStringParameters["from"]),
+		models.DateID(event.QueryStringParameters["to"]),
+	)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	history, err := h.userService.GetUserMatches(userID, filter, limit, cursor)
+	if err != nil {
+		return ErrorResponse(err), nil
+	}
+
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return ErrorResponse(fmt.Errorf("failed to marshal match history data: %w", err)), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(historyJSON),
+	}, nil
+}
+
 func (h *UserHandlerImpl) CreateUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var user models.User
-	err := json.Unmarshal([]byte(event.Body), &user)
+	user, err := parseUser(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       "Invalid request body",
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
-	createdUser, err := h.userService.CreateUser(&user)
+	createdUser, err := h.userService.CreateUser(user)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	createdUserJSON, err := json.Marshal(createdUser)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal created user data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal created user data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusCreated,
+		Headers:    etagHeaders(createdUser.Version),
 		Body:       string(createdUserJSON),
 	}, nil
 }
 
 func (h *UserHandlerImpl) UpdateUser(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	var user models.User
-	err := json.Unmarshal([]byte(event.Body), &user)
+	user, err := parseUser(event)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Body:       "Invalid request body",
-		}, nil
+		return ErrorResponse(err), nil
+	}
+	if user.Version, err = ifMatchVersion(event); err != nil {
+		return ErrorResponse(err), nil
 	}
 
-	updatedUser, err := h.userService.UpdateUser(&user)
+	updatedUser, err := h.userService.UpdateUser(user)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	updatedUserJSON, err := json.Marshal(updatedUser)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       "Failed to marshal updated user data",
-		}, nil
+		return ErrorResponse(fmt.Errorf("failed to marshal updated user data: %w", err)), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
+		Headers:    etagHeaders(updatedUser.Version),
 		Body:       string(updatedUserJSON),
 	}, nil
 }
@@ -159,13 +212,26 @@ func (h *UserHandlerImpl) DeleteUser(event events.APIGatewayProxyRequest) (event
 	userID := models.UserID(event.PathParameters["userId"])
 	err := h.userService.DeleteUser(&userID)
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       err.Error(),
-		}, nil
+		return ErrorResponse(err), nil
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusNoContent,
 	}, nil
-}
\ No newline at end of file
+}
+
+// parseUser builds a user from the request body, taking the user ID from the
+// path when present. GamesPlayed is maintained by the server, so the body's is
+// ignored.
+func parseUser(event events.APIGatewayProxyRequest) (*models.User, error) {
+	var body models.User
+	if err := json.Unmarshal([]byte(event.Body), &body); err != nil {
+		return nil, models.NewValidationError("invalid request body: %v", err)
+	}
+
+	if userID, ok := event.PathParameters["userId"]; ok {
+		body.UserID = models.UserID(userID)
+	}
+
+	return models.NewUser(body.UserID, body.Username, body.Email, nil)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// etagHeaders returns the headers of a response carrying a resource at
+// version, which is its ETag. The ETag is exposed to browsers, since the Cors
+// settings of the API only apply to preflight requests.
+func etagHeaders(version int) map[string]string {
+	return map[string]string{
+		"ETag":                          strconv.Quote(strconv.Itoa(version)),
+		"Access-Control-Expose-Headers": "ETag",
+	}
+}
+
+// ifMatchVersion reads the version in the optional If-Match header, the ETag
+// of the resource the update is based on. Without the header, or with "*", it
+// returns 0 so the update applies to any version.
+func ifMatchVersion(event events.APIGatewayProxyRequest) (int, error) {
+	var ifMatch string
+	for name, value := range event.Headers {
+		if strings.EqualFold(name, "If-Match") {
+			ifMatch = strings.TrimSpace(value)
+			break
+		}
+	}
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil || version < 1 {
+		return 0, models.NewValidationError("If-Match must be an ETag returned by the API, got %s", ifMatch)
+	}
+	return version, nil
+}
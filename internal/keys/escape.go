@@ -0,0 +1,143 @@
+// Package keys builds and parses the partition (Id) and sort (Range) keys of
+// every item in the single DynamoDB table, so the key layout is defined in one
+// place.
+//
+// A key is made of components joined by Separator. IDs are escaped wherever
+// they appear, so an ID containing a delimiter cannot be mistaken for several
+// components. Only '%', '.' and '#' are escaped; keys of IDs without them are
+// the same as before keys were escaped.
+package keys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Separator joins the components of a key.
+const Separator = "."
+
+// ScopeSeparator separates a game ID from the leaderboard scope in a
+// ScopedGameKey.
+const ScopeSeparator = "#"
+
+// escapedCharacters are the delimiters of keys and the escape character
+// itself, each escaped as '%' followed by its two-digit uppercase hex code.
+const escapedCharacters = "%.#"
+
+// Escape makes component safe to use as one component of a key. Escape(p) is
+// a prefix of Escape(s) exactly when p is a prefix of s, so prefix queries
+// still work on escaped IDs.
+func Escape(component string) string {
+	if !strings.ContainsAny(component, escapedCharacters) {
+		return component
+	}
+	var b strings.Builder
+	for i := 0; i < len(component); i++ {
+		if c := component[i]; strings.IndexByte(escapedCharacters, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Unescape reverses Escape. It fails on a '%' that doesn't start the escape of
+// one of the escaped characters.
+func Unescape(component string) (string, error) {
+	if !strings.Contains(component, "%") {
+		return component, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(component); i++ {
+		if component[i] != '%' {
+			b.WriteByte(component[i])
+			continue
+		}
+		if i+3 > len(component) {
+			return "", fmt.Errorf("invalid escape at the end of key component %q", component)
+		}
+		c, ok := unescapedCharacter(component[i : i+3])
+		if !ok {
+			return "", fmt.Errorf("invalid escape %q in key component %q", component[i:i+3], component)
+		}
+		b.WriteByte(c)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+func unescapedCharacter(escape string) (byte, bool) {
+	for i := 0; i < len(escapedCharacters); i++ {
+		if escape == fmt.Sprintf("%%%02X", escapedCharacters[i]) {
+			return escapedCharacters[i], true
+		}
+	}
+	return 0, false
+}
+
+// Join escapes each component and joins them with Separator.
+func Join(components ...string) string {
+	escaped := make([]string, len(components))
+	for i, component := range components {
+		escaped[i] = Escape(component)
+	}
+	return strings.Join(escaped, Separator)
+}
+
+// Split reverses Join.
+func Split(key string) ([]string, error) {
+	components := strings.Split(key, Separator)
+	for i, component := range components {
+		unescaped, err := Unescape(component)
+		if err != nil {
+			return nil, err
+		}
+		components[i] = unescaped
+	}
+	return components, nil
+}
+
+// splitN splits key into exactly n components, or fails naming what it
+// expected key to be.
+func splitN(key string, n int, kind string) ([]string, error) {
+	components, err := Split(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", kind, key, err)
+	}
+	if len(components) != n {
+		return nil, fmt.Errorf("invalid %s %q: expected %d components, found %d", kind, key, n, len(components))
+	}
+	for _, component := range components {
+		if component == "" {
+			return nil, fmt.Errorf("invalid %s %q: empty component", kind, key)
+		}
+	}
+	return components, nil
+}
+
+// cutPrefix removes prefix from a partition key and unescapes the ID that
+// follows it.
+func cutPrefix(id, prefix, kind string) (string, error) {
+	escaped, ok := strings.CutPrefix(id, prefix)
+	if !ok || escaped == "" {
+		return "", fmt.Errorf("invalid %s %q", kind, id)
+	}
+	unescaped, err := Unescape(escaped)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %w", kind, id, err)
+	}
+	return unescaped, nil
+}
+
+// parseID unescapes a sort key made of a single ID.
+func parseID(rangeKey, kind string) (string, error) {
+	if rangeKey == "" {
+		return "", fmt.Errorf("empty %s", kind)
+	}
+	id, err := Unescape(rangeKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %w", kind, rangeKey, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,256 @@
+package keys
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// Partition key prefixes, each followed by the ID the partition belongs to.
+const (
+	UserPartitionPrefix        = "USER_INFO-prefix:"
+	MatchPartitionPrefix       = "MATCH_INFO."
+	userMatchPartitionPrefix   = "USER_MATCH."
+	GameStatPartitionPrefix    = "GameStat."
+	scopedGameStatPrefix       = "ScopedGameStat."
+	LeaderboardPartitionPrefix = "Leaderboard."
+	seasonPartitionPrefix      = "SEASON_INFO."
+	ratingPartitionPrefix      = "RATING."
+)
+
+// GamePartition is the partition holding every game, keyed by GameRange.
+const GamePartition = "GAME_INFO"
+
+// RatingRecomputePartition holds the games whose ratings are waiting to be
+// recomputed, keyed by GameRange.
+const RatingRecomputePartition = "RATING_RECOMPUTE"
+
+// MatchFollowUpPartition holds the follow-ups of match writes waiting to be
+// carried out, keyed by MatchFollowUpRange.
+const MatchFollowUpPartition = "MATCH_FOLLOW_UP"
+
+// rangeEnd follows Separator in byte order, so a bound made of a prefix and
+// rangeEnd is above every key continuing the prefix with Separator.
+const rangeEnd = "/"
+
+// UserPartition spreads users over partitions by the first character of their
+// ID. Users whose IDs start with prefix all live in UserPartition(prefix).
+func UserPartition(userID models.UserID) string {
+	first, _ := utf8.DecodeRuneInString(string(userID))
+	if first == utf8.RuneError {
+		return UserPartitionPrefix
+	}
+	return UserPartitionPrefix + string(first)
+}
+
+// UserRange is the sort key of a user. UserRange(prefix) is a prefix of the
+// sort key of every user whose ID starts with prefix.
+func UserRange(userID models.UserID) string {
+	return Escape(string(userID))
+}
+
+// ParseUserRange reverses UserRange.
+func ParseUserRange(rangeKey string) (models.UserID, error) {
+	id, err := parseID(rangeKey, "user sort key")
+	return models.UserID(id), err
+}
+
+// GameRange is the sort key of a game in GamePartition.
+func GameRange(gameID models.GameID) string {
+	return Escape(string(gameID))
+}
+
+// ParseGameRange reverses GameRange.
+func ParseGameRange(rangeKey string) (models.GameID, error) {
+	id, err := parseID(rangeKey, "game sort key")
+	return models.GameID(id), err
+}
+
+// MatchFollowUpRange is the sort key of the follow-up of a game's matches on
+// a date, as "<game>.<date>".
+func MatchFollowUpRange(gameID models.GameID, dateID models.DateID) string {
+	return Join(string(gameID), string(dateID))
+}
+
+// ParseMatchFollowUpRange reverses MatchFollowUpRange.
+func ParseMatchFollowUpRange(rangeKey string) (models.GameID, models.DateID, error) {
+	components, err := splitN(rangeKey, 2, "match follow-up sort key")
+	if err != nil {
+		return "", "", err
+	}
+	return models.GameID(components[0]), models.DateID(components[1]), nil
+}
+
+// MatchPartition holds the matches of a game.
+func MatchPartition(gameID models.GameID) string {
+	return MatchPartitionPrefix + Escape(string(gameID))
+}
+
+// MatchRange orders a game's matches by date as "<date>.<match>".
+func MatchRange(dateID models.DateID, matchID models.MatchID) string {
+	return Join(string(dateID), string(matchID))
+}
+
+// DatePrefix starts the sort key of every match, or history entry, on date.
+func DatePrefix(date models.DateID) string {
+	return Escape(string(date)) + Separator
+}
+
+// DateRangeStart is below the sort key of every match, or history entry, on
+// date and above those of earlier dates.
+func DateRangeStart(date models.DateID) string {
+	return Escape(string(date))
+}
+
+// DateRangeEnd is above the sort key of every match, or history entry, on date
+// and below those of later dates.
+func DateRangeEnd(date models.DateID) string {
+	return Escape(string(date)) + rangeEnd
+}
+
+// ParseMatchKey reads the game, date and match ID from the partition and sort
+// keys of a match.
+func ParseMatchKey(id, rangeKey string) (models.GameID, models.DateID, models.MatchID, error) {
+	gameID, err := cutPrefix(id, MatchPartitionPrefix, "match partition key")
+	if err != nil {
+		return "", "", "", err
+	}
+	components, err := splitN(rangeKey, 2, "match sort key")
+	if err != nil {
+		return "", "", "", err
+	}
+	return models.GameID(gameID), models.DateID(components[0]), models.MatchID(components[1]), nil
+}
+
+// UserMatchPartition holds the match history of a user.
+func UserMatchPartition(userID models.UserID) string {
+	return userMatchPartitionPrefix + Escape(string(userID))
+}
+
+// UserMatchRange orders a user's history by date as "<date>.<game>.<match>".
+// The game comes before the match ID because match IDs are only unique within
+// a game.
+func UserMatchRange(dateID models.DateID, gameID models.GameID, matchID models.MatchID) string {
+	return Join(string(dateID), string(gameID), string(matchID))
+}
+
+// ParseUserMatchRange reverses UserMatchRange.
+func ParseUserMatchRange(rangeKey string) (models.DateID, models.GameID, models.MatchID, error) {
+	components, err := splitN(rangeKey, 3, "match history sort key")
+	if err != nil {
+		return "", "", "", err
+	}
+	return models.DateID(components[0]), models.GameID(components[1]), models.MatchID(components[2]), nil
+}
+
+// GameStatPartition holds the all-time GameStats of a user, keyed by game.
+func GameStatPartition(userID models.UserID) string {
+	return GameStatPartitionPrefix + Escape(string(userID))
+}
+
+// ParseGameStatPartition reverses GameStatPartition.
+func ParseGameStatPartition(id string) (models.UserID, error) {
+	userID, err := cutPrefix(id, GameStatPartitionPrefix, "GameStat partition key")
+	return models.UserID(userID), err
+}
+
+// ScopedGameStatPartition holds the GameStats of every player of a game
+// within a scope other than all-time, keyed by user, so the GameStats of a
+// period or season can be listed without knowing its players.
+func ScopedGameStatPartition(gameID models.GameID, scope models.LeaderboardScope) string {
+	return scopedGameStatPrefix + ScopedGameKey(gameID, scope)
+}
+
+// GameStatKey returns the partition and sort keys of the user's GameStat in
+// the game within scope: in GameStatPartition keyed by game for the all-time
+// scope, in ScopedGameStatPartition keyed by user otherwise.
+func GameStatKey(userID models.UserID, gameID models.GameID, scope models.LeaderboardScope) (string, string) {
+	if scope == models.AllTimeScope {
+		return GameStatPartition(userID), Escape(string(gameID))
+	}
+	return ScopedGameStatPartition(gameID, scope), Escape(string(userID))
+}
+
+// ParseGameStatKey reverses GameStatKey.
+func ParseGameStatKey(id, rangeKey string) (models.UserID, models.GameID, models.LeaderboardScope, error) {
+	if scopedGame, ok := strings.CutPrefix(id, scopedGameStatPrefix); ok {
+		gameID, scope, err := ParseScopedGameKey(scopedGame)
+		if err != nil {
+			return "", "", "", err
+		}
+		if scope == models.AllTimeScope {
+			return "", "", "", fmt.Errorf("invalid scoped GameStat partition key %q: no scope", id)
+		}
+		userID, err := parseID(rangeKey, "GameStat sort key")
+		return models.UserID(userID), gameID, scope, err
+	}
+	userID, err := ParseGameStatPartition(id)
+	if err != nil {
+		return "", "", "", err
+	}
+	gameID, err := parseID(rangeKey, "GameStat sort key")
+	return userID, models.GameID(gameID), models.AllTimeScope, err
+}
+
+// ScopedGameKey identifies a game's data within scope: the game ID itself for
+// the all-time scope, "<game>#<scope>" otherwise. It is the suffix of the
+// Leaderboard and scoped GameStat partitions. The scope ends the key and is
+// written as is, so scopes built from a date or season ID keep their format.
+func ScopedGameKey(gameID models.GameID, scope models.LeaderboardScope) string {
+	if scope == models.AllTimeScope {
+		return Escape(string(gameID))
+	}
+	return Escape(string(gameID)) + ScopeSeparator + string(scope)
+}
+
+// ParseScopedGameKey reverses ScopedGameKey.
+func ParseScopedGameKey(key string) (models.GameID, models.LeaderboardScope, error) {
+	escaped, scope, scoped := strings.Cut(key, ScopeSeparator)
+	gameID, err := parseID(escaped, "scoped game key")
+	if err != nil {
+		return "", "", err
+	}
+	if scoped && scope == "" {
+		return "", "", fmt.Errorf("invalid scoped game key %q: empty scope", key)
+	}
+	return models.GameID(gameID), models.LeaderboardScope(scope), nil
+}
+
+// LeaderboardPartition holds a game's leaderboards within scope.
+func LeaderboardPartition(gameID models.GameID, scope models.LeaderboardScope) string {
+	return LeaderboardPartitionPrefix + ScopedGameKey(gameID, scope)
+}
+
+// SeasonPartition holds the seasons of a game, keyed by SeasonRange.
+func SeasonPartition(gameID models.GameID) string {
+	return seasonPartitionPrefix + Escape(string(gameID))
+}
+
+// SeasonRange is the sort key of a season.
+func SeasonRange(seasonID models.SeasonID) string {
+	return Escape(string(seasonID))
+}
+
+// ParseSeasonRange reverses SeasonRange.
+func ParseSeasonRange(rangeKey string) (models.SeasonID, error) {
+	id, err := parseID(rangeKey, "season sort key")
+	return models.SeasonID(id), err
+}
+
+// RatingPartition holds the ratings of a game's players, keyed by RatingRange.
+func RatingPartition(gameID models.GameID) string {
+	return ratingPartitionPrefix + Escape(string(gameID))
+}
+
+// RatingRange is the sort key of a player's rating.
+func RatingRange(userID models.UserID) string {
+	return Escape(string(userID))
+}
+
+// ParseRatingRange reverses RatingRange.
+func ParseRatingRange(rangeKey string) (models.UserID, error) {
+	id, err := parseID(rangeKey, "rating sort key")
+	return models.UserID(id), err
+}
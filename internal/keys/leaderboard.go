@@ -0,0 +1,84 @@
+package keys
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// leaderboardValueWidth is the number of digits needed to print any uint64.
+const leaderboardValueWidth = 20
+
+const leaderboardSignBit = uint64(1) << 63
+
+// LeaderboardRange builds the sort key of a leaderboard item as
+// "<attr>.<encoded value>.<userID>", so a descending Query of an attribute's
+// prefix reads its leaderboard from the top.
+func LeaderboardRange(attr models.AttributeName, value models.AttributeStat, userID models.UserID) string {
+	return Join(string(attr), EncodeLeaderboardValue(value), string(userID))
+}
+
+// LeaderboardAttributePrefix starts the sort key of every item of the
+// attribute's leaderboard, and of no other attribute's.
+func LeaderboardAttributePrefix(attr models.AttributeName) string {
+	return Escape(string(attr)) + Separator
+}
+
+// LeaderboardAttributeEnd is above the sort key of every item of the
+// attribute's leaderboard and below those of the attributes after it.
+func LeaderboardAttributeEnd(attr models.AttributeName) string {
+	return Escape(string(attr)) + rangeEnd
+}
+
+// EncodeLeaderboardValue writes value in offset binary as a fixed-width decimal
+// string, so that comparing two encodings as strings gives the same result as
+// comparing the values, for every AttributeStat including negatives.
+func EncodeLeaderboardValue(value models.AttributeStat) string {
+	return fmt.Sprintf("%0*d", leaderboardValueWidth, uint64(value)^leaderboardSignBit)
+}
+
+// DecodeLeaderboardValue reverses EncodeLeaderboardValue.
+func DecodeLeaderboardValue(encoded string) (models.AttributeStat, error) {
+	if len(encoded) != leaderboardValueWidth {
+		return 0, fmt.Errorf("invalid leaderboard value %q", encoded)
+	}
+	u, err := strconv.ParseUint(encoded, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid leaderboard value %q: %w", encoded, err)
+	}
+	return models.AttributeStat(int64(u ^ leaderboardSignBit)), nil
+}
+
+// ParseLeaderboardRange reverses LeaderboardRange.
+func ParseLeaderboardRange(rangeKey string) (models.AttributeName, models.AttributeStat, models.UserID, error) {
+	components, err := splitN(rangeKey, 3, "leaderboard sort key")
+	if err != nil {
+		return "", 0, "", err
+	}
+	value, err := DecodeLeaderboardValue(components[1])
+	if err != nil {
+		return "", 0, "", err
+	}
+	return models.AttributeName(components[0]), value, models.UserID(components[2]), nil
+}
+
+// ParseLegacyLeaderboardRange reads the "<attr>.%05d.<userID>" sort keys
+// written before values were offset-encoded and IDs escaped.
+func ParseLegacyLeaderboardRange(rangeKey string, userID models.UserID) (models.AttributeName, models.AttributeStat, error) {
+	attrAndValue, ok := strings.CutSuffix(rangeKey, Separator+string(userID))
+	if !ok {
+		return "", 0, fmt.Errorf("leaderboard key %q does not belong to user %s", rangeKey, userID)
+	}
+	separator := strings.LastIndex(attrAndValue, Separator)
+	if separator <= 0 {
+		return "", 0, errors.New("invalid legacy leaderboard key " + rangeKey)
+	}
+	value, err := strconv.Atoi(attrAndValue[separator+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid legacy leaderboard key %q: %w", rangeKey, err)
+	}
+	return models.AttributeName(attrAndValue[:separator]), models.AttributeStat(value), nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import "slices"
+
+// AggregationMode is how the values a player gets for an attribute in their
+// matches combine into their GameStat. The zero value is AggregationSum.
+type AggregationMode string
+
+const (
+	AggregationSum AggregationMode = "sum"
+	AggregationMax AggregationMode = "max"
+	AggregationMin AggregationMode = "min"
+	// AggregationLast keeps the value of the player's latest match in history
+	// order, i.e. by DateID and then MatchID.
+	AggregationLast AggregationMode = "last"
+	// AggregationAvg keeps the average of the values, rounded toward zero.
+	AggregationAvg AggregationMode = "avg"
+)
+
+// AggregationModes lists every supported aggregation mode.
+var AggregationModes = []AggregationMode{AggregationSum, AggregationMax, AggregationMin, AggregationLast, AggregationAvg}
+
+// ParseAggregationMode validates an aggregation mode name. The empty string is
+// AggregationSum.
+func ParseAggregationMode(s string) (AggregationMode, error) {
+	if s == "" {
+		return AggregationSum, nil
+	}
+	for _, mode := range AggregationModes {
+		if string(mode) == s {
+			return mode, nil
+		}
+	}
+	return "", NewValidationError("invalid aggregation mode %q: must be one of sum, max, min, last or avg", s)
+}
+
+// Invertible reports whether a match's value can be taken back out of an
+// aggregate. Only sums are: the other modes need the remaining values.
+func (m AggregationMode) Invertible() bool {
+	return m == AggregationSum || m == ""
+}
+
+// Aggregate combines values, given in history order, with the mode. No
+// values aggregate to 0.
+func (m AggregationMode) Aggregate(values []AttributeStat) AttributeStat {
+	if len(values) == 0 {
+		return 0
+	}
+	switch m {
+	case AggregationMax:
+		return slices.Max(values)
+	case AggregationMin:
+		return slices.Min(values)
+	case AggregationLast:
+		return values[len(values)-1]
+	}
+	var sum AttributeStat
+	for _, value := range values {
+		sum += value
+	}
+	if m == AggregationAvg {
+		return sum / AttributeStat(len(values))
+	}
+	return sum
+}
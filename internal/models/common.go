@@ -4,6 +4,7 @@ type GameID string
 type UserID string
 type DateID string
 type MatchID string
+type SeasonID string
 type AttributeName string
 type AttributeStat int
 
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of errors the API reports to clients. Errors returned by the
+// repositories and services wrap one of them, so callers can tell them apart
+// with errors.Is; any other error is an internal one.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// kindError is an error of one of the kinds above with its own message and,
+// for invalid input, every problem found in it.
+type kindError struct {
+	kind     error
+	message  string
+	problems []string
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// NewNotFoundError returns an ErrNotFound error for a missing resource.
+func NewNotFoundError(format string, args ...any) error {
+	return &kindError{kind: ErrNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+// NewConflictError returns an ErrConflict error for a write that clashes with
+// the stored state, such as creating a resource that already exists.
+func NewConflictError(format string, args ...any) error {
+	return &kindError{kind: ErrConflict, message: fmt.Sprintf(format, args...)}
+}
+
+// NewValidationError returns an ErrValidation error for invalid input.
+func NewValidationError(format string, args ...any) error {
+	return &kindError{kind: ErrValidation, message: fmt.Sprintf(format, args...)}
+}
+
+// NewValidationProblemsError returns an ErrValidation error listing every
+// problem found in the input, so a client can fix them all at once.
+func NewValidationProblemsError(problems []string, format string, args ...any) error {
+	message := fmt.Sprintf(format, args...) + ": " + strings.Join(problems, "; ")
+	return &kindError{kind: ErrValidation, message: message, problems: problems}
+}
+
+// ErrorProblems returns the problems listed by err, or nil if it lists none.
+func ErrorProblems(err error) []string {
+	var kindErr *kindError
+	if !errors.As(err, &kindErr) {
+		return nil
+	}
+	return kindErr.problems
+}
+
+// NewForbiddenError returns an ErrForbidden error for an operation the
+// resource's state doesn't allow.
+func NewForbiddenError(format string, args ...any) error {
+	return &kindError{kind: ErrForbidden, message: fmt.Sprintf(format, args...)}
+}
+
+// NewPreconditionFailedError returns an ErrPreconditionFailed error for a write
+// based on a version of a resource that is no longer the stored one.
+func NewPreconditionFailedError(format string, args ...any) error {
+	return &kindError{kind: ErrPreconditionFailed, message: fmt.Sprintf(format, args...)}
+}
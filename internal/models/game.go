@@ -1,17 +1,77 @@
 package models
 
-import "errors"
+import "slices"
 
 type Game struct {
 	GameID      GameID  `json:"GameID"`
 	Description string            `json:"Description"`
 	Attributes  []AttributeName `json:"Attributes"`
 	RankedAttributes []AttributeName `json:"RankedAttributes"`
+	RatingSystem RatingSystem `json:"RatingSystem,omitempty"`
+	// Aggregations gives the aggregation mode of attributes that are not
+	// summed. Attributes missing from it are summed.
+	Aggregations map[AttributeName]AggregationMode `json:"Aggregations,omitempty"`
+	// RankDirections gives the sort direction of ranked attributes whose
+	// leaderboards rank lower values first. The others rank higher values
+	// first.
+	RankDirections map[AttributeName]SortDirection `json:"RankDirections,omitempty"`
+	// RemovedAggregations keeps the aggregation mode of attributes removed
+	// from the game, whose values stay in its GameStats. It is set by the
+	// server.
+	RemovedAggregations map[AttributeName]AggregationMode `json:"RemovedAggregations,omitempty"`
+	Status GameStatus `json:"Status,omitempty"`
+	Deletion *GameDeletion `json:"Deletion,omitempty"`
+	Version int `json:"Version"`
+}
+
+// GameStatus is where a game is in its lifecycle. Only active games accept
+// writes: an archived game is read-only until it is unarchived, and a game
+// being deleted stays read-only until its cascade delete removes it.
+type GameStatus string
+
+const (
+	GameStatusActive   GameStatus = ""
+	GameStatusArchived GameStatus = "archived"
+	GameStatusDeleting GameStatus = "deleting"
+)
+
+// GameDeletionStep is the step a game's cascade delete is at.
+type GameDeletionStep string
+
+const (
+	// GameDeletionMatches deletes the game's matches a page at a time, along
+	// with the GameStats, period leaderboards and GamesPlayed entries of their
+	// players.
+	GameDeletionMatches GameDeletionStep = "matches"
+	// GameDeletionLeaderboards deletes what is left: the all-time and season
+	// leaderboards with the GameStats of the players on them, the seasons,
+	// the ratings and finally the game.
+	GameDeletionLeaderboards GameDeletionStep = "leaderboards"
+)
+
+// GameDeletion is the progress of a game's cascade delete. It is stored with
+// the game after every page of work, so the delete resumes where it stopped.
+// The matches step needs no cursor: the matches it has deleted are gone, so
+// it always continues from the game's first remaining match.
+type GameDeletion struct {
+	Step           GameDeletionStep `json:"Step"`
+	MatchesDeleted int              `json:"MatchesDeleted"`
+}
+
+// CheckWritable returns an ErrForbidden error unless the game is active.
+func (g *Game) CheckWritable() error {
+	switch g.Status {
+	case GameStatusArchived:
+		return NewForbiddenError("game %s is archived", g.GameID)
+	case GameStatusDeleting:
+		return NewForbiddenError("game %s is being deleted", g.GameID)
+	}
+	return nil
 }
 
 func NewGame(id GameID, description string, attributes []AttributeName, rankedAttributes []AttributeName) (*Game, error) {
 	if id == "" {
-		return nil, errors.New("game id cannot be empty")
+		return nil, NewValidationError("game id cannot be empty")
 	}
 
 	if attributes == nil {
@@ -29,3 +89,82 @@ func NewGame(id GameID, description string, attributes []AttributeName, rankedAt
 		RankedAttributes:  rankedAttributes,
 	}, nil
 }
+
+// ValidateRatingSystem checks that the game's rating system is supported and
+// that, when it keeps ratings, none of its attributes clash with the rating
+// leaderboard.
+func (g *Game) ValidateRatingSystem() error {
+	if _, err := ParseRatingSystem(string(g.RatingSystem)); err != nil {
+		return err
+	}
+	if g.RatingSystem != RatingSystemNone && (slices.Contains(g.Attributes, RatingAttribute) || slices.Contains(g.RankedAttributes, RatingAttribute)) {
+		return NewValidationError("attribute %s is reserved for games with a rating system", RatingAttribute)
+	}
+	return nil
+}
+
+// ValidateAggregations checks that every aggregation mode of the game is
+// supported and belongs to one of its attributes.
+func (g *Game) ValidateAggregations() error {
+	for attr, mode := range g.Aggregations {
+		if !slices.Contains(g.Attributes, attr) {
+			return NewValidationError("aggregation mode given for attribute %s, which game %s does not have", attr, g.GameID)
+		}
+		if _, err := ParseAggregationMode(string(mode)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Aggregation returns the aggregation mode of attr.
+func (g *Game) Aggregation(attr AttributeName) AggregationMode {
+	if mode, ok := g.Aggregations[attr]; ok && mode != "" {
+		return mode
+	}
+	return AggregationSum
+}
+
+// MaxRankedAttributes bounds how many attributes a game ranks, so that the
+// writes of a player's GameStats and leaderboard entries in the day, week,
+// month and season of a match fit in a single transaction: each scope takes
+// its GameStat and, for every ranked attribute, the delete and put moving its
+// entry.
+const MaxRankedAttributes = 12
+
+// ValidateRankedAttributes checks that the game ranks at most
+// MaxRankedAttributes attributes.
+func (g *Game) ValidateRankedAttributes() error {
+	if len(g.RankedAttributes) > MaxRankedAttributes {
+		return NewValidationError("game %s ranks %d attributes, more than the %d allowed", g.GameID, len(g.RankedAttributes), MaxRankedAttributes)
+	}
+	return nil
+}
+
+// ValidateRankDirections checks that every sort direction of the game is
+// supported and belongs to one of its ranked attributes.
+func (g *Game) ValidateRankDirections() error {
+	for attr, direction := range g.RankDirections {
+		if !slices.Contains(g.RankedAttributes, attr) {
+			return NewValidationError("sort direction given for attribute %s, which game %s does not rank", attr, g.GameID)
+		}
+		if _, err := ParseSortDirection(string(direction)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RankDirection returns the sort direction of attr's leaderboard. The rating
+// leaderboard always ranks higher ratings first.
+func (g *Game) RankDirection(attr AttributeName) SortDirection {
+	if direction, ok := g.RankDirections[attr]; ok && direction != "" && !g.HasRatingLeaderboard(attr) {
+		return direction
+	}
+	return SortDescending
+}
+
+// HasRatingLeaderboard reports whether attr is the game's rating leaderboard.
+func (g *Game) HasRatingLeaderboard(attr AttributeName) bool {
+	return g.RatingSystem != RatingSystemNone && attr == RatingAttribute
+}
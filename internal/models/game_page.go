@@ -0,0 +1,37 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// GameFilter narrows a listing of games to those whose description contains
+// Description, ignoring case, that rank RankedAttribute and that have
+// Status. Empty fields don't filter, so there is no filtering on active games.
+type GameFilter struct {
+	Description     string
+	RankedAttribute AttributeName
+	Status          GameStatus
+}
+
+// Matches reports whether game passes the filter.
+func (f GameFilter) Matches(game *Game) bool {
+	if f.Description != "" && !strings.Contains(strings.ToLower(game.Description), strings.ToLower(f.Description)) {
+		return false
+	}
+	if f.RankedAttribute != "" && !slices.Contains(game.RankedAttributes, f.RankedAttribute) {
+		return false
+	}
+	if f.Status != GameStatusActive && game.Status != f.Status {
+		return false
+	}
+	return true
+}
+
+// GamePage is one page of the game catalogue, ordered by GameID. NextCursor
+// is empty on the last page.
+type GamePage struct {
+	Games      []*Game `json:"Games"`
+	Limit      int     `json:"Limit"`
+	NextCursor string  `json:"NextCursor,omitempty"`
+}
@@ -1,31 +1,91 @@
 package models
 
+// SortDirection is the order a ranked attribute's leaderboard is read in.
+// The zero value is SortDescending.
+type SortDirection string
+
+const (
+	// SortDescending ranks higher values first.
+	SortDescending SortDirection = "desc"
+	// SortAscending ranks lower values first, e.g. for times or golf scores.
+	SortAscending SortDirection = "asc"
+)
+
+// ParseSortDirection validates a sort direction name. The empty string is
+// SortDescending.
+func ParseSortDirection(s string) (SortDirection, error) {
+	switch SortDirection(s) {
+	case "", SortDescending:
+		return SortDescending, nil
+	case SortAscending:
+		return SortAscending, nil
+	}
+	return "", NewValidationError("invalid sort direction %q: must be asc or desc", s)
+}
+
+// LeaderboardEntry is one row of a leaderboard. Rank is 1-based.
+type LeaderboardEntry struct {
+	Rank     int           `json:"Rank"`
+	UserID   UserID        `json:"UserID"`
+	Username string        `json:"Username"`
+	Value    AttributeStat `json:"Value"`
+}
+
 type LeaderBoard struct {
-	GameID        GameID         `json:"GameID"`
-	AttributeName AttributeName  `json:"AttributeName"`
-	UserIDs         []UserID       `json:"UserIDs"`
+	GameID        GameID             `json:"GameID"`
+	AttributeName AttributeName      `json:"AttributeName"`
+	Entries       []LeaderboardEntry `json:"Entries"`
 }
 
+// BoundedLeaderboard is one page of a leaderboard. NextCursor is empty on the
+// last page.
 type BoundedLeaderboard struct {
 	LeaderBoard
-	Limit int            `json:"Limit"`
+	Limit      int    `json:"Limit"`
+	NextCursor string `json:"NextCursor,omitempty"`
 }
 
-func NewLeaderBoard(gameID GameID, attributeName AttributeName, userIDs []UserID) LeaderBoard {
-	if userIDs == nil {
-		userIDs = []UserID{}
+// LeaderboardAroundUser is the slice of a leaderboard centred on one user:
+// up to Window entries above and below them, plus the user's own entry.
+type LeaderboardAroundUser struct {
+	LeaderBoard
+	UserID UserID `json:"UserID"`
+	Rank   int    `json:"Rank"`
+	Window int    `json:"Window"`
+}
+
+func NewLeaderBoard(gameID GameID, attributeName AttributeName, entries []LeaderboardEntry) LeaderBoard {
+	if entries == nil {
+		entries = []LeaderboardEntry{}
 	}
 	return LeaderBoard{
 		GameID:        gameID,
 		AttributeName: attributeName,
-		UserIDs:       userIDs,
+		Entries:       entries,
 	}
 }
 
-func NewBoundedLeaderBoard(gameID GameID, attributeName AttributeName, userIDs []UserID, limit int) BoundedLeaderboard {
+func NewBoundedLeaderBoard(gameID GameID, attributeName AttributeName, entries []LeaderboardEntry, limit int) BoundedLeaderboard {
 	return BoundedLeaderboard{
-		LeaderBoard: NewLeaderBoard(gameID, attributeName, userIDs),
+		LeaderBoard: NewLeaderBoard(gameID, attributeName, entries),
 		Limit:       limit,
 	}
 }
 
+func NewLeaderboardAroundUser(gameID GameID, attributeName AttributeName, entries []LeaderboardEntry, userID UserID, rank int, window int) LeaderboardAroundUser {
+	return LeaderboardAroundUser{
+		LeaderBoard: NewLeaderBoard(gameID, attributeName, entries),
+		UserID:      userID,
+		Rank:        rank,
+		Window:      window,
+	}
+}
+
+// UserIDs returns the users on the leaderboard in rank order.
+func (l LeaderBoard) UserIDs() []UserID {
+	userIDs := make([]UserID, len(l.Entries))
+	for i, entry := range l.Entries {
+		userIDs[i] = entry.UserID
+	}
+	return userIDs
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period is the length of a time-windowed leaderboard.
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+)
+
+// Periods lists every period a match is counted towards.
+var Periods = []Period{PeriodDay, PeriodWeek, PeriodMonth}
+
+// dateLayout is the format of a DateID.
+const dateLayout = "2006-01-02"
+
+// LeaderboardScope selects which of a game's leaderboards, and the GameStats
+// feeding it, a read or write applies to. The zero value is the all-time
+// leaderboard.
+type LeaderboardScope string
+
+const AllTimeScope LeaderboardScope = ""
+
+// SeasonScope returns the scope of a season's GameStats and leaderboards.
+func SeasonScope(seasonID SeasonID) LeaderboardScope {
+	return LeaderboardScope(fmt.Sprintf("season.%s", seasonID))
+}
+
+// ParsePeriod validates a period name.
+func ParsePeriod(s string) (Period, error) {
+	for _, period := range Periods {
+		if string(period) == s {
+			return period, nil
+		}
+	}
+	return "", NewValidationError("invalid period %q: must be one of day, week or month", s)
+}
+
+// NewPeriodScope returns the scope of the period containing date, e.g.
+// "day.2024-06-03", "week.2024-W23" (ISO week) or "month.2024-06".
+func NewPeriodScope(period Period, date DateID) (LeaderboardScope, error) {
+	t, err := parseDate(date)
+	if err != nil {
+		return "", err
+	}
+
+	switch period {
+	case PeriodDay:
+		return LeaderboardScope(fmt.Sprintf("%s.%s", period, t.Format(dateLayout))), nil
+	case PeriodWeek:
+		year, week := t.ISOWeek()
+		return LeaderboardScope(fmt.Sprintf("%s.%04d-W%02d", period, year, week)), nil
+	case PeriodMonth:
+		return LeaderboardScope(fmt.Sprintf("%s.%s", period, t.Format("2006-01"))), nil
+	}
+	return "", NewValidationError("invalid period %q: must be one of day, week or month", period)
+}
+
+// PeriodScopes returns the scope of every period containing date.
+func PeriodScopes(date DateID) ([]LeaderboardScope, error) {
+	scopes := make([]LeaderboardScope, 0, len(Periods))
+	for _, period := range Periods {
+		scope, err := NewPeriodScope(period, date)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// PeriodDates returns the first and last dates of the period containing date,
+// e.g. the Monday and Sunday of its ISO week.
+func PeriodDates(period Period, date DateID) (DateID, DateID, error) {
+	t, err := parseDate(date)
+	if err != nil {
+		return "", "", err
+	}
+
+	var first, last time.Time
+	switch period {
+	case PeriodDay:
+		first, last = t, t
+	case PeriodWeek:
+		first = t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		last = first.AddDate(0, 0, 6)
+	case PeriodMonth:
+		first = t.AddDate(0, 0, 1-t.Day())
+		last = first.AddDate(0, 1, -1)
+	default:
+		return "", "", NewValidationError("invalid period %q: must be one of day, week or month", period)
+	}
+	return DateID(first.Format(dateLayout)), DateID(last.Format(dateLayout)), nil
+}
+
+func parseDate(date DateID) (time.Time, error) {
+	t, err := time.Parse(dateLayout, string(date))
+	if err != nil {
+		return time.Time{}, NewValidationError("invalid date %q: must be a YYYY-MM-DD calendar date", date)
+	}
+	return t, nil
+}
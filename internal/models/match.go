@@ -1,6 +1,11 @@
 package models
 
-import "errors"
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
 
 type Match struct {
 	MatchID MatchID `json:"MatchID"`
@@ -10,41 +15,38 @@ type Match struct {
 	TeamScores []int `json:"TeamScores"`
 	TeamMembers [][]string `json:"TeamMembers"`
 	PlayerAttributesMap map[UserID]AttributesStatsMap `json:"PlayerAttributesMap"`
+	Version int `json:"Version"`
 }
 
 func NewMatch(matchID MatchID, dateID DateID, gameID GameID, teamNames []string, teamScores []int, teamMembers [][]string, playerAttributesMap map[UserID]AttributesStatsMap) (*Match, error) {
 	if matchID == "" {
-		return nil, errors.New("match id cannot be empty")
+		return nil, NewValidationError("match id cannot be empty")
 	}
 	if dateID == "" {
-		return nil, errors.New("date id cannot be empty")
+		return nil, NewValidationError("date id cannot be empty")
+	}
+	// The date starts the match's sort key, so it must be exactly YYYY-MM-DD
+	if _, err := parseDate(dateID); err != nil {
+		return nil, err
 	}
 	if gameID == "" {
-		return nil, errors.New("game id cannot be empty")
+		return nil, NewValidationError("game id cannot be empty")
 	}
 	if len(teamNames) == 0 {
-		return nil, errors.New("team names list cannot be empty")
+		return nil, NewValidationError("team names list cannot be empty")
 	}
 	if len(teamScores) == 0 {
-		return nil, errors.New("team scores list cannot be empty")
+		return nil, NewValidationError("team scores list cannot be empty")
 	}
 	if len(teamMembers) == 0 {
-		return nil, errors.New("team members list cannot be empty")
+		return nil, NewValidationError("team members list cannot be empty")
 	}
 	if len(teamMembers) != len(teamScores) {
-		return nil, errors.New("team members list and team scores list must have the same length")
+		return nil, NewValidationError("team members list and team scores list must have the same length")
 	}
 	if playerAttributesMap == nil {
 		playerAttributesMap = make(map[UserID]AttributesStatsMap)
 	}
-	// Check if any attribute stat value is negative
-	for _, attrs := range playerAttributesMap {
-		for _, value := range attrs {
-			if value < 0 {
-				return nil, errors.New("attribute stat values cannot be negative")
-			}
-		}
-	}
 	return &Match{
 		MatchID: matchID,
 		DateID: dateID,
@@ -69,4 +71,187 @@ func (m *Match) SetPlayerAttributes(userID UserID, attrs AttributesStatsMap) {
 		m.PlayerAttributesMap = make(map[UserID]AttributesStatsMap)
 	}
 	m.PlayerAttributesMap[userID] = attrs
-}
\ No newline at end of file
+}
+
+// Players returns the members of every team in team order, each once.
+func (m *Match) Players() []UserID {
+	var players []UserID
+	for _, members := range m.TeamMembers {
+		for _, member := range members {
+			if !slices.Contains(players, UserID(member)) {
+				players = append(players, UserID(member))
+			}
+		}
+	}
+	return players
+}
+
+// anonymousPlayerPrefix starts the IDs standing in for deleted users in the
+// matches they played. No user ID can start with it.
+const anonymousPlayerPrefix = "~deleted-"
+
+// IsAnonymousPlayer reports whether userID stands in for a deleted user.
+func IsAnonymousPlayer(userID UserID) bool {
+	return strings.HasPrefix(string(userID), anonymousPlayerPrefix)
+}
+
+// Anonymize replaces userID in the teams by an ID unique within the match
+// that no user can have, and drops the player's attributes, so the match
+// keeps its result for the other players. It reports whether userID played
+// in the match.
+func (m *Match) Anonymize(userID UserID) bool {
+	players := m.Players()
+	if !slices.Contains(players, userID) {
+		return false
+	}
+
+	anonymous := 1
+	for _, player := range players {
+		if IsAnonymousPlayer(player) {
+			anonymous++
+		}
+	}
+	anonymousID := fmt.Sprintf("%s%d", anonymousPlayerPrefix, anonymous)
+	for _, members := range m.TeamMembers {
+		for i, member := range members {
+			if UserID(member) == userID {
+				members[i] = anonymousID
+			}
+		}
+	}
+	delete(m.PlayerAttributesMap, userID)
+	return true
+}
+
+// Problems lists every way the match doesn't fit game: an invalid date, team
+// names and members that don't line up with scores, empty teams, players in
+// several teams or in none, and attributes the game doesn't have. Whether the
+// players exist is for the caller to check.
+func (m *Match) Problems(game *Game) []string {
+	var problems []string
+	// The date places the match in its periods and seasons
+	if _, err := parseDate(m.DateID); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid date %q: must be a YYYY-MM-DD calendar date", m.DateID))
+	}
+	if len(m.TeamNames) != len(m.TeamScores) {
+		problems = append(problems, fmt.Sprintf("%d team names for %d team scores", len(m.TeamNames), len(m.TeamScores)))
+	}
+	if len(m.TeamMembers) != len(m.TeamScores) {
+		problems = append(problems, fmt.Sprintf("%d teams of members for %d team scores", len(m.TeamMembers), len(m.TeamScores)))
+	}
+
+	teams := make(map[UserID]int)
+	for i, members := range m.TeamMembers {
+		if len(members) == 0 {
+			problems = append(problems, fmt.Sprintf("team %d has no members", i+1))
+		}
+		for _, member := range members {
+			userID := UserID(member)
+			if userID == "" {
+				problems = append(problems, fmt.Sprintf("team %d has a member with an empty user id", i+1))
+				continue
+			}
+			if team, ok := teams[userID]; ok {
+				if team != i {
+					problems = append(problems, fmt.Sprintf("player %s is in teams %d and %d", userID, team+1, i+1))
+				} else {
+					problems = append(problems, fmt.Sprintf("player %s is listed twice in team %d", userID, i+1))
+				}
+				continue
+			}
+			teams[userID] = i
+		}
+	}
+
+	// Sort so the same payload always reports its problems in the same order
+	var userIDs []UserID
+	for userID := range m.PlayerAttributesMap {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	for _, userID := range userIDs {
+		if _, ok := teams[userID]; !ok {
+			problems = append(problems, fmt.Sprintf("player %s has attributes but is in no team", userID))
+		}
+		if IsAnonymousPlayer(userID) {
+			problems = append(problems, fmt.Sprintf("player %s was deleted and cannot have attributes", userID))
+		}
+		var names []AttributeName
+		for name := range m.PlayerAttributesMap[userID] {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+		for _, name := range names {
+			if !slices.Contains(game.Attributes, name) {
+				problems = append(problems, fmt.Sprintf("attribute %s of player %s is not an attribute of game %s", name, userID, game.GameID))
+			}
+		}
+	}
+	return problems
+}
+
+// TeamRanks returns each team's finishing position from TeamScores, where 0
+// is first and teams with equal scores share a position.
+func (m *Match) TeamRanks() []int {
+	ranks := make([]int, len(m.TeamScores))
+	for i, score := range m.TeamScores {
+		for _, other := range m.TeamScores {
+			if other > score {
+				ranks[i]++
+			}
+		}
+	}
+	return ranks
+}
+
+// SameResult reports whether both matches have the same teams and scores, so
+// they affect ratings in the same way.
+func (m *Match) SameResult(other *Match) bool {
+	return slices.Equal(m.TeamScores, other.TeamScores) && slices.EqualFunc(m.TeamMembers, other.TeamMembers, slices.Equal[[]string])
+}
+
+// UserMatches returns the entry of each player's match history for the match.
+// Anonymous players have none.
+func (m *Match) UserMatches() []*UserMatch {
+	ranks := m.TeamRanks()
+	var userMatches []*UserMatch
+	for _, userID := range m.Players() {
+		if IsAnonymousPlayer(userID) {
+			continue
+		}
+		userMatch := &UserMatch{
+			UserID:     userID,
+			GameID:     m.GameID,
+			DateID:     m.DateID,
+			MatchID:    m.MatchID,
+			Attributes: AttributesStatsMap{},
+		}
+		for i, members := range m.TeamMembers {
+			if slices.Contains(members, string(userID)) {
+				if i < len(m.TeamNames) {
+					userMatch.TeamName = m.TeamNames[i]
+				}
+				userMatch.Result = teamResult(ranks, i)
+				break
+			}
+		}
+		for name, value := range m.PlayerAttributesMap[userID] {
+			userMatch.Attributes[name] = value
+		}
+		userMatches = append(userMatches, userMatch)
+	}
+	return userMatches
+}
+
+// teamResult returns the result of the team finishing at ranks[team].
+func teamResult(ranks []int, team int) MatchResult {
+	if ranks[team] > 0 {
+		return MatchResultLoss
+	}
+	for i, rank := range ranks {
+		if i != team && rank == 0 {
+			return MatchResultDraw
+		}
+	}
+	return MatchResultWin
+}
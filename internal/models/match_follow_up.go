@@ -0,0 +1,17 @@
+package models
+
+// MatchFollowUp records the work left once matches of a game played on a date
+// have been written: the day, week, month and season GameStats and
+// leaderboards of Players must be brought in line with their match history,
+// and Leavers, who left or lost a match, must have the game removed from their
+// GamesPlayed if they have no other match of it. The match writes carry it
+// out right away, and a background worker retries what they could not finish.
+// Version counts the requests made, so a follow-up is only dropped if no
+// request came in while it was being carried out.
+type MatchFollowUp struct {
+	GameID  GameID   `json:"GameID"`
+	DateID  DateID   `json:"DateID"`
+	Players []UserID `json:"Players"`
+	Leavers []UserID `json:"Leavers"`
+	Version int      `json:"Version"`
+}
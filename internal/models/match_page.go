@@ -0,0 +1,30 @@
+package models
+
+// DateRange is the dates from From to To, both included.
+type DateRange struct {
+	From DateID `json:"From"`
+	To   DateID `json:"To"`
+}
+
+func NewDateRange(from DateID, to DateID) (DateRange, error) {
+	if _, err := parseDate(from); err != nil {
+		return DateRange{}, err
+	}
+	if _, err := parseDate(to); err != nil {
+		return DateRange{}, err
+	}
+	if to < from {
+		return DateRange{}, NewValidationError("to cannot be before from")
+	}
+	return DateRange{From: from, To: to}, nil
+}
+
+// MatchPage is one page of a game's matches within a date range, oldest
+// first. NextCursor is empty on the last page.
+type MatchPage struct {
+	GameID GameID `json:"GameID"`
+	DateRange
+	Matches    []*Match `json:"Matches"`
+	Limit      int      `json:"Limit"`
+	NextCursor string   `json:"NextCursor,omitempty"`
+}
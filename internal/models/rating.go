@@ -0,0 +1,67 @@
+package models
+
+// RatingSystem is the algorithm a game uses to rate its players from match
+// results. The zero value means the game keeps no ratings.
+type RatingSystem string
+
+const (
+	RatingSystemNone      RatingSystem = ""
+	RatingSystemElo       RatingSystem = "elo"
+	RatingSystemGlicko2   RatingSystem = "glicko2"
+	RatingSystemTrueSkill RatingSystem = "trueskill"
+)
+
+// RatingSystems lists every supported rating system.
+var RatingSystems = []RatingSystem{RatingSystemElo, RatingSystemGlicko2, RatingSystemTrueSkill}
+
+// RatingAttribute is the ranked leaderboard kept for games with a rating
+// system. Games cannot use it as one of their own attributes.
+const RatingAttribute AttributeName = "rating"
+
+// ParseRatingSystem validates a rating system name. The empty string is
+// RatingSystemNone.
+func ParseRatingSystem(s string) (RatingSystem, error) {
+	if s == string(RatingSystemNone) {
+		return RatingSystemNone, nil
+	}
+	for _, system := range RatingSystems {
+		if string(system) == s {
+			return system, nil
+		}
+	}
+	return "", NewValidationError("invalid rating system %q: must be one of elo, glicko2 or trueskill", s)
+}
+
+// Rating is a player's skill estimate in a game. Mu is the estimated skill and
+// Sigma its uncertainty; Volatility is only used by Glicko-2. Value is what the
+// rating leaderboard ranks the player by. LastDateID and LastMatchID identify
+// the latest match, in history order, that the rating includes.
+type Rating struct {
+	GameID        GameID        `json:"GameID"`
+	UserID        UserID        `json:"UserID"`
+	Mu            float64       `json:"Mu"`
+	Sigma         float64       `json:"Sigma"`
+	Volatility    float64       `json:"Volatility"`
+	Value         AttributeStat `json:"Value"`
+	MatchesPlayed int           `json:"MatchesPlayed"`
+	LastDateID    DateID        `json:"LastDateID"`
+	LastMatchID   MatchID       `json:"LastMatchID"`
+}
+
+// PlayedBefore reports whether every match the rating includes comes before
+// match in history order, i.e. by DateID and then MatchID.
+func (r *Rating) PlayedBefore(match *Match) bool {
+	if r.LastDateID != match.DateID {
+		return r.LastDateID < match.DateID
+	}
+	return r.LastMatchID < match.MatchID
+}
+
+// RatingRecompute records that a game's ratings must be replayed from its
+// match history, which is left to a background worker. Version counts the
+// requests made, so the worker only drops the record if no request came in
+// while it was replaying.
+type RatingRecompute struct {
+	GameID  GameID `json:"GameID"`
+	Version int    `json:"Version"`
+}
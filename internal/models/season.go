@@ -0,0 +1,53 @@
+package models
+
+// Season is a named date range of a game. Matches played within it count
+// towards its own GameStats and leaderboards; once Closed, those are frozen.
+type Season struct {
+	GameID    GameID   `json:"GameID"`
+	SeasonID  SeasonID `json:"SeasonID"`
+	Name      string   `json:"Name"`
+	StartDate DateID   `json:"StartDate"`
+	EndDate   DateID   `json:"EndDate"`
+	Closed    bool     `json:"Closed"`
+}
+
+func NewSeason(gameID GameID, seasonID SeasonID, name string, startDate DateID, endDate DateID) (*Season, error) {
+	if gameID == "" {
+		return nil, NewValidationError("game id cannot be empty")
+	}
+	if seasonID == "" {
+		return nil, NewValidationError("season id cannot be empty")
+	}
+	if _, err := parseDate(startDate); err != nil {
+		return nil, err
+	}
+	if _, err := parseDate(endDate); err != nil {
+		return nil, err
+	}
+	if endDate < startDate {
+		return nil, NewValidationError("end date cannot be before start date")
+	}
+
+	return &Season{
+		GameID:    gameID,
+		SeasonID:  seasonID,
+		Name:      name,
+		StartDate: startDate,
+		EndDate:   endDate,
+	}, nil
+}
+
+// Contains reports whether date falls within the season, both ends included.
+// YYYY-MM-DD dates compare correctly as strings.
+func (s *Season) Contains(date DateID) bool {
+	return s.StartDate <= date && date <= s.EndDate
+}
+
+// Overlaps reports whether the two seasons share at least one day.
+func (s *Season) Overlaps(other *Season) bool {
+	return s.StartDate <= other.EndDate && other.StartDate <= s.EndDate
+}
+
+func (s *Season) Scope() LeaderboardScope {
+	return SeasonScope(s.SeasonID)
+}
@@ -1,6 +1,7 @@
 package models
 
-import "errors"
+import "slices"
+
 // UserBasic represents the basic information of a user.
 type UserBasic struct {
 	UserID   UserID `json:"UserID"`
@@ -11,13 +12,19 @@ type UserBasic struct {
 type User struct {
 	UserBasic
 	Email           string                     `json:"Email"`
+	// GamesPlayed is the games the user has matches in, kept up to date by
+	// the match service
 	GamesPlayed     []GameID                   `json:"GamesPlayed"`
+	Version         int                        `json:"Version"`
 }
 
 // NewUserBasic creates a new UserBasic with initialized fields
 func NewUserBasic(id UserID, username string) (*UserBasic, error) {
 	if username == "" {
-		return nil, errors.New("username cannot be empty")
+		return nil, NewValidationError("username cannot be empty")
+	}
+	if IsAnonymousPlayer(id) {
+		return nil, NewValidationError("user id cannot start with %q", anonymousPlayerPrefix)
 	}
 
 	return &UserBasic{
@@ -34,12 +41,16 @@ func NewUser(id UserID, username, email string, gamesPlayed []GameID) (*User, er
 	}
 
 	if email == "" {
-		return nil, errors.New("email cannot be empty")
+		return nil, NewValidationError("email cannot be empty")
 	}
 
+	// GamesPlayed is a set, kept in GameID order
+	gamesPlayed = slices.Clone(gamesPlayed)
 	if gamesPlayed == nil {
 		gamesPlayed = []GameID{}
 	}
+	slices.Sort(gamesPlayed)
+	gamesPlayed = slices.Compact(gamesPlayed)
 
 	return &User{
 		UserBasic:      *userBasic,
@@ -0,0 +1,54 @@
+package models
+
+// MatchResult is how a match ended for one team.
+type MatchResult string
+
+const (
+	MatchResultWin  MatchResult = "win"
+	MatchResultDraw MatchResult = "draw"
+	MatchResultLoss MatchResult = "loss"
+)
+
+// UserMatch is an entry of a user's match history: a match they played with
+// their team, its result and their attributes in it.
+type UserMatch struct {
+	UserID     UserID             `json:"UserID"`
+	GameID     GameID             `json:"GameID"`
+	DateID     DateID             `json:"DateID"`
+	MatchID    MatchID            `json:"MatchID"`
+	TeamName   string             `json:"TeamName"`
+	Result     MatchResult        `json:"Result"`
+	Attributes AttributesStatsMap `json:"Attributes"`
+}
+
+// UserMatchHistory is one page of a user's match history, newest first.
+// NextCursor is empty on the last page.
+type UserMatchHistory struct {
+	UserID     UserID       `json:"UserID"`
+	Matches    []*UserMatch `json:"Matches"`
+	Limit      int          `json:"Limit"`
+	NextCursor string       `json:"NextCursor,omitempty"`
+}
+
+// UserMatchFilter narrows a user's match history to one game and to matches
+// played from From to To, both included. Empty fields don't filter.
+type UserMatchFilter struct {
+	GameID GameID
+	From   DateID
+	To     DateID
+}
+
+func NewUserMatchFilter(gameID GameID, from DateID, to DateID) (UserMatchFilter, error) {
+	for _, date := range []DateID{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := parseDate(date); err != nil {
+			return UserMatchFilter{}, err
+		}
+	}
+	if from != "" && to != "" && to < from {
+		return UserMatchFilter{}, NewValidationError("to cannot be before from")
+	}
+	return UserMatchFilter{GameID: gameID, From: from, To: to}, nil
+}
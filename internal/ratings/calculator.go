@@ -0,0 +1,63 @@
+package ratings
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// Calculator rates the players of a game from its match results.
+type Calculator interface {
+	// NewRating returns the rating of a player who has not played yet.
+	NewRating(gameID models.GameID, userID models.UserID) *models.Rating
+	// Rate updates the ratings of a match's players in place. teams holds the
+	// ratings of each team's members and ranks each team's finishing
+	// position, where 0 is first and equal ranks are draws. Every update is
+	// computed from the ratings as they were before the match.
+	Rate(teams [][]*models.Rating, ranks []int)
+}
+
+// NewCalculator returns the Calculator implementing system.
+func NewCalculator(system models.RatingSystem) (Calculator, error) {
+	switch system {
+	case models.RatingSystemElo:
+		return &Elo{K: 32}, nil
+	case models.RatingSystemGlicko2:
+		return &Glicko2{Tau: 0.5}, nil
+	case models.RatingSystemTrueSkill:
+		return NewTrueSkill(), nil
+	}
+	return nil, fmt.Errorf("unsupported rating system %q", system)
+}
+
+// outcome returns the score of a team finishing at rank against one finishing
+// at opponentRank: 1 for a win, 0.5 for a draw and 0 for a loss.
+func outcome(rank, opponentRank int) float64 {
+	switch {
+	case rank < opponentRank:
+		return 1
+	case rank == opponentRank:
+		return 0.5
+	}
+	return 0
+}
+
+// teamMean returns the average Mu and the root mean square Sigma of a team,
+// which stands in for the team when it is rated against another.
+func teamMean(team []*models.Rating) (float64, float64) {
+	if len(team) == 0 {
+		return 0, 0
+	}
+	var mu, variance float64
+	for _, rating := range team {
+		mu += rating.Mu
+		variance += rating.Sigma * rating.Sigma
+	}
+	n := float64(len(team))
+	return mu / n, math.Sqrt(variance / n)
+}
+
+func round(x float64) models.AttributeStat {
+	return models.AttributeStat(math.Round(x))
+}
@@ -0,0 +1,50 @@
+package ratings
+
+import (
+	"math"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+const eloInitialRating = 1500
+
+// Elo rates teams by their average rating. A team's rating change is K times
+// its score minus its expected score, averaged over every opposing team, and
+// is applied to each of its members.
+type Elo struct {
+	K float64
+}
+
+func (e *Elo) NewRating(gameID models.GameID, userID models.UserID) *models.Rating {
+	rating := &models.Rating{GameID: gameID, UserID: userID, Mu: eloInitialRating}
+	rating.Value = round(rating.Mu)
+	return rating
+}
+
+func (e *Elo) Rate(teams [][]*models.Rating, ranks []int) {
+	if len(teams) < 2 {
+		return
+	}
+
+	means := make([]float64, len(teams))
+	for i, team := range teams {
+		means[i], _ = teamMean(team)
+	}
+
+	for i, team := range teams {
+		var change float64
+		for j := range teams {
+			if i == j {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, (means[j]-means[i])/400))
+			change += outcome(ranks[i], ranks[j]) - expected
+		}
+		change *= e.K / float64(len(teams)-1)
+
+		for _, rating := range team {
+			rating.Mu += change
+			rating.Value = round(rating.Mu)
+		}
+	}
+}
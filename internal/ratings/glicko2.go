@@ -0,0 +1,134 @@
+package ratings
+
+import (
+	"math"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+const (
+	glicko2InitialRating     = 1500
+	glicko2InitialDeviation  = 350
+	glicko2InitialVolatility = 0.06
+	// glicko2Scale converts between the Glicko and Glicko-2 scales.
+	glicko2Scale = 173.7178
+	// glicko2Epsilon is the convergence tolerance of the volatility update.
+	glicko2Epsilon = 0.000001
+)
+
+// Glicko2 implements Mark Glickman's Glicko-2 system, treating each match as a
+// rating period. A player is rated against every opposing team as if it were
+// a single player with the team's average rating and deviation. Tau limits
+// how fast volatility changes.
+type Glicko2 struct {
+	Tau float64
+}
+
+func (g *Glicko2) NewRating(gameID models.GameID, userID models.UserID) *models.Rating {
+	rating := &models.Rating{
+		GameID:     gameID,
+		UserID:     userID,
+		Mu:         glicko2InitialRating,
+		Sigma:      glicko2InitialDeviation,
+		Volatility: glicko2InitialVolatility,
+	}
+	rating.Value = round(rating.Mu)
+	return rating
+}
+
+func (g *Glicko2) Rate(teams [][]*models.Rating, ranks []int) {
+	if len(teams) < 2 {
+		return
+	}
+
+	means := make([]float64, len(teams))
+	deviations := make([]float64, len(teams))
+	for i, team := range teams {
+		means[i], deviations[i] = teamMean(team)
+	}
+
+	updated := make([][]models.Rating, len(teams))
+	for i, team := range teams {
+		updated[i] = make([]models.Rating, len(team))
+		for k, rating := range team {
+			updated[i][k] = g.rate(*rating, i, ranks, means, deviations)
+		}
+	}
+	for i, team := range teams {
+		for k, rating := range team {
+			*rating = updated[i][k]
+		}
+	}
+}
+
+// rate returns the rating of a member of team i after the match.
+func (g *Glicko2) rate(rating models.Rating, i int, ranks []int, means, deviations []float64) models.Rating {
+	mu := (rating.Mu - glicko2InitialRating) / glicko2Scale
+	phi := rating.Sigma / glicko2Scale
+
+	// Estimated variance and improvement from the match's results
+	var invVariance, improvement float64
+	for j := range ranks {
+		if i == j {
+			continue
+		}
+		opponentMu := (means[j] - glicko2InitialRating) / glicko2Scale
+		gPhi := glicko2G(deviations[j] / glicko2Scale)
+		expected := 1 / (1 + math.Exp(-gPhi*(mu-opponentMu)))
+		invVariance += gPhi * gPhi * expected * (1 - expected)
+		improvement += gPhi * (outcome(ranks[i], ranks[j]) - expected)
+	}
+	v := 1 / invVariance
+	delta := v * improvement
+
+	sigma := g.volatility(phi, v, delta, rating.Volatility)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+
+	rating.Mu = newMu*glicko2Scale + glicko2InitialRating
+	rating.Sigma = newPhi * glicko2Scale
+	rating.Volatility = sigma
+	rating.Value = round(rating.Mu)
+	return rating
+}
+
+// volatility finds the new volatility with the Illinois algorithm, as in step
+// 5 of the Glicko-2 paper.
+func (g *Glicko2) volatility(phi, v, delta, sigma float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(g.Tau*g.Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*g.Tau) < 0 {
+			k++
+		}
+		B = a - k*g.Tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glicko2Epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
+
+func glicko2G(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
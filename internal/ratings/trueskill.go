@@ -0,0 +1,171 @@
+package ratings
+
+import (
+	"math"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// TrueSkill is a TrueSkill-style system. Every pair of teams is rated with
+// the two-team TrueSkill update and a player's changes are averaged over the
+// opposing teams, which approximates the full factor graph for matches with
+// more than two teams. Ratings use the usual defaults scaled by 60, so a new
+// player has Mu 1500 and Sigma 500, and the leaderboard ranks players by the
+// conservative estimate Mu - 3*Sigma.
+type TrueSkill struct {
+	Mu              float64
+	Sigma           float64
+	Beta            float64
+	Tau             float64
+	DrawProbability float64
+}
+
+func NewTrueSkill() *TrueSkill {
+	return &TrueSkill{
+		Mu:              1500,
+		Sigma:           500,
+		Beta:            250,
+		Tau:             5,
+		DrawProbability: 0.1,
+	}
+}
+
+func (t *TrueSkill) NewRating(gameID models.GameID, userID models.UserID) *models.Rating {
+	rating := &models.Rating{GameID: gameID, UserID: userID, Mu: t.Mu, Sigma: t.Sigma}
+	rating.Value = t.value(rating)
+	return rating
+}
+
+func (t *TrueSkill) Rate(teams [][]*models.Rating, ranks []int) {
+	if len(teams) < 2 {
+		return
+	}
+
+	// Skills drift between matches
+	variances := make([][]float64, len(teams))
+	for i, team := range teams {
+		variances[i] = make([]float64, len(team))
+		for k, rating := range team {
+			variances[i][k] = rating.Sigma*rating.Sigma + t.Tau*t.Tau
+		}
+	}
+
+	muChanges := make([][]float64, len(teams))
+	varianceFactors := make([][]float64, len(teams))
+	for i, team := range teams {
+		muChanges[i] = make([]float64, len(team))
+		varianceFactors[i] = make([]float64, len(team))
+	}
+
+	for i := range teams {
+		for j := range teams {
+			if i == j {
+				continue
+			}
+			t.ratePair(teams, variances, ranks, i, j, muChanges, varianceFactors)
+		}
+	}
+
+	opponents := float64(len(teams) - 1)
+	for i, team := range teams {
+		for k, rating := range team {
+			rating.Mu += muChanges[i][k] / opponents
+			rating.Sigma = math.Sqrt(variances[i][k] * math.Max(varianceFactors[i][k]/opponents, 0.0001))
+			rating.Value = t.value(rating)
+		}
+	}
+}
+
+// ratePair accumulates the two-team update of team i's members against team
+// j into muChanges and varianceFactors.
+func (t *TrueSkill) ratePair(teams [][]*models.Rating, variances [][]float64, ranks []int, i, j int, muChanges, varianceFactors [][]float64) {
+	var muI, muJ, c2 float64
+	for k, rating := range teams[i] {
+		muI += rating.Mu
+		c2 += variances[i][k]
+	}
+	for k, rating := range teams[j] {
+		muJ += rating.Mu
+		c2 += variances[j][k]
+	}
+	players := float64(len(teams[i]) + len(teams[j]))
+	c2 += players * t.Beta * t.Beta
+	c := math.Sqrt(c2)
+
+	epsilon := drawMargin(t.DrawProbability, players, t.Beta) / c
+	diff := (muI - muJ) / c
+
+	var v, w float64
+	switch {
+	case ranks[i] < ranks[j]:
+		v, w = vWin(diff, epsilon), wWin(diff, epsilon)
+	case ranks[i] > ranks[j]:
+		v, w = -vWin(-diff, epsilon), wWin(-diff, epsilon)
+	default:
+		v, w = vDraw(diff, epsilon), wDraw(diff, epsilon)
+	}
+
+	for k := range teams[i] {
+		variance := variances[i][k]
+		muChanges[i][k] += variance / c * v
+		varianceFactors[i][k] += 1 - variance/c2*w
+	}
+}
+
+func (t *TrueSkill) value(rating *models.Rating) models.AttributeStat {
+	return round(rating.Mu - 3*rating.Sigma)
+}
+
+// drawMargin returns the performance difference below which a match between
+// the given number of players is drawn with probability p.
+func drawMargin(p, players, beta float64) float64 {
+	return normInv((p+1)/2) * math.Sqrt(players) * beta
+}
+
+func vWin(t, epsilon float64) float64 {
+	x := t - epsilon
+	denom := normCDF(x)
+	if denom < 1e-300 {
+		return -x
+	}
+	return normPDF(x) / denom
+}
+
+func wWin(t, epsilon float64) float64 {
+	v := vWin(t, epsilon)
+	w := v * (v + t - epsilon)
+	return math.Min(math.Max(w, 0), 1)
+}
+
+func vDraw(t, epsilon float64) float64 {
+	denom := normCDF(epsilon-t) - normCDF(-epsilon-t)
+	if denom < 1e-300 {
+		if t < 0 {
+			return -t - epsilon
+		}
+		return -t + epsilon
+	}
+	return (normPDF(-epsilon-t) - normPDF(epsilon-t)) / denom
+}
+
+func wDraw(t, epsilon float64) float64 {
+	denom := normCDF(epsilon-t) - normCDF(-epsilon-t)
+	if denom < 1e-300 {
+		return 1
+	}
+	v := vDraw(t, epsilon)
+	w := v*v + ((epsilon-t)*normPDF(epsilon-t)+(epsilon+t)*normPDF(epsilon+t))/denom
+	return math.Min(math.Max(w, 0), 1)
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func normCDF(x float64) float64 {
+	return math.Erfc(-x/math.Sqrt2) / 2
+}
+
+func normInv(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// Conditions on the Id key attribute, which every stored item has: a create
+// must not overwrite an item and an update must not create one.
+const (
+	itemNotExistsCondition = "attribute_not_exists(Id)"
+	itemExistsCondition    = "attribute_exists(Id)"
+)
+
+// versionAttribute is the attribute holding the number of writes of a
+// versioned item. Items written before versions were kept lack it and count
+// as version 1.
+const versionAttribute = "Version"
+
+// guardVersion makes put write version+1 only if the stored item is still at
+// version, or, when version is 0, only if there is no stored item.
+func guardVersion(put *dynamodb.Put, version int) {
+	put.Item[versionAttribute] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(version + 1))}
+	if version == 0 {
+		put.ConditionExpression = aws.String(itemNotExistsCondition)
+		return
+	}
+
+	put.ConditionExpression = aws.String(versionCondition(version))
+	put.ExpressionAttributeNames = map[string]*string{"#Version": aws.String(versionAttribute)}
+	put.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+		":version": {N: aws.String(strconv.Itoa(version))},
+	}
+}
+
+// versionCondition is the condition that the stored item is at version, with
+// #Version naming the version attribute and :version holding version unless
+// it is 0, which stands for no stored item.
+func versionCondition(version int) string {
+	switch version {
+	case 0:
+		return itemNotExistsCondition
+	case 1:
+		return "attribute_exists(Id) AND (#Version = :version OR attribute_not_exists(#Version))"
+	}
+	return "#Version = :version"
+}
+
+// unmarshalVersion reads the version of a stored item.
+func unmarshalVersion(item map[string]*dynamodb.AttributeValue) (int, error) {
+	if item[versionAttribute] == nil || item[versionAttribute].N == nil {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(*item[versionAttribute].N)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version: %w", err)
+	}
+	return version, nil
+}
+
+// conditionalPut writes put right away when tx is nil, returning failed if its
+// condition doesn't hold. Otherwise it stages put in tx, and a failed
+// condition is reported by the transaction.
+func conditionalPut(db *dynamodb.DynamoDB, put *dynamodb.Put, tx *dynamodb.TransactWriteItemsInput, failed error) error {
+	if tx != nil {
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Put: put})
+		return nil
+	}
+
+	_, err := db.PutItem(&dynamodb.PutItemInput{
+		TableName:                 put.TableName,
+		Item:                      put.Item,
+		ConditionExpression:       put.ConditionExpression,
+		ExpressionAttributeNames:  put.ExpressionAttributeNames,
+		ExpressionAttributeValues: put.ExpressionAttributeValues,
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return failed
+	}
+	return err
+}
+
+// transactionError translates a transaction cancelled by a failed condition
+// into the error of the first write whose condition failed. Other errors are
+// returned unchanged.
+func transactionError(tx *dynamodb.TransactWriteItemsInput, err error) error {
+	var cancelled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		return err
+	}
+	for i, reason := range cancelled.CancellationReasons {
+		if aws.StringValue(reason.Code) == "ConditionalCheckFailed" && i < len(tx.TransactItems) {
+			return conditionFailedError(tx.TransactItems[i])
+		}
+	}
+	return err
+}
+
+// conditionFailedError describes why the condition of a staged write failed:
+// the item it creates already exists, the item it updates doesn't, or the
+// item changed since the version the write is based on.
+func conditionFailedError(item *dynamodb.TransactWriteItem) error {
+	var condition *string
+	var names map[string]*string
+	switch {
+	case item.Put != nil:
+		condition, names = item.Put.ConditionExpression, item.Put.ExpressionAttributeNames
+	case item.Update != nil:
+		condition, names = item.Update.ConditionExpression, item.Update.ExpressionAttributeNames
+	case item.Delete != nil:
+		condition, names = item.Delete.ConditionExpression, item.Delete.ExpressionAttributeNames
+	case item.ConditionCheck != nil:
+		condition, names = item.ConditionCheck.ConditionExpression, item.ConditionCheck.ExpressionAttributeNames
+	}
+
+	id, rangeKey := transactItemKey(item)
+	switch {
+	case names["#Version"] != nil:
+		return models.NewPreconditionFailedError("item %s/%s changed since it was read", id, rangeKey)
+	case aws.StringValue(condition) == itemNotExistsCondition:
+		return models.NewConflictError("item %s/%s already exists", id, rangeKey)
+	}
+	return models.NewNotFoundError("item %s/%s does not exist", id, rangeKey)
+}
+
+// transactItemKey returns the Id and Range of the item a staged write acts on.
+func transactItemKey(item *dynamodb.TransactWriteItem) (string, string) {
+	var key map[string]*dynamodb.AttributeValue
+	switch {
+	case item.Put != nil:
+		key = item.Put.Item
+	case item.Update != nil:
+		key = item.Update.Key
+	case item.Delete != nil:
+		key = item.Delete.Key
+	case item.ConditionCheck != nil:
+		key = item.ConditionCheck.Key
+	}
+	if key["Id"] == nil || key["Range"] == nil {
+		return "", ""
+	}
+	return aws.StringValue(key["Id"].S), aws.StringValue(key["Range"].S)
+}
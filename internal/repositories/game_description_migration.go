@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+)
+
+// BackfillGameDescriptions sets the lower-cased description the description
+// filter of GetGames searches on every game stored before it was kept. The
+// game's version is left alone, as the game itself doesn't change, and a game
+// rewritten concurrently already has the attribute, so the backfill can be
+// re-run safely. It returns the number of games updated.
+func BackfillGameDescriptions(db *dynamodb.DynamoDB, tableName string) (int, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("Id = :id"),
+		FilterExpression:       aws.String("attribute_not_exists(" + descriptionLowerAttribute + ")"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {S: aws.String(keys.GamePartition)},
+		},
+	}
+
+	updated := 0
+	for {
+		output, err := db.Query(input)
+		if err != nil {
+			return updated, fmt.Errorf("failed to query games: %w", err)
+		}
+
+		for _, item := range output.Items {
+			if item["Description"] == nil || item["Description"].S == nil {
+				return updated, errors.New("game item is missing its description")
+			}
+			_, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+				TableName:           aws.String(tableName),
+				Key:                 map[string]*dynamodb.AttributeValue{"Id": item["Id"], "Range": item["Range"]},
+				UpdateExpression:    aws.String("SET " + descriptionLowerAttribute + " = :description"),
+				ConditionExpression: aws.String("attribute_not_exists(" + descriptionLowerAttribute + ")"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":description": {S: aws.String(strings.ToLower(*item["Description"].S))},
+				},
+			})
+			var awsErr awserr.Error
+			if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				continue
+			}
+			if err != nil {
+				return updated, fmt.Errorf("failed to backfill the description of game %s: %w", *item["Range"].S, err)
+			}
+			updated++
+		}
+
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	return updated, nil
+}
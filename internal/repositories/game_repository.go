@@ -7,7 +7,12 @@ import (
 
 type GameRepository interface {
 	GetGame(id models.GameID) (*models.Game, error)
+	// GetGames returns a page of at most limit games passing filter, in
+	// GameID order, starting after the game the cursor points to.
+	GetGames(filter models.GameFilter, limit int, cursor string) (models.GamePage, error)
 	CreateGame(game *models.Game, tx *dynamodb.TransactWriteItemsInput) (*models.Game, error)
+	// UpdateGame replaces the game if the stored one is still at game.Version
+	// and sets game.Version to the version written.
 	UpdateGame(game *models.Game, tx *dynamodb.TransactWriteItemsInput) (*models.Game, error)
 	DeleteGame(id models.GameID, tx *dynamodb.TransactWriteItemsInput) error
 }
@@ -2,12 +2,19 @@ package repositories
 
 import (
 	"fmt"
+	"github.com/mquan1409/game-api/internal/keys"
 	"github.com/mquan1409/game-api/internal/models"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"errors"
+	"strconv"
+	"strings"
 )
 
+// descriptionLowerAttribute holds the lower-cased description of a game, which
+// the description filter of GetGames searches so that it ignores case.
+const descriptionLowerAttribute = "DescriptionLower"
+
 type DynamoDBGameRepository struct {
 	db *dynamodb.DynamoDB
 	tableName string
@@ -22,10 +29,10 @@ func (r *DynamoDBGameRepository) GetGame(id models.GameID) (*models.Game, error)
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
 			"Id": {
-				S: aws.String("GAME_INFO"),
+				S: aws.String(keys.GamePartition),
 			},
 			"Range": {
-				S: aws.String(string(id)),
+				S: aws.String(keys.GameRange(id)),
 			},
 		},
 	}
@@ -36,7 +43,7 @@ func (r *DynamoDBGameRepository) GetGame(id models.GameID) (*models.Game, error)
 	}
 
 	if result.Item == nil {
-		return nil, errors.New("game not found")
+		return nil, models.NewNotFoundError("game %s not found", id)
 	}
 
 	game, err := unmarshalGameFromDynamoDB(result.Item)
@@ -47,6 +54,79 @@ func (r *DynamoDBGameRepository) GetGame(id models.GameID) (*models.Game, error)
 	return game, nil
 }
 
+// GetGames queries the GAME_INFO partition. The filter is applied after
+// reading, so the query keeps reading until the page is full or every game
+// has been read.
+func (r *DynamoDBGameRepository) GetGames(filter models.GameFilter, limit int, cursor string) (models.GamePage, error) {
+	if limit < 1 {
+		return models.GamePage{}, models.NewValidationError("limit must be at least 1")
+	}
+	startRange, err := DecodeRangeCursor(cursor)
+	if err != nil {
+		return models.GamePage{}, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		KeyConditionExpression: aws.String("Id = :id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {S: aws.String(keys.GamePartition)},
+		},
+	}
+	var filters []string
+	if filter.Description != "" {
+		filters = append(filters, "contains("+descriptionLowerAttribute+", :description)")
+		input.ExpressionAttributeValues[":description"] = &dynamodb.AttributeValue{S: aws.String(strings.ToLower(filter.Description))}
+	}
+	if filter.RankedAttribute != "" {
+		filters = append(filters, "contains(RankedAttributes, :rankedAttribute)")
+		input.ExpressionAttributeValues[":rankedAttribute"] = &dynamodb.AttributeValue{S: aws.String(string(filter.RankedAttribute))}
+	}
+	if filter.Status != models.GameStatusActive {
+		filters = append(filters, "#status = :status")
+		input.ExpressionAttributeNames = map[string]*string{"#status": aws.String("Status")}
+		input.ExpressionAttributeValues[":status"] = &dynamodb.AttributeValue{S: aws.String(string(filter.Status))}
+	}
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+	if startRange != "" {
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"Id":    {S: aws.String(keys.GamePartition)},
+			"Range": {S: aws.String(startRange)},
+		}
+	}
+
+	// Read one item more than requested to know whether another page follows
+	var items []map[string]*dynamodb.AttributeValue
+	for len(items) <= limit {
+		input.Limit = aws.Int64(int64(limit + 1 - len(items)))
+		result, err := r.db.Query(input)
+		if err != nil {
+			return models.GamePage{}, fmt.Errorf("failed to query games: %w", err)
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	page := models.GamePage{Games: []*models.Game{}, Limit: limit}
+	if len(items) > limit {
+		items = items[:limit]
+		page.NextCursor = EncodeRangeCursor(*items[limit-1]["Range"].S)
+	}
+	for _, item := range items {
+		game, err := unmarshalGameFromDynamoDB(item)
+		if err != nil {
+			return models.GamePage{}, fmt.Errorf("failed to unmarshal game: %w", err)
+		}
+		page.Games = append(page.Games, game)
+	}
+	return page, nil
+}
+
 func (r *DynamoDBGameRepository) CreateGame(game *models.Game, tx *dynamodb.TransactWriteItemsInput) (*models.Game, error) {
 	item, err := r.marshalGameToDynamoDBAttributeValue(game)
 	if err != nil {
@@ -57,28 +137,22 @@ func (r *DynamoDBGameRepository) CreateGame(game *models.Game, tx *dynamodb.Tran
 		Item:      item,
 		TableName: aws.String(r.tableName),
 	}
+	guardVersion(putItem, 0)
 
-	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
-			Put: putItem,
-		})
-		return game, nil
-	}	
-
-	input := &dynamodb.PutItemInput{
-		Item:      putItem.Item,
-		TableName: putItem.TableName,
-	}
-
-	_, err = r.db.PutItem(input)
+	err = conditionalPut(r.db, putItem, tx, models.NewConflictError("game %s already exists", game.GameID))
 	if err != nil {
 		return nil, err
 	}
 
+	game.Version = 1
 	return game, nil
 }
 
 func (r *DynamoDBGameRepository) UpdateGame(game *models.Game, tx *dynamodb.TransactWriteItemsInput) (*models.Game, error) {
+	if game.Version == 0 {
+		return nil, models.NewValidationError("game %s has no version to update", game.GameID)
+	}
+
 	item, err := r.marshalGameToDynamoDBAttributeValue(game)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal game: %w", err)
@@ -88,24 +162,14 @@ func (r *DynamoDBGameRepository) UpdateGame(game *models.Game, tx *dynamodb.Tran
 		Item:      item,
 		TableName: aws.String(r.tableName),
 	}
+	guardVersion(putItem, game.Version)
 
-	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
-			Put: putItem,
-		})
-		return game, nil
-	}
-
-	input := &dynamodb.PutItemInput{
-		Item:      putItem.Item,
-		TableName: putItem.TableName,
-	}
-
-	_, err = r.db.PutItem(input)
+	err = conditionalPut(r.db, putItem, tx, models.NewPreconditionFailedError("game %s changed since version %d", game.GameID, game.Version))
 	if err != nil {
 		return nil, err
 	}
 
+	game.Version++
 	return game, nil
 }
 
@@ -113,10 +177,10 @@ func (r *DynamoDBGameRepository) DeleteGame(id models.GameID, tx *dynamodb.Trans
 	deleteItem := &dynamodb.Delete{
 		Key: map[string]*dynamodb.AttributeValue{
 			"Id": {
-				S: aws.String("GAME_INFO"),
+				S: aws.String(keys.GamePartition),
 			},
 			"Range": {
-				S: aws.String(string(id)),
+				S: aws.String(keys.GameRange(id)),
 			},
 		},
 		TableName: aws.String(r.tableName),
@@ -141,7 +205,13 @@ func unmarshalGameFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*model
 		return nil, errors.New("item is nil")
 	}
 
-	gameID := models.GameID(*item["Range"].S)
+	if item["Range"] == nil || item["Range"].S == nil {
+		return nil, errors.New("error GameID is missing or invalid")
+	}
+	gameID, err := keys.ParseGameRange(*item["Range"].S)
+	if err != nil {
+		return nil, err
+	}
 	description := *item["Description"].S
 
 	var attributes []models.AttributeName
@@ -162,14 +232,57 @@ func unmarshalGameFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*model
 		}
 	}
 
-	return models.NewGame(gameID, description, attributes, rankedAttributes)
+	game, err := models.NewGame(gameID, description, attributes, rankedAttributes)
+	if err != nil {
+		return nil, err
+	}
+	if ratingSystemAV, ok := item["RatingSystem"]; ok && ratingSystemAV.S != nil {
+		game.RatingSystem = models.RatingSystem(*ratingSystemAV.S)
+	}
+	if aggregationsAV, ok := item["Aggregations"]; ok && aggregationsAV.M != nil {
+		game.Aggregations = make(map[models.AttributeName]models.AggregationMode, len(aggregationsAV.M))
+		for attr, av := range aggregationsAV.M {
+			if av.S != nil {
+				game.Aggregations[models.AttributeName(attr)] = models.AggregationMode(*av.S)
+			}
+		}
+	}
+	if removedAggregationsAV, ok := item["RemovedAggregations"]; ok && removedAggregationsAV.M != nil {
+		game.RemovedAggregations = make(map[models.AttributeName]models.AggregationMode, len(removedAggregationsAV.M))
+		for attr, av := range removedAggregationsAV.M {
+			if av.S != nil {
+				game.RemovedAggregations[models.AttributeName(attr)] = models.AggregationMode(*av.S)
+			}
+		}
+	}
+	if rankDirectionsAV, ok := item["RankDirections"]; ok && rankDirectionsAV.M != nil {
+		game.RankDirections = make(map[models.AttributeName]models.SortDirection, len(rankDirectionsAV.M))
+		for attr, av := range rankDirectionsAV.M {
+			if av.S != nil {
+				game.RankDirections[models.AttributeName(attr)] = models.SortDirection(*av.S)
+			}
+		}
+	}
+	if statusAV, ok := item["Status"]; ok && statusAV.S != nil {
+		game.Status = models.GameStatus(*statusAV.S)
+	}
+	if deletionAV, ok := item["Deletion"]; ok && deletionAV.M != nil {
+		if game.Deletion, err = unmarshalGameDeletion(deletionAV.M); err != nil {
+			return nil, err
+		}
+	}
+	if game.Version, err = unmarshalVersion(item); err != nil {
+		return nil, err
+	}
+	return game, nil
 }
 
 func (r *DynamoDBGameRepository) marshalGameToDynamoDBAttributeValue(game *models.Game) (map[string]*dynamodb.AttributeValue, error) {
 	av := make(map[string]*dynamodb.AttributeValue)
-	av["Id"] = &dynamodb.AttributeValue{S: aws.String("GAME_INFO")}
-	av["Range"] = &dynamodb.AttributeValue{S: aws.String(string(game.GameID))}
+	av["Id"] = &dynamodb.AttributeValue{S: aws.String(keys.GamePartition)}
+	av["Range"] = &dynamodb.AttributeValue{S: aws.String(keys.GameRange(game.GameID))}
 	av["Description"] = &dynamodb.AttributeValue{S: aws.String(game.Description)}
+	av[descriptionLowerAttribute] = &dynamodb.AttributeValue{S: aws.String(strings.ToLower(game.Description))}
 	av["Attributes"] = &dynamodb.AttributeValue{L: make([]*dynamodb.AttributeValue, len(game.Attributes))}
 
 	for i, attr := range game.Attributes {
@@ -182,5 +295,50 @@ func (r *DynamoDBGameRepository) marshalGameToDynamoDBAttributeValue(game *model
 		av["RankedAttributes"].L[i] = &dynamodb.AttributeValue{S: aws.String(string(attr))}
 	}
 
+	if game.RatingSystem != models.RatingSystemNone {
+		av["RatingSystem"] = &dynamodb.AttributeValue{S: aws.String(string(game.RatingSystem))}
+	}
+	if len(game.Aggregations) > 0 {
+		av["Aggregations"] = &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue, len(game.Aggregations))}
+		for attr, mode := range game.Aggregations {
+			av["Aggregations"].M[string(attr)] = &dynamodb.AttributeValue{S: aws.String(string(mode))}
+		}
+	}
+	if len(game.RemovedAggregations) > 0 {
+		av["RemovedAggregations"] = &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue, len(game.RemovedAggregations))}
+		for attr, mode := range game.RemovedAggregations {
+			av["RemovedAggregations"].M[string(attr)] = &dynamodb.AttributeValue{S: aws.String(string(mode))}
+		}
+	}
+	if len(game.RankDirections) > 0 {
+		av["RankDirections"] = &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue, len(game.RankDirections))}
+		for attr, direction := range game.RankDirections {
+			av["RankDirections"].M[string(attr)] = &dynamodb.AttributeValue{S: aws.String(string(direction))}
+		}
+	}
+	if game.Status != models.GameStatusActive {
+		av["Status"] = &dynamodb.AttributeValue{S: aws.String(string(game.Status))}
+	}
+	if game.Deletion != nil {
+		av["Deletion"] = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+			"Step":           {S: aws.String(string(game.Deletion.Step))},
+			"MatchesDeleted": {N: aws.String(strconv.Itoa(game.Deletion.MatchesDeleted))},
+		}}
+	}
+
 	return av, nil
 }
+
+func unmarshalGameDeletion(m map[string]*dynamodb.AttributeValue) (*models.GameDeletion, error) {
+	if m["Step"] == nil || m["Step"].S == nil || m["MatchesDeleted"] == nil || m["MatchesDeleted"].N == nil {
+		return nil, errors.New("error Deletion is missing or invalid")
+	}
+	matchesDeleted, err := strconv.Atoi(*m["MatchesDeleted"].N)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Deletion: %w", err)
+	}
+	return &models.GameDeletion{
+		Step:           models.GameDeletionStep(*m["Step"].S),
+		MatchesDeleted: matchesDeleted,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// userMatchMigrationPageSize is the number of history entries read at once
+// when rebuilding a user's GamesPlayed.
+const userMatchMigrationPageSize = 100
+
+// RebuildGamesPlayed sets the GamesPlayed of every user to the games in their
+// match history, storing it as the string set AddGamePlayed and
+// RemoveGamePlayed update. Users are written conditionally on the version
+// read, so the rebuild fails rather than overwrite a concurrent write, and
+// can be re-run safely. It returns the number of users rewritten.
+func RebuildGamesPlayed(db *dynamodb.DynamoDB, tableName string) (int, error) {
+	userRepository := &DynamoDBUserRepository{db: db, tableName: tableName}
+	matchRepository := &MatchDynamoDBRepository{db: db, tableName: tableName}
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("begins_with(Id, :prefix)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {S: aws.String(keys.UserPartitionPrefix)},
+		},
+	}
+
+	rewritten := 0
+	for {
+		output, err := db.Scan(input)
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to scan user items: %w", err)
+		}
+
+		for _, item := range output.Items {
+			user, err := userRepository.unmarshalUserFromDynamoDB(item)
+			if err != nil {
+				return rewritten, err
+			}
+			gamesPlayed, err := historyGames(matchRepository, user.UserID)
+			if err != nil {
+				return rewritten, err
+			}
+			// Items already holding the right set need no rewrite
+			isList := item["GamesPlayed"] != nil && item["GamesPlayed"].L != nil
+			if !isList && slices.Equal(user.GamesPlayed, gamesPlayed) {
+				continue
+			}
+			user.GamesPlayed = gamesPlayed
+			if _, err := userRepository.UpdateUser(user, nil); err != nil {
+				return rewritten, fmt.Errorf("failed to rebuild games played of %s: %w", user.UserID, err)
+			}
+			rewritten++
+		}
+
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	return rewritten, nil
+}
+
+// historyGames returns the games in the user's match history in GameID order.
+func historyGames(matchRepository *MatchDynamoDBRepository, userID models.UserID) ([]models.GameID, error) {
+	gameIDs := []models.GameID{}
+	cursor := ""
+	for {
+		history, err := matchRepository.GetUserMatches(userID, models.UserMatchFilter{}, userMatchMigrationPageSize, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read match history of %s: %w", userID, err)
+		}
+		for _, userMatch := range history.Matches {
+			if !slices.Contains(gameIDs, userMatch.GameID) {
+				gameIDs = append(gameIDs, userMatch.GameID)
+			}
+		}
+		if history.NextCursor == "" {
+			break
+		}
+		cursor = history.NextCursor
+	}
+	slices.Sort(gameIDs)
+	return gameIDs, nil
+}
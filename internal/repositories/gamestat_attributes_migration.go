@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// FoldLegacyGameStatAttributes moves the totals of the legacy Attributes map
+// of every all-time GameStat into the top-level totals and removes the map.
+// A summed attribute's legacy total is added to its top-level one, which only
+// holds the increments since. An attribute that is not summed keeps its
+// top-level value, recomputed from the player's whole history, and takes the
+// legacy one only if it has none. GameStats are written conditionally on the
+// version read, so the migration fails rather than lose a concurrent
+// increment, and can be re-run safely. It returns the number of GameStats
+// rewritten.
+func FoldLegacyGameStatAttributes(db *dynamodb.DynamoDB, tableName string) (int, error) {
+	gameRepository := &DynamoDBGameRepository{db: db, tableName: tableName}
+	games := map[models.GameID]*models.Game{}
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("begins_with(Id, :prefix) AND attribute_exists(Attributes)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {S: aws.String(keys.GameStatPartitionPrefix)},
+		},
+	}
+
+	rewritten := 0
+	for {
+		output, err := db.Scan(input)
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to scan GameStat items: %w", err)
+		}
+
+		for _, item := range output.Items {
+			if item["Id"] == nil || item["Id"].S == nil || item["Range"] == nil || item["Range"].S == nil {
+				return rewritten, errors.New("GameStat item is missing its key")
+			}
+			userID, gameID, _, err := keys.ParseGameStatKey(*item["Id"].S, *item["Range"].S)
+			if err != nil {
+				return rewritten, err
+			}
+			game, ok := games[gameID]
+			if !ok {
+				// The GameStats of a deleted game count as summed until
+				// they are deleted with it
+				game, err = gameRepository.GetGame(gameID)
+				if err != nil && !errors.Is(err, models.ErrNotFound) {
+					return rewritten, fmt.Errorf("failed to read game %s: %w", gameID, err)
+				}
+				games[gameID] = game
+			}
+			if err := foldLegacyGameStatItem(db, tableName, item, game); err != nil {
+				return rewritten, fmt.Errorf("failed to fold legacy stats of user %s in game %s: %w", userID, gameID, err)
+			}
+			rewritten++
+		}
+
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	return rewritten, nil
+}
+
+// foldLegacyGameStatItem sets the top-level totals folded from item's legacy
+// Attributes map and removes the map in one update conditional on the
+// version of item. A nil game counts every attribute as summed.
+func foldLegacyGameStatItem(db *dynamodb.DynamoDB, tableName string, item map[string]*dynamodb.AttributeValue, game *models.Game) error {
+	version, err := unmarshalVersion(item)
+	if err != nil {
+		return err
+	}
+	names := map[string]*string{
+		"#Version":    aws.String(versionAttribute),
+		"#Attributes": aws.String("Attributes"),
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":one":     {N: aws.String("1")},
+		":version": {N: aws.String(strconv.Itoa(version))},
+	}
+
+	attrNames := make([]string, 0, len(item["Attributes"].M))
+	for attrName := range item["Attributes"].M {
+		attrNames = append(attrNames, attrName)
+	}
+	slices.Sort(attrNames)
+	var assignments []string
+	for i, attrName := range attrNames {
+		legacy, err := strconv.Atoi(aws.StringValue(item["Attributes"].M[attrName].N))
+		if err != nil {
+			return err
+		}
+		total := legacy
+		if stat := item[gameStatAttributePrefix+attrName]; stat != nil && stat.N != nil {
+			value, err := strconv.Atoi(*stat.N)
+			if err != nil {
+				return err
+			}
+			if game != nil && game.Aggregation(models.AttributeName(attrName)) != models.AggregationSum {
+				continue
+			}
+			total += value
+		}
+		names[fmt.Sprintf("#s%d", i)] = aws.String(gameStatAttributePrefix + attrName)
+		values[fmt.Sprintf(":s%d", i)] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(total))}
+		assignments = append(assignments, fmt.Sprintf("#s%d = :s%d", i, i))
+	}
+
+	updateExpression := "REMOVE #Attributes ADD #Version :one"
+	if len(assignments) > 0 {
+		updateExpression = "SET " + strings.Join(assignments, ", ") + " " + updateExpression
+	}
+	_, err = db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String(tableName),
+		Key:                       map[string]*dynamodb.AttributeValue{"Id": item["Id"], "Range": item["Range"]},
+		UpdateExpression:          aws.String(updateExpression),
+		ConditionExpression:       aws.String(versionCondition(version)),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
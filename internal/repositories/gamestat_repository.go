@@ -6,8 +6,30 @@ import (
 )
 
 type GameStatRepository interface {
+	// WithScope returns a repository reading and writing the GameStats of
+	// scope instead. The all-time scope is the default.
+	WithScope(scope models.LeaderboardScope) GameStatRepository
 	GetGameStat(userID models.UserID, gameID models.GameID) (*models.GameStat, error)
+	// GetGameStatsByUser returns the user's GameStats in every game, ordered
+	// by GameID. When attrs is not empty, only those attributes are read.
+	// Only all-time GameStats are kept per user; repositories of other scopes
+	// answer with a validation error.
+	GetGameStatsByUser(userID models.UserID, attrs []models.AttributeName) ([]*models.GameStat, error)
 	CreateGameStat(gameStat *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error
+	// UpdateGameStat writes the GameStat if the stored one is still at
+	// gameStat.Version, 0 meaning none is stored yet, and sets
+	// gameStat.Version to the version written.
 	UpdateGameStat(gameStat *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error
+	// IncrementGameStat atomically adds deltas to the user's attribute totals
+	// in the game and sets the attributes in values, creating the GameStat if
+	// there is none. An attribute must not be in both. When basedOn is not
+	// nil, it is the GameStat the caller read and the increment applies only
+	// if the stored one is still at basedOn.Version; otherwise it always
+	// applies. Either way the version is incremented.
+	IncrementGameStat(userID models.UserID, gameID models.GameID, deltas models.AttributesStatsMap, values models.AttributesStatsMap, basedOn *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error
 	DeleteGameStat(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error
+	// DeleteGameStatsByGame deletes the GameStats of every player of the
+	// game. Only GameStats of scopes other than all-time are kept per game;
+	// the all-time repository answers with a validation error.
+	DeleteGameStatsByGame(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error
 }
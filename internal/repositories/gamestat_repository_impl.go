@@ -3,30 +3,38 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
 	"github.com/mquan1409/game-api/internal/models"
-	"github.com/mquan1409/game-api/internal/utils"
 )
 
+// gameStatAttributePrefix prefixes the top-level attributes holding the
+// totals of a GameStat, keeping them apart from Id, Range and Version.
+const gameStatAttributePrefix = "Stat."
+
 type GameStatDynamoDBRepository struct {
 	db        *dynamodb.DynamoDB
 	tableName string
+	scope     models.LeaderboardScope
 }
 
 func NewDynamoDBGameStatRepository(db *dynamodb.DynamoDB, tableName string) GameStatRepository {
 	return &GameStatDynamoDBRepository{db: db, tableName: tableName}
 }
 
+func (r *GameStatDynamoDBRepository) WithScope(scope models.LeaderboardScope) GameStatRepository {
+	return &GameStatDynamoDBRepository{db: r.db, tableName: r.tableName, scope: scope}
+}
+
 func (r *GameStatDynamoDBRepository) GetGameStat(userID models.UserID, gameID models.GameID) (*models.GameStat, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"Id":    {S: aws.String(fmt.Sprintf("GameStat.%s", userID))},
-			"Range": {S: aws.String(string(gameID))},
-		},
+		Key:       r.gameStatKey(userID, gameID),
 	}
 
 	result, err := r.db.GetItem(input)
@@ -34,40 +42,83 @@ func (r *GameStatDynamoDBRepository) GetGameStat(userID models.UserID, gameID mo
 		return nil, err
 	}
 	if result.Item == nil {
-		return nil, errors.New("game stat not found")
+		return nil, models.NewNotFoundError("user %s has no stats in game %s", userID, gameID)
 	}
 
 	return r.unmarshalGameStatFromDynamoDB(result.Item)
 }
 
-func (r *GameStatDynamoDBRepository) CreateGameStat(gameStat *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error {
-	if !utils.AttributesPositive(gameStat.GameAttributes) {
-		return errors.New("attributes must be positive")
+// GetGameStatsByUser reads the user's all-time GameStats with one Query of
+// their partition.
+func (r *GameStatDynamoDBRepository) GetGameStatsByUser(userID models.UserID, attrs []models.AttributeName) ([]*models.GameStat, error) {
+	if r.scope != models.AllTimeScope {
+		return nil, models.NewValidationError("GameStats of scope %s are kept per game and cannot be listed by user", r.scope)
 	}
-	av, err := r.marshalGameStatToDynamoDBAttributeValue(gameStat)
-	if err != nil {
-		return err
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		KeyConditionExpression: aws.String("Id = :id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {S: aws.String(keys.GameStatPartition(userID))},
+		},
 	}
-
-	input := &dynamodb.Put{
-		TableName: aws.String(r.tableName),
-		Item:      av,
+	if len(attrs) > 0 {
+		// Project the key, the version and the total of each attribute
+		names := map[string]*string{
+			"#Id":      aws.String("Id"),
+			"#Range":   aws.String("Range"),
+			"#Version": aws.String(versionAttribute),
+		}
+		projection := []string{"#Id", "#Range", "#Version"}
+		for i, attr := range attrs {
+			names[fmt.Sprintf("#s%d", i)] = aws.String(gameStatAttributePrefix + string(attr))
+			projection = append(projection, fmt.Sprintf("#s%d", i))
+		}
+		input.ProjectionExpression = aws.String(strings.Join(projection, ", "))
+		input.ExpressionAttributeNames = names
 	}
-	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Put: input})
+
+	gameStats := []*models.GameStat{}
+	for {
+		result, err := r.db.Query(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query game stats: %w", err)
+		}
+
+		for _, item := range result.Items {
+			gameStat, err := r.unmarshalGameStatFromDynamoDB(item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal game stat: %w", err)
+			}
+			gameStats = append(gameStats, gameStat)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
 	}
 
-	_, err = r.db.PutItem(&dynamodb.PutItemInput{
-		TableName: input.TableName,
-		Item:      input.Item,
+	// Escaping changes how some game IDs sort, so the sort key order is not
+	// quite GameID order
+	slices.SortFunc(gameStats, func(a, b *models.GameStat) int {
+		return strings.Compare(string(a.GameID), string(b.GameID))
 	})
-	return err
+	return gameStats, nil
+}
+
+func (r *GameStatDynamoDBRepository) CreateGameStat(gameStat *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error {
+	gameStat.Version = 0
+	return r.putGameStat(gameStat, models.NewConflictError("user %s already has stats in game %s", gameStat.UserID, gameStat.GameID), tx)
 }
 
 func (r *GameStatDynamoDBRepository) UpdateGameStat(gameStat *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error {
-	if !utils.AttributesPositive(gameStat.GameAttributes) {
-		return errors.New("attributes must be positive")
-	}
+	return r.putGameStat(gameStat, models.NewPreconditionFailedError("stats of user %s in game %s changed since version %d", gameStat.UserID, gameStat.GameID, gameStat.Version), tx)
+}
+
+// putGameStat writes the GameStat guarded by its version, returning failed
+// when the stored one is at another version, and then sets gameStat.Version
+// to the version written.
+func (r *GameStatDynamoDBRepository) putGameStat(gameStat *models.GameStat, failed error, tx *dynamodb.TransactWriteItemsInput) error {
 	av, err := r.marshalGameStatToDynamoDBAttributeValue(gameStat)
 	if err != nil {
 		return err
@@ -77,27 +128,92 @@ func (r *GameStatDynamoDBRepository) UpdateGameStat(gameStat *models.GameStat, t
 		TableName: aws.String(r.tableName),
 		Item:      av,
 	}
+	guardVersion(input, gameStat.Version)
+	if err := conditionalPut(r.db, input, tx, failed); err != nil {
+		return err
+	}
+	gameStat.Version++
+	return nil
+}
+
+// IncrementGameStat adds the deltas to the attribute totals with a single ADD
+// update, so concurrent increments of the same GameStat all apply. The totals
+// are top-level attributes, as ADD cannot update nested ones. The values of
+// attributes that are not summed are SET in the same update.
+func (r *GameStatDynamoDBRepository) IncrementGameStat(userID models.UserID, gameID models.GameID, deltas models.AttributesStatsMap, values models.AttributesStatsMap, basedOn *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error {
+	names := map[string]*string{"#Version": aws.String(versionAttribute)}
+	expressionValues := map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}}
+	additions := []string{"#Version :one"}
+
+	attrNames := make([]models.AttributeName, 0, len(deltas))
+	for attrName := range deltas {
+		attrNames = append(attrNames, attrName)
+	}
+	slices.Sort(attrNames)
+	for i, attrName := range attrNames {
+		names[fmt.Sprintf("#a%d", i)] = aws.String(gameStatAttributePrefix + string(attrName))
+		expressionValues[fmt.Sprintf(":a%d", i)] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(int(deltas[attrName])))}
+		additions = append(additions, fmt.Sprintf("#a%d :a%d", i, i))
+	}
+	updateExpression := "ADD " + strings.Join(additions, ", ")
+
+	setNames := make([]models.AttributeName, 0, len(values))
+	for attrName := range values {
+		setNames = append(setNames, attrName)
+	}
+	slices.Sort(setNames)
+	var assignments []string
+	for i, attrName := range setNames {
+		names[fmt.Sprintf("#s%d", i)] = aws.String(gameStatAttributePrefix + string(attrName))
+		expressionValues[fmt.Sprintf(":s%d", i)] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(int(values[attrName])))}
+		assignments = append(assignments, fmt.Sprintf("#s%d = :s%d", i, i))
+	}
+	if len(assignments) > 0 {
+		updateExpression += " SET " + strings.Join(assignments, ", ")
+	}
+
+	update := &dynamodb.Update{
+		TableName:                 aws.String(r.tableName),
+		Key:                       r.gameStatKey(userID, gameID),
+		UpdateExpression:          aws.String(updateExpression),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: expressionValues,
+	}
+	if basedOn != nil {
+		update.ConditionExpression = aws.String(versionCondition(basedOn.Version))
+		if basedOn.Version != 0 {
+			expressionValues[":version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(basedOn.Version))}
+		}
+	}
+
 	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Put: input})
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Update: update})
+		return nil
 	}
 
-	_, err = r.db.PutItem(&dynamodb.PutItemInput{
-		TableName: input.TableName,
-		Item:      input.Item,
+	_, err := r.db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ConditionExpression:       update.ConditionExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
 	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return models.NewPreconditionFailedError("stats of user %s in game %s changed since version %d", userID, gameID, basedOn.Version)
+	}
 	return err
 }
 
 func (r *GameStatDynamoDBRepository) DeleteGameStat(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
 	input := &dynamodb.Delete{
 		TableName: aws.String(r.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"Id":    {S: aws.String(fmt.Sprintf("GameStat.%s", userID))},
-			"Range": {S: aws.String(string(gameID))},
-		},
+		Key:       r.gameStatKey(userID, gameID),
 	}
 	if tx != nil {	
 		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Delete: input})
+		return nil
 	}
 
 	_, err := r.db.DeleteItem(&dynamodb.DeleteItemInput{
@@ -107,18 +223,71 @@ func (r *GameStatDynamoDBRepository) DeleteGameStat(userID models.UserID, gameID
 	return err
 }
 
+// DeleteGameStatsByGame queries the keys of the game's partition in the scope
+// a page at a time. Without a transaction each GameStat is deleted as soon as
+// it is read, so a deletion that fails part way can be retried and only finds
+// the rest.
+func (r *GameStatDynamoDBRepository) DeleteGameStatsByGame(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	if r.scope == models.AllTimeScope {
+		return models.NewValidationError("all-time GameStats are kept per user and cannot be deleted by game")
+	}
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		KeyConditionExpression: aws.String("Id = :id"),
+		ProjectionExpression:   aws.String("Id, #range"),
+		ExpressionAttributeNames: map[string]*string{
+			"#range": aws.String("Range"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {S: aws.String(keys.ScopedGameStatPartition(gameID, r.scope))},
+		},
+	}
+	for {
+		result, err := r.db.Query(input)
+		if err != nil {
+			return fmt.Errorf("failed to query GameStats of game %s: %w", gameID, err)
+		}
+
+		for _, item := range result.Items {
+			deleteItem := &dynamodb.Delete{
+				TableName: aws.String(r.tableName),
+				Key:       map[string]*dynamodb.AttributeValue{"Id": item["Id"], "Range": item["Range"]},
+			}
+			if tx != nil {
+				tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Delete: deleteItem})
+				continue
+			}
+			if _, err := r.db.DeleteItem(&dynamodb.DeleteItemInput{TableName: deleteItem.TableName, Key: deleteItem.Key}); err != nil {
+				return err
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
 func (r *GameStatDynamoDBRepository) unmarshalGameStatFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*models.GameStat, error) {
 	// Extract UserID and GameID
-	idParts := strings.SplitN(*item["Id"].S, ".", 2)
-	if len(idParts) != 2 {
-		return nil, errors.New("invalid Id format")
+	if item["Id"] == nil || item["Id"].S == nil || item["Range"] == nil || item["Range"].S == nil {
+		return nil, errors.New("GameStat item is missing its key")
+	}
+	userID, gameID, _, err := keys.ParseGameStatKey(*item["Id"].S, *item["Range"].S)
+	if err != nil {
+		return nil, err
 	}
-	userID := models.UserID(idParts[1])
-	gameID := models.GameID(*item["Range"].S)
 
-	// Extract GameAttributes
+	// Extract GameAttributes. The Attributes map of GameStats written before
+	// the totals were top-level attributes is folded into them by
+	// FoldLegacyGameStatAttributes
 	gameAttributes := make(models.AttributesStatsMap)
-	for attrName, attrValue := range item["Attributes"].M {
+	for name, attrValue := range item {
+		attrName, ok := strings.CutPrefix(name, gameStatAttributePrefix)
+		if !ok || attrValue.N == nil {
+			continue
+		}
 		value, err := strconv.Atoi(*attrValue.N)
 		if err != nil {
 			return nil, err
@@ -126,22 +295,36 @@ func (r *GameStatDynamoDBRepository) unmarshalGameStatFromDynamoDB(item map[stri
 		gameAttributes[models.AttributeName(attrName)] = models.AttributeStat(value)
 	}
 
-	return models.NewGameStat(userID, gameID, gameAttributes)
+	gameStat, err := models.NewGameStat(userID, gameID, gameAttributes)
+	if err != nil {
+		return nil, err
+	}
+	if gameStat.Version, err = unmarshalVersion(item); err != nil {
+		return nil, err
+	}
+	return gameStat, nil
 }
 
 func (r *GameStatDynamoDBRepository) marshalGameStatToDynamoDBAttributeValue(gameStat *models.GameStat) (map[string]*dynamodb.AttributeValue, error) {
 	av := make(map[string]*dynamodb.AttributeValue)
 
 	// Set Id and Range
-	av["Id"] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("GameStat.%s", gameStat.UserID))}
-	av["Range"] = &dynamodb.AttributeValue{S: aws.String(string(gameStat.GameID))}
+	for name, value := range r.gameStatKey(gameStat.UserID, gameStat.GameID) {
+		av[name] = value
+	}
 
-	// Set Attributes
-	attributes := make(map[string]*dynamodb.AttributeValue)
+	// Set the attribute totals
 	for attrName, attrValue := range gameStat.GameAttributes {
-		attributes[string(attrName)] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(int(attrValue)))}
+		av[gameStatAttributePrefix+string(attrName)] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(int(attrValue)))}
 	}
-	av["Attributes"] = &dynamodb.AttributeValue{M: attributes}
 
 	return av, nil
 }
+
+func (r *GameStatDynamoDBRepository) gameStatKey(userID models.UserID, gameID models.GameID) map[string]*dynamodb.AttributeValue {
+	id, rangeKey := keys.GameStatKey(userID, gameID, r.scope)
+	return map[string]*dynamodb.AttributeValue{
+		"Id":    {S: aws.String(id)},
+		"Range": {S: aws.String(rangeKey)},
+	}
+}
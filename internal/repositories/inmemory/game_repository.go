@@ -0,0 +1,151 @@
+package inmemory
+
+import (
+	"slices"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type InMemoryGameRepository struct {
+	store *Store
+}
+
+func NewInMemoryGameRepository(store *Store) repositories.GameRepository {
+	return &InMemoryGameRepository{store: store}
+}
+
+func (r *InMemoryGameRepository) GetGame(id models.GameID) (*models.Game, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	game, ok := r.store.games[id]
+	if !ok {
+		return nil, models.NewNotFoundError("game %s not found", id)
+	}
+	return copyGame(game), nil
+}
+
+func (r *InMemoryGameRepository) GetGames(filter models.GameFilter, limit int, cursor string) (models.GamePage, error) {
+	if limit < 1 {
+		return models.GamePage{}, models.NewValidationError("limit must be at least 1")
+	}
+	startRange, err := repositories.DecodeRangeCursor(cursor)
+	if err != nil {
+		return models.GamePage{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var gameIDs []models.GameID
+	for id, game := range r.store.games {
+		if string(id) > startRange && filter.Matches(game) {
+			gameIDs = append(gameIDs, id)
+		}
+	}
+	slices.Sort(gameIDs)
+
+	page := models.GamePage{Games: []*models.Game{}, Limit: limit}
+	if len(gameIDs) > limit {
+		gameIDs = gameIDs[:limit]
+		page.NextCursor = repositories.EncodeRangeCursor(string(gameIDs[limit-1]))
+	}
+	for _, id := range gameIDs {
+		page.Games = append(page.Games, copyGame(r.store.games[id]))
+	}
+	return page, nil
+}
+
+func (r *InMemoryGameRepository) CreateGame(game *models.Game, tx *dynamodb.TransactWriteItemsInput) (*models.Game, error) {
+	if err := r.putGame(game, 0, models.NewConflictError("game %s already exists", game.GameID), tx); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func (r *InMemoryGameRepository) UpdateGame(game *models.Game, tx *dynamodb.TransactWriteItemsInput) (*models.Game, error) {
+	if game.Version == 0 {
+		return nil, models.NewValidationError("game %s has no version to update", game.GameID)
+	}
+	if err := r.putGame(game, game.Version, models.NewPreconditionFailedError("game %s changed since version %d", game.GameID, game.Version), tx); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func (r *InMemoryGameRepository) DeleteGame(id models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.write(tx, deleteItem(keys.GamePartition, keys.GameRange(id)), func() {
+		delete(r.store.games, id)
+	})
+	return nil
+}
+
+// putGame writes the game if the stored one is at version, 0 meaning there is
+// none, returning failed otherwise, and sets game.Version to the new version.
+func (r *InMemoryGameRepository) putGame(game *models.Game, version int, failed error, tx *dynamodb.TransactWriteItemsInput) error {
+	stored := copyGame(game)
+	stored.Version = version + 1
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current := func() int {
+		if game, ok := r.store.games[stored.GameID]; ok {
+			return game.Version
+		}
+		return 0
+	}
+	err := r.store.writeIf(tx, putItem(keys.GamePartition, keys.GameRange(game.GameID)), checkVersion(current, version, failed), func() {
+		r.store.games[stored.GameID] = stored
+	})
+	if err != nil {
+		return err
+	}
+	game.Version = stored.Version
+	return nil
+}
+
+func copyGame(game *models.Game) *models.Game {
+	attributes := make([]models.AttributeName, len(game.Attributes))
+	copy(attributes, game.Attributes)
+	rankedAttributes := make([]models.AttributeName, len(game.RankedAttributes))
+	copy(rankedAttributes, game.RankedAttributes)
+	copied := &models.Game{
+		GameID:           game.GameID,
+		Description:      game.Description,
+		Attributes:       attributes,
+		RankedAttributes: rankedAttributes,
+		RatingSystem:     game.RatingSystem,
+		Status:           game.Status,
+		Version:          game.Version,
+	}
+	if game.Aggregations != nil {
+		copied.Aggregations = make(map[models.AttributeName]models.AggregationMode, len(game.Aggregations))
+		for attr, mode := range game.Aggregations {
+			copied.Aggregations[attr] = mode
+		}
+	}
+	if game.RemovedAggregations != nil {
+		copied.RemovedAggregations = make(map[models.AttributeName]models.AggregationMode, len(game.RemovedAggregations))
+		for attr, mode := range game.RemovedAggregations {
+			copied.RemovedAggregations[attr] = mode
+		}
+	}
+	if game.RankDirections != nil {
+		copied.RankDirections = make(map[models.AttributeName]models.SortDirection, len(game.RankDirections))
+		for attr, direction := range game.RankDirections {
+			copied.RankDirections[attr] = direction
+		}
+	}
+	if game.Deletion != nil {
+		deletion := *game.Deletion
+		copied.Deletion = &deletion
+	}
+	return copied
+}
@@ -0,0 +1,178 @@
+package inmemory
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type InMemoryGameStatRepository struct {
+	store *Store
+	scope models.LeaderboardScope
+}
+
+func NewInMemoryGameStatRepository(store *Store) repositories.GameStatRepository {
+	return &InMemoryGameStatRepository{store: store}
+}
+
+func (r *InMemoryGameStatRepository) WithScope(scope models.LeaderboardScope) repositories.GameStatRepository {
+	return &InMemoryGameStatRepository{store: r.store, scope: scope}
+}
+
+func (r *InMemoryGameStatRepository) GetGameStat(userID models.UserID, gameID models.GameID) (*models.GameStat, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	gameStat, ok := r.store.gameStats[userID][keys.ScopedGameKey(gameID, r.scope)]
+	if !ok {
+		return nil, models.NewNotFoundError("user %s has no stats in game %s", userID, gameID)
+	}
+	return copyGameStat(gameStat), nil
+}
+
+func (r *InMemoryGameStatRepository) GetGameStatsByUser(userID models.UserID, attrs []models.AttributeName) ([]*models.GameStat, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.scope != models.AllTimeScope {
+		return nil, models.NewValidationError("GameStats of scope %s are kept per game and cannot be listed by user", r.scope)
+	}
+	gameStats := []*models.GameStat{}
+	for rangeKey, gameStat := range r.store.gameStats[userID] {
+		if _, scope, err := keys.ParseScopedGameKey(rangeKey); err != nil || scope != models.AllTimeScope {
+			continue
+		}
+		copied := copyGameStat(gameStat)
+		if len(attrs) > 0 {
+			copied.GameAttributes = models.AttributesStatsMap{}
+			for _, attr := range attrs {
+				if value, ok := gameStat.GameAttributes[attr]; ok {
+					copied.GameAttributes[attr] = value
+				}
+			}
+		}
+		gameStats = append(gameStats, copied)
+	}
+	slices.SortFunc(gameStats, func(a, b *models.GameStat) int {
+		return strings.Compare(string(a.GameID), string(b.GameID))
+	})
+	return gameStats, nil
+}
+
+func (r *InMemoryGameStatRepository) CreateGameStat(gameStat *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error {
+	return r.putGameStat(gameStat, 0, models.NewConflictError("user %s already has stats in game %s", gameStat.UserID, gameStat.GameID), tx)
+}
+
+func (r *InMemoryGameStatRepository) UpdateGameStat(gameStat *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error {
+	return r.putGameStat(gameStat, gameStat.Version, models.NewPreconditionFailedError("stats of user %s in game %s changed since version %d", gameStat.UserID, gameStat.GameID, gameStat.Version), tx)
+}
+
+func (r *InMemoryGameStatRepository) IncrementGameStat(userID models.UserID, gameID models.GameID, deltas models.AttributesStatsMap, values models.AttributesStatsMap, basedOn *models.GameStat, tx *dynamodb.TransactWriteItemsInput) error {
+	deltas = copyAttributes(deltas)
+	values = copyAttributes(values)
+	rangeKey := keys.ScopedGameKey(gameID, r.scope)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var check func() error
+	if basedOn != nil {
+		check = checkVersion(func() int {
+			if gameStat, ok := r.store.gameStats[userID][rangeKey]; ok {
+				return gameStat.Version
+			}
+			return 0
+		}, basedOn.Version, models.NewPreconditionFailedError("stats of user %s in game %s changed since version %d", userID, gameID, basedOn.Version))
+	}
+	return r.store.writeIf(tx, updateItem(keys.GameStatKey(userID, gameID, r.scope)), check, func() {
+		if r.store.gameStats[userID] == nil {
+			r.store.gameStats[userID] = make(map[string]*models.GameStat)
+		}
+		gameStat, ok := r.store.gameStats[userID][rangeKey]
+		if !ok {
+			gameStat = &models.GameStat{UserID: userID, GameID: gameID, GameAttributes: models.AttributesStatsMap{}}
+			r.store.gameStats[userID][rangeKey] = gameStat
+		}
+		for attrName, delta := range deltas {
+			gameStat.GameAttributes[attrName] += delta
+		}
+		for attrName, value := range values {
+			gameStat.GameAttributes[attrName] = value
+		}
+		gameStat.Version++
+	})
+}
+
+func (r *InMemoryGameStatRepository) DeleteGameStat(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rangeKey := keys.ScopedGameKey(gameID, r.scope)
+	r.store.write(tx, deleteItem(keys.GameStatKey(userID, gameID, r.scope)), func() {
+		delete(r.store.gameStats[userID], rangeKey)
+	})
+	return nil
+}
+
+func (r *InMemoryGameStatRepository) DeleteGameStatsByGame(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	if r.scope == models.AllTimeScope {
+		return models.NewValidationError("all-time GameStats are kept per user and cannot be deleted by game")
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rangeKey := keys.ScopedGameKey(gameID, r.scope)
+	for userID, gameStats := range r.store.gameStats {
+		if _, ok := gameStats[rangeKey]; !ok {
+			continue
+		}
+		r.store.write(tx, deleteItem(keys.GameStatKey(userID, gameID, r.scope)), func() {
+			delete(r.store.gameStats[userID], rangeKey)
+		})
+	}
+	return nil
+}
+
+// putGameStat writes the GameStat if the stored one is at version, 0 meaning
+// there is none, returning failed otherwise, and sets gameStat.Version to the
+// new version.
+func (r *InMemoryGameStatRepository) putGameStat(gameStat *models.GameStat, version int, failed error, tx *dynamodb.TransactWriteItemsInput) error {
+	stored := copyGameStat(gameStat)
+	stored.Version = version + 1
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rangeKey := keys.ScopedGameKey(gameStat.GameID, r.scope)
+	current := func() int {
+		if gameStat, ok := r.store.gameStats[stored.UserID][rangeKey]; ok {
+			return gameStat.Version
+		}
+		return 0
+	}
+	err := r.store.writeIf(tx, putItem(keys.GameStatKey(gameStat.UserID, gameStat.GameID, r.scope)), checkVersion(current, version, failed), func() {
+		if r.store.gameStats[stored.UserID] == nil {
+			r.store.gameStats[stored.UserID] = make(map[string]*models.GameStat)
+		}
+		r.store.gameStats[stored.UserID][rangeKey] = stored
+	})
+	if err != nil {
+		return err
+	}
+	gameStat.Version = stored.Version
+	return nil
+}
+
+func copyGameStat(gameStat *models.GameStat) *models.GameStat {
+	return &models.GameStat{
+		UserID:         gameStat.UserID,
+		GameID:         gameStat.GameID,
+		GameAttributes: copyAttributes(gameStat.GameAttributes),
+		Version:        gameStat.Version,
+	}
+}
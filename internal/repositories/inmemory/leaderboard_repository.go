@@ -0,0 +1,230 @@
+package inmemory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+// InMemoryLeaderboardRepository keeps each game's leaderboard as the same
+// Range keys the DynamoDB repository writes, so ordering and prefix matching
+// behave identically.
+type InMemoryLeaderboardRepository struct {
+	store *Store
+	scope models.LeaderboardScope
+}
+
+func NewInMemoryLeaderboardRepository(store *Store) repositories.LeaderboardRepository {
+	return &InMemoryLeaderboardRepository{store: store}
+}
+
+func (r *InMemoryLeaderboardRepository) WithScope(scope models.LeaderboardScope) repositories.LeaderboardRepository {
+	return &InMemoryLeaderboardRepository{store: r.store, scope: scope}
+}
+
+func (r *InMemoryLeaderboardRepository) GetLeaderboard(gameID models.GameID, attr models.AttributeName, direction models.SortDirection) (models.LeaderBoard, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entries, _ := r.rankedEntries(gameID, attr, direction, "", 0, 0)
+	return models.NewLeaderBoard(gameID, attr, entries), nil
+}
+
+func (r *InMemoryLeaderboardRepository) GetBoundedLeaderboard(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, limit int, cursor string) (models.BoundedLeaderboard, error) {
+	if limit < 1 {
+		return models.BoundedLeaderboard{}, models.NewValidationError("limit must be at least 1")
+	}
+
+	startRange, startRank, err := repositories.DecodeLeaderboardCursor(cursor, keys.LeaderboardPartition(gameID, r.scope), attr, direction)
+	if err != nil {
+		return models.BoundedLeaderboard{}, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entries, lastRange := r.rankedEntries(gameID, attr, direction, startRange, startRank, limit)
+	leaderboard := models.NewBoundedLeaderBoard(gameID, attr, entries, limit)
+	if lastRange != "" {
+		leaderboard.NextCursor = repositories.EncodeLeaderboardCursor(keys.LeaderboardPartition(gameID, r.scope), direction, lastRange, startRank+len(entries))
+	}
+	return leaderboard, nil
+}
+
+func (r *InMemoryLeaderboardRepository) GetLeaderboardAroundUser(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, userID models.UserID, value models.AttributeStat, window int) (models.LeaderboardAroundUser, error) {
+	if window < 0 {
+		return models.LeaderboardAroundUser{}, models.NewValidationError("window cannot be negative")
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entries, _ := r.rankedEntries(gameID, attr, direction, "", 0, 0)
+	position := -1
+	for i, entry := range entries {
+		if entry.UserID == userID && entry.Value == value {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return models.LeaderboardAroundUser{}, models.NewNotFoundError("user %s is not on the %s leaderboard for game %s", userID, attr, gameID)
+	}
+
+	from := position - window
+	if from < 0 {
+		from = 0
+	}
+	to := position + window + 1
+	if to > len(entries) {
+		to = len(entries)
+	}
+
+	return models.NewLeaderboardAroundUser(gameID, attr, entries[from:to], userID, position+1, window), nil
+}
+
+func (r *InMemoryLeaderboardRepository) AddLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.addItem(gameID, userID, attr, value, tx)
+	return nil
+}
+
+func (r *InMemoryLeaderboardRepository) UpdateLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, oldValue models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error {
+	if oldValue == value {
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.deleteItem(gameID, userID, attr, oldValue, tx)
+	r.addItem(gameID, userID, attr, value, tx)
+	return nil
+}
+
+func (r *InMemoryLeaderboardRepository) DeleteLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, oldValue models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.deleteItem(gameID, userID, attr, oldValue, tx)
+	return nil
+}
+
+func (r *InMemoryLeaderboardRepository) DeleteLeaderboardItemsByGame(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.deleteItemsWhere(gameID, tx, func(rangeKey string, userID models.UserID) bool {
+		return true
+	})
+	return nil
+}
+
+func (r *InMemoryLeaderboardRepository) DeleteLeaderboardItemsByGameAndUser(gameID models.GameID, userID models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.deleteItemsWhere(gameID, tx, func(rangeKey string, itemUserID models.UserID) bool {
+		return itemUserID == userID
+	})
+	return nil
+}
+
+func (r *InMemoryLeaderboardRepository) DeleteLeaderboardItemsByGameAndAttribute(gameID models.GameID, attr models.AttributeName, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	prefix := keys.LeaderboardAttributePrefix(attr)
+	r.deleteItemsWhere(gameID, tx, func(rangeKey string, userID models.UserID) bool {
+		return strings.HasPrefix(rangeKey, prefix)
+	})
+	return nil
+}
+
+// rankedEntries returns the attribute's leaderboard in Range key order,
+// descending unless direction is SortAscending, the same order a Query with
+// the matching ScanIndexForward yields, starting after startRange when it is
+// set and ranking entries after startRank. A limit of 0 returns every entry.
+// When entries remain past the limit, lastRange is the Range key of the last
+// one returned. Callers must hold r.store.mu.
+func (r *InMemoryLeaderboardRepository) rankedEntries(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, startRange string, startRank int, limit int) (entries []models.LeaderboardEntry, lastRange string) {
+	board := keys.ScopedGameKey(gameID, r.scope)
+	prefix := keys.LeaderboardAttributePrefix(attr)
+	ascending := direction == models.SortAscending
+	var rangeKeys []string
+	for rangeKey := range r.store.leaderboards[board] {
+		after := rangeKey < startRange
+		if ascending {
+			after = rangeKey > startRange
+		}
+		if strings.HasPrefix(rangeKey, prefix) && (startRange == "" || after) {
+			rangeKeys = append(rangeKeys, rangeKey)
+		}
+	}
+	if ascending {
+		sort.Strings(rangeKeys)
+	} else {
+		sort.Sort(sort.Reverse(sort.StringSlice(rangeKeys)))
+	}
+
+	if limit > 0 && len(rangeKeys) > limit {
+		rangeKeys = rangeKeys[:limit]
+		lastRange = rangeKeys[limit-1]
+	}
+
+	entries = []models.LeaderboardEntry{}
+	for i, rangeKey := range rangeKeys {
+		userID := r.store.leaderboards[board][rangeKey]
+		_, value, _, _ := keys.ParseLeaderboardRange(rangeKey)
+		entries = append(entries, models.LeaderboardEntry{Rank: startRank + i + 1, UserID: userID, Value: value})
+	}
+	return entries, lastRange
+}
+
+// Callers must hold r.store.mu.
+func (r *InMemoryLeaderboardRepository) addItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) {
+	board := keys.ScopedGameKey(gameID, r.scope)
+	rangeKey := keys.LeaderboardRange(attr, value, userID)
+	r.store.write(tx, putItem(leaderboardPartition(board), rangeKey), func() {
+		if r.store.leaderboards[board] == nil {
+			r.store.leaderboards[board] = make(map[string]models.UserID)
+		}
+		r.store.leaderboards[board][rangeKey] = userID
+	})
+}
+
+// deleteItem stages a delete only when the item exists, like the DynamoDB
+// repository does. Callers must hold r.store.mu.
+func (r *InMemoryLeaderboardRepository) deleteItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, oldValue models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) {
+	board := keys.ScopedGameKey(gameID, r.scope)
+	rangeKey := keys.LeaderboardRange(attr, oldValue, userID)
+	if _, ok := r.store.leaderboards[board][rangeKey]; !ok {
+		return
+	}
+	r.store.write(tx, deleteItem(leaderboardPartition(board), rangeKey), func() {
+		delete(r.store.leaderboards[board], rangeKey)
+	})
+}
+
+// Callers must hold r.store.mu.
+func (r *InMemoryLeaderboardRepository) deleteItemsWhere(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput, match func(rangeKey string, userID models.UserID) bool) {
+	board := keys.ScopedGameKey(gameID, r.scope)
+	for rangeKey, userID := range r.store.leaderboards[board] {
+		if !match(rangeKey, userID) {
+			continue
+		}
+		r.store.write(tx, deleteItem(leaderboardPartition(board), rangeKey), func() {
+			delete(r.store.leaderboards[board], rangeKey)
+		})
+	}
+}
+
+func leaderboardPartition(board string) string {
+	return keys.LeaderboardPartitionPrefix + board
+}
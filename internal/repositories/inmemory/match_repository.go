@@ -0,0 +1,399 @@
+package inmemory
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type InMemoryMatchRepository struct {
+	store *Store
+}
+
+func NewInMemoryMatchRepository(store *Store) repositories.MatchRepository {
+	return &InMemoryMatchRepository{store: store}
+}
+
+func (r *InMemoryMatchRepository) GetMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) (*models.Match, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	match, ok := r.store.matches[gameID][keys.MatchRange(dateID, matchID)]
+	if !ok {
+		return nil, models.NewNotFoundError("match %s of game %s on %s not found", matchID, gameID, dateID)
+	}
+	return copyMatch(match), nil
+}
+
+func (r *InMemoryMatchRepository) GetMatchesByGameAndDate(gameID models.GameID, dateID models.DateID) ([]*models.Match, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	prefix := keys.DatePrefix(dateID)
+	var rangeKeys []string
+	for rangeKey := range r.store.matches[gameID] {
+		if strings.HasPrefix(rangeKey, prefix) {
+			rangeKeys = append(rangeKeys, rangeKey)
+		}
+	}
+	sort.Strings(rangeKeys)
+
+	var matches []*models.Match
+	for _, rangeKey := range rangeKeys {
+		matches = append(matches, copyMatch(r.store.matches[gameID][rangeKey]))
+	}
+	return matches, nil
+}
+
+func (r *InMemoryMatchRepository) GetMatchesByGame(gameID models.GameID) ([]*models.Match, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var rangeKeys []string
+	for rangeKey := range r.store.matches[gameID] {
+		rangeKeys = append(rangeKeys, rangeKey)
+	}
+	sort.Strings(rangeKeys)
+
+	var matches []*models.Match
+	for _, rangeKey := range rangeKeys {
+		matches = append(matches, copyMatch(r.store.matches[gameID][rangeKey]))
+	}
+	return matches, nil
+}
+
+func (r *InMemoryMatchRepository) GetMatchesByGameAndDateRange(gameID models.GameID, dates models.DateRange, limit int, cursor string) (models.MatchPage, error) {
+	if limit < 1 {
+		return models.MatchPage{}, models.NewValidationError("limit must be at least 1")
+	}
+	startRange, err := repositories.DecodeRangeCursor(cursor)
+	if err != nil {
+		return models.MatchPage{}, err
+	}
+	start, end := keys.DateRangeStart(dates.From), keys.DateRangeEnd(dates.To)
+	if startRange != "" && (startRange < start || startRange > end) {
+		return models.MatchPage{}, repositories.ErrInvalidCursor
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var rangeKeys []string
+	for rangeKey := range r.store.matches[gameID] {
+		if rangeKey >= start && rangeKey <= end && rangeKey > startRange {
+			rangeKeys = append(rangeKeys, rangeKey)
+		}
+	}
+	sort.Strings(rangeKeys)
+
+	page := models.MatchPage{GameID: gameID, DateRange: dates, Matches: []*models.Match{}, Limit: limit}
+	if len(rangeKeys) > limit {
+		rangeKeys = rangeKeys[:limit]
+		page.NextCursor = repositories.EncodeRangeCursor(rangeKeys[limit-1])
+	}
+	for _, rangeKey := range rangeKeys {
+		page.Matches = append(page.Matches, copyMatch(r.store.matches[gameID][rangeKey]))
+	}
+	return page, nil
+}
+
+func (r *InMemoryMatchRepository) CreateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error) {
+	if err := r.putMatch(match, 0, models.NewConflictError("match %s of game %s on %s already exists", match.MatchID, match.GameID, match.DateID), tx); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+func (r *InMemoryMatchRepository) UpdateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error) {
+	if match.Version == 0 {
+		return nil, models.NewValidationError("match %s of game %s on %s has no version to update", match.MatchID, match.GameID, match.DateID)
+	}
+
+	r.store.mu.Lock()
+	_, ok := r.store.matches[match.GameID][keys.MatchRange(match.DateID, match.MatchID)]
+	r.store.mu.Unlock()
+	if !ok {
+		return nil, models.NewNotFoundError("match %s of game %s on %s not found", match.MatchID, match.GameID, match.DateID)
+	}
+
+	if err := r.putMatch(match, match.Version, models.NewPreconditionFailedError("match %s of game %s on %s changed since version %d", match.MatchID, match.GameID, match.DateID, match.Version), tx); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+func (r *InMemoryMatchRepository) DeleteMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rangeKey := keys.MatchRange(dateID, matchID)
+	oldMatch := r.store.matches[gameID][rangeKey]
+	r.store.write(tx, deleteItem(keys.MatchPartition(gameID), rangeKey), func() {
+		delete(r.store.matches[gameID], rangeKey)
+	})
+	if oldMatch != nil {
+		for _, userMatch := range oldMatch.UserMatches() {
+			r.deleteUserMatch(userMatch, tx)
+		}
+	}
+	return nil
+}
+
+// GetUserMatches pages through the user's match history like the DynamoDB
+// repository: newest first, continuing after the Range key in cursor.
+func (r *InMemoryMatchRepository) GetUserMatches(userID models.UserID, filter models.UserMatchFilter, limit int, cursor string) (models.UserMatchHistory, error) {
+	if limit < 1 {
+		return models.UserMatchHistory{}, models.NewValidationError("limit must be at least 1")
+	}
+	startRange, err := repositories.DecodeRangeCursor(cursor)
+	if err != nil {
+		return models.UserMatchHistory{}, err
+	}
+	if startRange != "" && !userMatchRangeInFilter(startRange, filter) {
+		return models.UserMatchHistory{}, repositories.ErrInvalidCursor
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var rangeKeys []string
+	for rangeKey, userMatch := range r.store.userMatches[userID] {
+		if !userMatchRangeInFilter(rangeKey, filter) || (startRange != "" && rangeKey >= startRange) {
+			continue
+		}
+		if filter.GameID != "" && userMatch.GameID != filter.GameID {
+			continue
+		}
+		rangeKeys = append(rangeKeys, rangeKey)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(rangeKeys)))
+
+	history := models.UserMatchHistory{UserID: userID, Matches: []*models.UserMatch{}, Limit: limit}
+	if len(rangeKeys) > limit {
+		rangeKeys = rangeKeys[:limit]
+		history.NextCursor = repositories.EncodeRangeCursor(rangeKeys[limit-1])
+	}
+	for _, rangeKey := range rangeKeys {
+		history.Matches = append(history.Matches, copyUserMatch(r.store.userMatches[userID][rangeKey]))
+	}
+	return history, nil
+}
+
+func (r *InMemoryMatchRepository) GetMatchFollowUps() ([]*models.MatchFollowUp, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rangeKeys := make([]string, 0, len(r.store.matchFollowUps))
+	for rangeKey := range r.store.matchFollowUps {
+		rangeKeys = append(rangeKeys, rangeKey)
+	}
+	// DynamoDB returns the partition in sort key order
+	sort.Strings(rangeKeys)
+
+	followUps := []*models.MatchFollowUp{}
+	for _, rangeKey := range rangeKeys {
+		followUps = append(followUps, copyMatchFollowUp(r.store.matchFollowUps[rangeKey]))
+	}
+	return followUps, nil
+}
+
+func (r *InMemoryMatchRepository) GetMatchFollowUp(gameID models.GameID, dateID models.DateID) (*models.MatchFollowUp, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	followUp, ok := r.store.matchFollowUps[keys.MatchFollowUpRange(gameID, dateID)]
+	if !ok {
+		return nil, models.NewNotFoundError("matches of game %s on %s have no pending follow-up", gameID, dateID)
+	}
+	return copyMatchFollowUp(followUp), nil
+}
+
+func (r *InMemoryMatchRepository) RequestMatchFollowUp(gameID models.GameID, dateID models.DateID, players, leavers []models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
+	rangeKey := keys.MatchFollowUpRange(gameID, dateID)
+	players, leavers = slices.Clone(players), slices.Clone(leavers)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.write(tx, updateItem(keys.MatchFollowUpPartition, rangeKey), func() {
+		followUp, ok := r.store.matchFollowUps[rangeKey]
+		if !ok {
+			followUp = &models.MatchFollowUp{GameID: gameID, DateID: dateID, Players: []models.UserID{}, Leavers: []models.UserID{}}
+			r.store.matchFollowUps[rangeKey] = followUp
+		}
+		followUp.Players = addToSet(followUp.Players, players)
+		followUp.Leavers = addToSet(followUp.Leavers, leavers)
+		followUp.Version++
+	})
+	return nil
+}
+
+func (r *InMemoryMatchRepository) DeleteMatchFollowUp(followUp *models.MatchFollowUp, tx *dynamodb.TransactWriteItemsInput) error {
+	rangeKey := keys.MatchFollowUpRange(followUp.GameID, followUp.DateID)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	check := checkVersion(func() int {
+		if stored, ok := r.store.matchFollowUps[rangeKey]; ok {
+			return stored.Version
+		}
+		return 0
+	}, followUp.Version, models.NewPreconditionFailedError("the follow-up of game %s on %s was requested again since version %d", followUp.GameID, followUp.DateID, followUp.Version))
+	return r.store.writeIf(tx, deleteItem(keys.MatchFollowUpPartition, rangeKey), check, func() {
+		delete(r.store.matchFollowUps, rangeKey)
+	})
+}
+
+// addToSet adds the user IDs missing from set, keeping it sorted like the
+// string sets DynamoDB returns.
+func addToSet(set []models.UserID, userIDs []models.UserID) []models.UserID {
+	for _, userID := range userIDs {
+		if !slices.Contains(set, userID) {
+			set = append(set, userID)
+		}
+	}
+	slices.Sort(set)
+	return set
+}
+
+func copyMatchFollowUp(followUp *models.MatchFollowUp) *models.MatchFollowUp {
+	copied := *followUp
+	copied.Players = slices.Clone(followUp.Players)
+	copied.Leavers = slices.Clone(followUp.Leavers)
+	return &copied
+}
+
+// putMatch stages the match, if the stored one is at version, 0 meaning there
+// is none, and its players' history entries, and deletes the entries the
+// stored match has for players that are not in match. It returns failed when
+// the stored match is at another version, and sets match.Version to the new
+// version.
+func (r *InMemoryMatchRepository) putMatch(match *models.Match, version int, failed error, tx *dynamodb.TransactWriteItemsInput) error {
+	stored := copyMatch(match)
+	stored.Version = version + 1
+	rangeKey := keys.MatchRange(match.DateID, match.MatchID)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	check := checkVersion(func() int {
+		if match, ok := r.store.matches[stored.GameID][rangeKey]; ok {
+			return match.Version
+		}
+		return 0
+	}, version, failed)
+
+	// Without a transaction the check must also keep the history entries
+	// from being written, so it runs before anything is
+	if tx == nil {
+		if err := check(); err != nil {
+			return err
+		}
+		check = nil
+	}
+
+	oldMatch := r.store.matches[match.GameID][rangeKey]
+	r.store.writeIf(tx, putItem(keys.MatchPartition(match.GameID), rangeKey), check, func() {
+		if r.store.matches[stored.GameID] == nil {
+			r.store.matches[stored.GameID] = make(map[string]*models.Match)
+		}
+		r.store.matches[stored.GameID][rangeKey] = stored
+	})
+
+	players := stored.Players()
+	for _, userMatch := range stored.UserMatches() {
+		r.putUserMatch(userMatch, tx)
+	}
+	if oldMatch != nil {
+		for _, userMatch := range oldMatch.UserMatches() {
+			if !slices.Contains(players, userMatch.UserID) {
+				r.deleteUserMatch(userMatch, tx)
+			}
+		}
+	}
+	match.Version = stored.Version
+	return nil
+}
+
+// putUserMatch stages a history entry. Callers must hold r.store.mu.
+func (r *InMemoryMatchRepository) putUserMatch(userMatch *models.UserMatch, tx *dynamodb.TransactWriteItemsInput) {
+	r.store.write(tx, putItem(keys.UserMatchPartition(userMatch.UserID), userMatchRange(userMatch)), func() {
+		r.store.putUserMatch(userMatch)
+	})
+}
+
+// deleteUserMatch stages the removal of a history entry. Callers must hold
+// r.store.mu.
+func (r *InMemoryMatchRepository) deleteUserMatch(userMatch *models.UserMatch, tx *dynamodb.TransactWriteItemsInput) {
+	rangeKey := userMatchRange(userMatch)
+	r.store.write(tx, deleteItem(keys.UserMatchPartition(userMatch.UserID), rangeKey), func() {
+		delete(r.store.userMatches[userMatch.UserID], rangeKey)
+	})
+}
+
+// putUserMatch stores a history entry right away. Callers must hold s.mu.
+func (s *Store) putUserMatch(userMatch *models.UserMatch) {
+	if s.userMatches[userMatch.UserID] == nil {
+		s.userMatches[userMatch.UserID] = make(map[string]*models.UserMatch)
+	}
+	s.userMatches[userMatch.UserID][userMatchRange(userMatch)] = userMatch
+}
+
+func copyMatch(match *models.Match) *models.Match {
+	teamNames := make([]string, len(match.TeamNames))
+	copy(teamNames, match.TeamNames)
+	teamScores := make([]int, len(match.TeamScores))
+	copy(teamScores, match.TeamScores)
+	teamMembers := make([][]string, len(match.TeamMembers))
+	for i, team := range match.TeamMembers {
+		teamMembers[i] = make([]string, len(team))
+		copy(teamMembers[i], team)
+	}
+	playerAttributesMap := make(map[models.UserID]models.AttributesStatsMap, len(match.PlayerAttributesMap))
+	for userID, attributes := range match.PlayerAttributesMap {
+		playerAttributesMap[userID] = copyAttributes(attributes)
+	}
+	return &models.Match{
+		MatchID:             match.MatchID,
+		DateID:              match.DateID,
+		GameID:              match.GameID,
+		TeamNames:           teamNames,
+		TeamScores:          teamScores,
+		TeamMembers:         teamMembers,
+		PlayerAttributesMap: playerAttributesMap,
+		Version:             match.Version,
+	}
+}
+
+func userMatchRange(userMatch *models.UserMatch) string {
+	return keys.UserMatchRange(userMatch.DateID, userMatch.GameID, userMatch.MatchID)
+}
+
+// userMatchRangeInFilter reports whether a history Range key lies within the
+// dates of filter.
+func userMatchRangeInFilter(rangeKey string, filter models.UserMatchFilter) bool {
+	if filter.From != "" && rangeKey < keys.DateRangeStart(filter.From) {
+		return false
+	}
+	return filter.To == "" || rangeKey <= keys.DateRangeEnd(filter.To)
+}
+
+func copyUserMatch(userMatch *models.UserMatch) *models.UserMatch {
+	copied := *userMatch
+	copied.Attributes = copyAttributes(userMatch.Attributes)
+	return &copied
+}
+
+func copyAttributes(attributes models.AttributesStatsMap) models.AttributesStatsMap {
+	copied := make(models.AttributesStatsMap, len(attributes))
+	for name, value := range attributes {
+		copied[name] = value
+	}
+	return copied
+}
@@ -0,0 +1,138 @@
+package inmemory
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type InMemoryRatingRepository struct {
+	store *Store
+}
+
+func NewInMemoryRatingRepository(store *Store) repositories.RatingRepository {
+	return &InMemoryRatingRepository{store: store}
+}
+
+func (r *InMemoryRatingRepository) GetRating(gameID models.GameID, userID models.UserID) (*models.Rating, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rating, ok := r.store.ratings[gameID][userID]
+	if !ok {
+		return nil, models.NewNotFoundError("user %s has no rating in game %s", userID, gameID)
+	}
+	return copyRating(rating), nil
+}
+
+func (r *InMemoryRatingRepository) GetRatingsByGame(gameID models.GameID) ([]*models.Rating, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	ratings := []*models.Rating{}
+	for _, rating := range r.store.ratings[gameID] {
+		ratings = append(ratings, copyRating(rating))
+	}
+
+	// DynamoDB returns the partition in sort key order
+	sort.Slice(ratings, func(i, j int) bool {
+		return ratings[i].UserID < ratings[j].UserID
+	})
+	return ratings, nil
+}
+
+func (r *InMemoryRatingRepository) UpdateRating(rating *models.Rating, tx *dynamodb.TransactWriteItemsInput) error {
+	stored := copyRating(rating)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.write(tx, putItem(keys.RatingPartition(rating.GameID), keys.RatingRange(rating.UserID)), func() {
+		if r.store.ratings[stored.GameID] == nil {
+			r.store.ratings[stored.GameID] = make(map[models.UserID]*models.Rating)
+		}
+		r.store.ratings[stored.GameID][stored.UserID] = stored
+	})
+	return nil
+}
+
+func (r *InMemoryRatingRepository) DeleteRating(gameID models.GameID, userID models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.write(tx, deleteItem(keys.RatingPartition(gameID), keys.RatingRange(userID)), func() {
+		delete(r.store.ratings[gameID], userID)
+	})
+	return nil
+}
+
+func (r *InMemoryRatingRepository) GetRatingRecomputes() ([]*models.RatingRecompute, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	recomputes := []*models.RatingRecompute{}
+	for gameID, version := range r.store.ratingRecomputes {
+		recomputes = append(recomputes, &models.RatingRecompute{GameID: gameID, Version: version})
+	}
+
+	// DynamoDB returns the partition in sort key order
+	sort.Slice(recomputes, func(i, j int) bool {
+		return recomputes[i].GameID < recomputes[j].GameID
+	})
+	return recomputes, nil
+}
+
+func (r *InMemoryRatingRepository) GetRatingRecompute(gameID models.GameID) (*models.RatingRecompute, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	version, ok := r.store.ratingRecomputes[gameID]
+	if !ok {
+		return nil, models.NewNotFoundError("game %s has no pending rating recompute", gameID)
+	}
+	return &models.RatingRecompute{GameID: gameID, Version: version}, nil
+}
+
+func (r *InMemoryRatingRepository) RequestRatingRecompute(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.write(tx, updateItem(keys.RatingRecomputePartition, keys.GameRange(gameID)), func() {
+		r.store.ratingRecomputes[gameID]++
+	})
+	return nil
+}
+
+func (r *InMemoryRatingRepository) CheckNoRatingRecompute(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.writeIf(tx, conditionCheckItem(keys.RatingRecomputePartition, keys.GameRange(gameID)), func() error {
+		if _, ok := r.store.ratingRecomputes[gameID]; ok {
+			return models.NewPreconditionFailedError("the ratings of game %s are being recomputed", gameID)
+		}
+		return nil
+	}, func() {})
+}
+
+func (r *InMemoryRatingRepository) DeleteRatingRecompute(recompute *models.RatingRecompute, tx *dynamodb.TransactWriteItemsInput) error {
+	gameID := recompute.GameID
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	check := checkVersion(func() int {
+		return r.store.ratingRecomputes[gameID]
+	}, recompute.Version, models.NewPreconditionFailedError("the ratings of game %s were requested again since version %d", gameID, recompute.Version))
+	return r.store.writeIf(tx, deleteItem(keys.RatingRecomputePartition, keys.GameRange(gameID)), check, func() {
+		delete(r.store.ratingRecomputes, gameID)
+	})
+}
+
+func copyRating(rating *models.Rating) *models.Rating {
+	copied := *rating
+	return &copied
+}
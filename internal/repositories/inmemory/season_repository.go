@@ -0,0 +1,100 @@
+package inmemory
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type InMemorySeasonRepository struct {
+	store *Store
+}
+
+func NewInMemorySeasonRepository(store *Store) repositories.SeasonRepository {
+	return &InMemorySeasonRepository{store: store}
+}
+
+func (r *InMemorySeasonRepository) GetSeason(gameID models.GameID, seasonID models.SeasonID) (*models.Season, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	season, ok := r.store.seasons[gameID][seasonID]
+	if !ok {
+		return nil, models.NewNotFoundError("season %s of game %s not found", seasonID, gameID)
+	}
+	return copySeason(season), nil
+}
+
+func (r *InMemorySeasonRepository) GetSeasonsByGame(gameID models.GameID) ([]*models.Season, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	seasons := []*models.Season{}
+	for _, season := range r.store.seasons[gameID] {
+		seasons = append(seasons, copySeason(season))
+	}
+
+	// DynamoDB returns the partition in sort key order
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].SeasonID < seasons[j].SeasonID
+	})
+	return seasons, nil
+}
+
+func (r *InMemorySeasonRepository) CreateSeason(season *models.Season, tx *dynamodb.TransactWriteItemsInput) (*models.Season, error) {
+	err := r.putSeason(season, tx, func() error {
+		if _, ok := r.store.seasons[season.GameID][season.SeasonID]; ok {
+			return models.NewConflictError("season %s already exists", season.SeasonID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+func (r *InMemorySeasonRepository) UpdateSeason(season *models.Season, tx *dynamodb.TransactWriteItemsInput) (*models.Season, error) {
+	err := r.putSeason(season, tx, func() error {
+		if _, ok := r.store.seasons[season.GameID][season.SeasonID]; !ok {
+			return models.NewNotFoundError("season %s of game %s not found", season.SeasonID, season.GameID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+func (r *InMemorySeasonRepository) DeleteSeason(gameID models.GameID, seasonID models.SeasonID, tx *dynamodb.TransactWriteItemsInput) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.write(tx, deleteItem(keys.SeasonPartition(gameID), keys.SeasonRange(seasonID)), func() {
+		delete(r.store.seasons[gameID], seasonID)
+	})
+	return nil
+}
+
+func (r *InMemorySeasonRepository) putSeason(season *models.Season, tx *dynamodb.TransactWriteItemsInput, check func() error) error {
+	stored := copySeason(season)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.writeIf(tx, putItem(keys.SeasonPartition(season.GameID), keys.SeasonRange(season.SeasonID)), check, func() {
+		if r.store.seasons[stored.GameID] == nil {
+			r.store.seasons[stored.GameID] = make(map[models.SeasonID]*models.Season)
+		}
+		r.store.seasons[stored.GameID][stored.SeasonID] = stored
+	})
+}
+
+func copySeason(season *models.Season) *models.Season {
+	copied := *season
+	return &copied
+}
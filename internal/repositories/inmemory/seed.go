@@ -0,0 +1,121 @@
+package inmemory
+
+import (
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// NewSeededStore returns a Store holding the same users, games, matches, match
+// histories, game stats and leaderboards that scripts/seed_table.sh loads from
+// internal/config/add-*.json into DynamoDB Local.
+func NewSeededStore() *Store {
+	s := NewStore()
+
+	// The seed items have no version attribute, so DynamoDB reads them as
+	// version 1
+
+	users := []*models.User{
+		{UserBasic: models.UserBasic{UserID: "user1", Username: "AliceWonder"}, Email: "alice.wonder@example.com", GamesPlayed: []models.GameID{"pickleball", "pool", "soccer"}},
+		{UserBasic: models.UserBasic{UserID: "user2", Username: "BobBuilder"}, Email: "bob.builder@example.com", GamesPlayed: []models.GameID{"pickleball", "pool", "soccer"}},
+		{UserBasic: models.UserBasic{UserID: "user3", Username: "CharlieChaplin"}, Email: "charlie.chaplin@example.com", GamesPlayed: []models.GameID{"pool", "soccer"}},
+		{UserBasic: models.UserBasic{UserID: "dianadancer", Username: "DianaDancer"}, Email: "dianadancer@example.com", GamesPlayed: []models.GameID{"pickleball", "soccer"}},
+		{UserBasic: models.UserBasic{UserID: "eveexplorer", Username: "EveExplorer"}, Email: "eveexplorer@example.com", GamesPlayed: []models.GameID{"pickleball", "pool"}},
+	}
+	for _, user := range users {
+		user.Version = 1
+		s.users[user.UserID] = user
+	}
+
+	games := []*models.Game{
+		{GameID: "soccer", Description: "Soccer", Attributes: []models.AttributeName{"elo", "goals", "assists", "shots_on_target", "passes_completed"}, RankedAttributes: []models.AttributeName{"elo"}},
+		{GameID: "pool", Description: "Pool", Attributes: []models.AttributeName{"elo", "banks", "pockets", "breaks", "safeties"}, RankedAttributes: []models.AttributeName{"elo"}},
+		{GameID: "pickleball", Description: "Pickleball", Attributes: []models.AttributeName{"elo", "dinks", "volleys", "serves", "third_shot_drops"}, RankedAttributes: []models.AttributeName{"elo"}},
+	}
+	for _, game := range games {
+		game.Version = 1
+		s.games[game.GameID] = game
+	}
+
+	matches := []*models.Match{
+		{
+			MatchID: "match1", DateID: "2023-06-01", GameID: "soccer",
+			TeamNames:   []string{"Team A", "Team B"},
+			TeamScores:  []int{2, 1},
+			TeamMembers: [][]string{{"user1", "user2"}, {"user3", "dianadancer"}},
+			PlayerAttributesMap: map[models.UserID]models.AttributesStatsMap{
+				"user1":       {"goals": 1, "assists": 1, "shots_on_target": 3, "passes_completed": 20},
+				"user2":       {"goals": 1, "assists": 0, "shots_on_target": 2, "passes_completed": 15},
+				"user3":       {"goals": 1, "assists": 0, "shots_on_target": 2, "passes_completed": 18},
+				"dianadancer": {"goals": 0, "assists": 1, "shots_on_target": 1, "passes_completed": 22},
+			},
+		},
+		{
+			MatchID: "match2", DateID: "2023-06-02", GameID: "pool",
+			TeamNames:   []string{"Team X", "Team Y"},
+			TeamScores:  []int{3, 2},
+			TeamMembers: [][]string{{"user2", "eveexplorer"}, {"user1", "user3"}},
+			PlayerAttributesMap: map[models.UserID]models.AttributesStatsMap{
+				"user1":       {"banks": 2, "pockets": 3, "breaks": 1, "safeties": 2},
+				"user2":       {"banks": 1, "pockets": 4, "breaks": 2, "safeties": 1},
+				"user3":       {"banks": 3, "pockets": 2, "breaks": 0, "safeties": 3},
+				"eveexplorer": {"banks": 2, "pockets": 3, "breaks": 1, "safeties": 2},
+			},
+		},
+		{
+			MatchID: "match3", DateID: "2023-06-03", GameID: "pickleball",
+			TeamNames:   []string{"Team Alpha", "Team Beta"},
+			TeamScores:  []int{11, 9},
+			TeamMembers: [][]string{{"dianadancer", "eveexplorer"}, {"user1", "user2"}},
+			PlayerAttributesMap: map[models.UserID]models.AttributesStatsMap{
+				"user1":       {"dinks": 5, "volleys": 7, "serves": 10, "third_shot_drops": 3},
+				"user2":       {"dinks": 6, "volleys": 8, "serves": 9, "third_shot_drops": 4},
+				"dianadancer": {"dinks": 7, "volleys": 6, "serves": 11, "third_shot_drops": 5},
+				"eveexplorer": {"dinks": 8, "volleys": 5, "serves": 10, "third_shot_drops": 6},
+			},
+		},
+	}
+	for _, match := range matches {
+		match.Version = 1
+		if s.matches[match.GameID] == nil {
+			s.matches[match.GameID] = make(map[string]*models.Match)
+		}
+		s.matches[match.GameID][keys.MatchRange(match.DateID, match.MatchID)] = match
+		for _, userMatch := range match.UserMatches() {
+			s.putUserMatch(userMatch)
+		}
+	}
+
+	gameStats := []*models.GameStat{
+		{UserID: "user1", GameID: "soccer", GameAttributes: models.AttributesStatsMap{"elo": 2, "goals": 1, "assists": 1, "shots_on_target": 3, "passes_completed": 20}},
+		{UserID: "user1", GameID: "pool", GameAttributes: models.AttributesStatsMap{"elo": 2, "banks": 2, "pockets": 3, "breaks": 1, "safeties": 2}},
+		{UserID: "user1", GameID: "pickleball", GameAttributes: models.AttributesStatsMap{"elo": 9, "dinks": 5, "volleys": 7, "serves": 10, "third_shot_drops": 3}},
+		{UserID: "user2", GameID: "soccer", GameAttributes: models.AttributesStatsMap{"elo": 2, "goals": 1, "assists": 0, "shots_on_target": 2, "passes_completed": 15}},
+		{UserID: "user2", GameID: "pool", GameAttributes: models.AttributesStatsMap{"elo": 3, "banks": 1, "pockets": 4, "breaks": 2, "safeties": 1}},
+		{UserID: "user2", GameID: "pickleball", GameAttributes: models.AttributesStatsMap{"elo": 9, "dinks": 6, "volleys": 8, "serves": 9, "third_shot_drops": 4}},
+		{UserID: "user3", GameID: "soccer", GameAttributes: models.AttributesStatsMap{"elo": 1, "goals": 1, "assists": 0, "shots_on_target": 2, "passes_completed": 18}},
+		{UserID: "user3", GameID: "pool", GameAttributes: models.AttributesStatsMap{"elo": 2, "banks": 3, "pockets": 2, "breaks": 0, "safeties": 3}},
+		{UserID: "user3", GameID: "pickleball", GameAttributes: models.AttributesStatsMap{"elo": 0, "dinks": 0, "volleys": 0, "serves": 0, "third_shot_drops": 0}},
+		{UserID: "dianadancer", GameID: "soccer", GameAttributes: models.AttributesStatsMap{"elo": 1, "goals": 0, "assists": 1, "shots_on_target": 1, "passes_completed": 22}},
+		{UserID: "dianadancer", GameID: "pool", GameAttributes: models.AttributesStatsMap{"elo": 0, "banks": 0, "pockets": 0, "breaks": 0, "safeties": 0}},
+		{UserID: "dianadancer", GameID: "pickleball", GameAttributes: models.AttributesStatsMap{"elo": 11, "dinks": 7, "volleys": 6, "serves": 11, "third_shot_drops": 5}},
+		{UserID: "eveexplorer", GameID: "soccer", GameAttributes: models.AttributesStatsMap{"elo": 0, "goals": 0, "assists": 0, "shots_on_target": 0, "passes_completed": 0}},
+		{UserID: "eveexplorer", GameID: "pool", GameAttributes: models.AttributesStatsMap{"elo": 3, "banks": 2, "pockets": 3, "breaks": 1, "safeties": 2}},
+		{UserID: "eveexplorer", GameID: "pickleball", GameAttributes: models.AttributesStatsMap{"elo": 11, "dinks": 8, "volleys": 5, "serves": 10, "third_shot_drops": 6}},
+	}
+	for _, gameStat := range gameStats {
+		gameStat.Version = 1
+		if s.gameStats[gameStat.UserID] == nil {
+			s.gameStats[gameStat.UserID] = make(map[string]*models.GameStat)
+		}
+		board := keys.ScopedGameKey(gameStat.GameID, models.AllTimeScope)
+		s.gameStats[gameStat.UserID][board] = gameStat
+
+		// Every seeded game ranks elo, and the seed leaderboards list every player
+		if s.leaderboards[board] == nil {
+			s.leaderboards[board] = make(map[string]models.UserID)
+		}
+		s.leaderboards[board][keys.LeaderboardRange("elo", gameStat.GameAttributes["elo"], gameStat.UserID)] = gameStat.UserID
+	}
+
+	return s
+}
@@ -0,0 +1,155 @@
+package inmemory
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+// Store holds the data shared by the in-memory repositories. It plays the role
+// of the DynamoDB table: repositories built on the same Store see each other's
+// writes, and writes staged in a transaction are applied together by
+// ExecuteTransaction.
+type Store struct {
+	mu sync.Mutex
+
+	users        map[models.UserID]*models.User
+	games        map[models.GameID]*models.Game
+	matches      map[models.GameID]map[string]*models.Match
+	gameStats    map[models.UserID]map[string]*models.GameStat
+	leaderboards map[string]map[string]models.UserID
+	seasons      map[models.GameID]map[models.SeasonID]*models.Season
+	ratings      map[models.GameID]map[models.UserID]*models.Rating
+	userMatches  map[models.UserID]map[string]*models.UserMatch
+	// ratingRecomputes holds the version of each pending rating recompute
+	ratingRecomputes map[models.GameID]int
+	// matchFollowUps holds the pending match follow-ups by MatchFollowUpRange
+	matchFollowUps map[string]*models.MatchFollowUp
+
+	pending map[*dynamodb.TransactWriteItemsInput][]pendingWrite
+}
+
+// pendingWrite is a write staged in a transaction: op applies it once check,
+// which is nil for unconditional writes, has passed.
+type pendingWrite struct {
+	check func() error
+	op    func()
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:            make(map[models.UserID]*models.User),
+		games:            make(map[models.GameID]*models.Game),
+		matches:          make(map[models.GameID]map[string]*models.Match),
+		gameStats:        make(map[models.UserID]map[string]*models.GameStat),
+		leaderboards:     make(map[string]map[string]models.UserID),
+		seasons:          make(map[models.GameID]map[models.SeasonID]*models.Season),
+		ratings:          make(map[models.GameID]map[models.UserID]*models.Rating),
+		userMatches:      make(map[models.UserID]map[string]*models.UserMatch),
+		ratingRecomputes: make(map[models.GameID]int),
+		matchFollowUps:   make(map[string]*models.MatchFollowUp),
+		pending:          make(map[*dynamodb.TransactWriteItemsInput][]pendingWrite),
+	}
+}
+
+// write applies op right away when tx is nil. Otherwise it records item in tx,
+// so the transaction size matches what DynamoDB would see, and defers op until
+// the transaction is executed. Callers must hold s.mu.
+func (s *Store) write(tx *dynamodb.TransactWriteItemsInput, item *dynamodb.TransactWriteItem, op func()) {
+	s.writeIf(tx, item, nil, op)
+}
+
+// writeIf is write guarded by check, the counterpart of a DynamoDB condition
+// expression. Without tx, op is skipped and check's error returned when it
+// fails. In a transaction, ExecuteTransaction runs every check before applying
+// any write and applies none if one fails. Callers must hold s.mu.
+func (s *Store) writeIf(tx *dynamodb.TransactWriteItemsInput, item *dynamodb.TransactWriteItem, check func() error, op func()) error {
+	if tx == nil {
+		if check != nil {
+			if err := check(); err != nil {
+				return err
+			}
+		}
+		op()
+		return nil
+	}
+	tx.TransactItems = append(tx.TransactItems, item)
+	s.pending[tx] = append(s.pending[tx], pendingWrite{check: check, op: op})
+	return nil
+}
+
+// checkVersion is the check of a write based on version of an item, the
+// counterpart of the DynamoDB version condition: it fails with failed unless
+// current, which returns the stored item's version or 0 when there is none,
+// still returns version.
+func checkVersion(current func() int, version int, failed error) func() error {
+	return func() error {
+		if current() != version {
+			return failed
+		}
+		return nil
+	}
+}
+
+type InMemoryTransactionRepository struct {
+	store *Store
+}
+
+func NewInMemoryTransactionRepository(store *Store) repositories.TransactionRepository {
+	return &InMemoryTransactionRepository{store: store}
+}
+
+func (r *InMemoryTransactionRepository) ExecuteTransaction(tx *dynamodb.TransactWriteItemsInput) error {
+	if tx == nil {
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	writes := r.store.pending[tx]
+	delete(r.store.pending, tx)
+
+	if err := repositories.CheckTransaction(tx); err != nil {
+		return err
+	}
+
+	for _, write := range writes {
+		if write.check == nil {
+			continue
+		}
+		if err := write.check(); err != nil {
+			return err
+		}
+	}
+	for _, write := range writes {
+		write.op()
+	}
+	return nil
+}
+
+func putItem(id, rangeKey string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Put: &dynamodb.Put{Item: itemKey(id, rangeKey)}}
+}
+
+func updateItem(id, rangeKey string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Update: &dynamodb.Update{Key: itemKey(id, rangeKey)}}
+}
+
+func deleteItem(id, rangeKey string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{Key: itemKey(id, rangeKey)}}
+}
+
+func conditionCheckItem(id, rangeKey string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{Key: itemKey(id, rangeKey)}}
+}
+
+func itemKey(id, rangeKey string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Id":    {S: aws.String(id)},
+		"Range": {S: aws.String(rangeKey)},
+	}
+}
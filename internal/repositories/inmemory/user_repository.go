@@ -0,0 +1,192 @@
+package inmemory
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type InMemoryUserRepository struct {
+	store *Store
+}
+
+func NewInMemoryUserRepository(store *Store) repositories.UserRepository {
+	return &InMemoryUserRepository{store: store}
+}
+
+func (r *InMemoryUserRepository) GetUser(id models.UserID) (*models.User, error) {
+	if id == "" {
+		return nil, models.NewValidationError("id cannot be empty")
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, models.NewNotFoundError("user %s not found", id)
+	}
+	return copyUser(user), nil
+}
+
+func (r *InMemoryUserRepository) GetUserBasicsByPrefix(prefix string) ([]*models.UserBasic, error) {
+	if prefix == "" {
+		return nil, models.NewValidationError("prefix cannot be empty")
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var userBasics []*models.UserBasic
+	for id, user := range r.store.users {
+		if strings.HasPrefix(string(id), prefix) {
+			userBasic := user.UserBasic
+			userBasics = append(userBasics, &userBasic)
+		}
+	}
+
+	// DynamoDB returns the partition in sort key order
+	sort.Slice(userBasics, func(i, j int) bool {
+		return userBasics[i].UserID < userBasics[j].UserID
+	})
+
+	return userBasics, nil
+}
+
+func (r *InMemoryUserRepository) GetUserBasics(ids []models.UserID) ([]*models.UserBasic, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	userBasics := []*models.UserBasic{}
+	seen := make(map[models.UserID]bool)
+	for _, id := range ids {
+		user, ok := r.store.users[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		userBasic := user.UserBasic
+		userBasics = append(userBasics, &userBasic)
+	}
+	return userBasics, nil
+}
+
+func (r *InMemoryUserRepository) CreateUser(user *models.User, tx *dynamodb.TransactWriteItemsInput) (*models.User, error) {
+	if user.UserID == "" {
+		return nil, models.NewValidationError("user ID cannot be empty")
+	}
+	if err := r.putUser(user, 0, models.NewConflictError("user %s already exists", user.UserID), tx); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) UpdateUser(user *models.User, tx *dynamodb.TransactWriteItemsInput) (*models.User, error) {
+	if user.UserID == "" {
+		return nil, models.NewValidationError("user ID cannot be empty")
+	}
+	if user.Version == 0 {
+		return nil, models.NewValidationError("user %s has no version to update", user.UserID)
+	}
+	if err := r.putUser(user, user.Version, models.NewPreconditionFailedError("user %s changed since version %d", user.UserID, user.Version), tx); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) DeleteUser(id *models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
+	if *id == "" {
+		return models.NewValidationError("id cannot be empty")
+	}
+	userID := *id
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.write(tx, deleteItem(keys.UserPartition(userID), keys.UserRange(userID)), func() {
+		delete(r.store.users, userID)
+	})
+	return nil
+}
+
+func (r *InMemoryUserRepository) AddGamePlayed(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	return r.updateGamesPlayed(userID, tx, func(gamesPlayed []models.GameID) []models.GameID {
+		if i, found := slices.BinarySearch(gamesPlayed, gameID); !found {
+			gamesPlayed = slices.Insert(gamesPlayed, i, gameID)
+		}
+		return gamesPlayed
+	})
+}
+
+func (r *InMemoryUserRepository) RemoveGamePlayed(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	return r.updateGamesPlayed(userID, tx, func(gamesPlayed []models.GameID) []models.GameID {
+		if i, found := slices.BinarySearch(gamesPlayed, gameID); found {
+			gamesPlayed = slices.Delete(gamesPlayed, i, i+1)
+		}
+		return gamesPlayed
+	})
+}
+
+// updateGamesPlayed applies update to the stored user's GamesPlayed, which is
+// kept sorted, and increments the version, failing if there is no such user.
+func (r *InMemoryUserRepository) updateGamesPlayed(userID models.UserID, tx *dynamodb.TransactWriteItemsInput, update func([]models.GameID) []models.GameID) error {
+	if userID == "" {
+		return models.NewValidationError("user ID cannot be empty")
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	exists := func() error {
+		if _, ok := r.store.users[userID]; !ok {
+			return models.NewNotFoundError("user %s not found", userID)
+		}
+		return nil
+	}
+	return r.store.writeIf(tx, updateItem(keys.UserPartition(userID), keys.UserRange(userID)), exists, func() {
+		user := r.store.users[userID]
+		user.GamesPlayed = update(user.GamesPlayed)
+		user.Version++
+	})
+}
+
+// putUser writes the user if the stored one is at version, 0 meaning there is
+// none, returning failed otherwise, and sets user.Version to the new version.
+func (r *InMemoryUserRepository) putUser(user *models.User, version int, failed error, tx *dynamodb.TransactWriteItemsInput) error {
+	stored := copyUser(user)
+	stored.Version = version + 1
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current := func() int {
+		if user, ok := r.store.users[stored.UserID]; ok {
+			return user.Version
+		}
+		return 0
+	}
+	err := r.store.writeIf(tx, putItem(keys.UserPartition(user.UserID), keys.UserRange(user.UserID)), checkVersion(current, version, failed), func() {
+		r.store.users[stored.UserID] = stored
+	})
+	if err != nil {
+		return err
+	}
+	user.Version = stored.Version
+	return nil
+}
+
+func copyUser(user *models.User) *models.User {
+	gamesPlayed := make([]models.GameID, len(user.GamesPlayed))
+	copy(gamesPlayed, user.GamesPlayed)
+	return &models.User{
+		UserBasic:   user.UserBasic,
+		Email:       user.Email,
+		GamesPlayed: gamesPlayed,
+		Version:     user.Version,
+	}
+}
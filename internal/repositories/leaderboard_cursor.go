@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// ErrInvalidCursor is returned when a leaderboard cursor was not produced by
+// EncodeLeaderboardCursor for the requested leaderboard.
+var ErrInvalidCursor = models.NewValidationError("invalid cursor")
+
+// leaderboardCursor records where a page ended: the Range key of its last
+// entry and that entry's rank, so the next page can continue numbering. The
+// partition, which names the game and the scope, and the direction are kept
+// so the cursor is only accepted for the leaderboard it was issued for.
+type leaderboardCursor struct {
+	Partition string               `json:"p"`
+	Direction models.SortDirection `json:"d"`
+	Range     string               `json:"r"`
+	Rank      int                  `json:"n"`
+}
+
+// EncodeLeaderboardCursor returns the opaque token handed to clients for the
+// page of the leaderboard stored in partition, read in direction, that starts
+// after the entry stored under rangeKey.
+func EncodeLeaderboardCursor(partition string, direction models.SortDirection, rangeKey string, rank int) string {
+	data, _ := json.Marshal(leaderboardCursor{Partition: partition, Direction: direction, Range: rangeKey, Rank: rank})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeLeaderboardCursor reverses EncodeLeaderboardCursor and checks that the
+// cursor was issued for the attribute's leaderboard in partition read in
+// direction. An empty cursor means the first page and decodes to an empty
+// Range key and rank 0.
+func DecodeLeaderboardCursor(cursor string, partition string, attr models.AttributeName, direction models.SortDirection) (string, int, error) {
+	if cursor == "" {
+		return "", 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	var c leaderboardCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	if c.Partition != partition || c.Direction != direction || !strings.HasPrefix(c.Range, keys.LeaderboardAttributePrefix(attr)) || c.Rank < 1 {
+		return "", 0, ErrInvalidCursor
+	}
+	return c.Range, c.Rank, nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// MigrateLeaderboardRangeKeys rewrites every Leaderboard.<game> item that still
+// uses the legacy "<attr>.%05d.<userID>" sort key to the encoding produced by
+// keys.LeaderboardRange. Items already in the new format are skipped, so the
+// migration can be re-run safely. It returns the number of items rewritten.
+func MigrateLeaderboardRangeKeys(db *dynamodb.DynamoDB, tableName string) (int, error) {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("begins_with(Id, :prefix)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {S: aws.String(keys.LeaderboardPartitionPrefix)},
+		},
+	}
+
+	migrated := 0
+	for {
+		output, err := db.Scan(input)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to scan leaderboard items: %w", err)
+		}
+
+		for _, item := range output.Items {
+			rewritten, err := migrateLeaderboardItem(db, tableName, item)
+			if err != nil {
+				return migrated, err
+			}
+			if rewritten {
+				migrated++
+			}
+		}
+
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	return migrated, nil
+}
+
+// migrateLeaderboardItem swaps a legacy item for its re-keyed copy in one
+// transaction, so a board never shows the user twice or not at all.
+func migrateLeaderboardItem(db *dynamodb.DynamoDB, tableName string, item map[string]*dynamodb.AttributeValue) (bool, error) {
+	if item["Range"] == nil || item["Range"].S == nil || item["UserId"] == nil || item["UserId"].S == nil {
+		return false, errors.New("leaderboard item is missing Range or UserId")
+	}
+	rangeKey := *item["Range"].S
+	userID := models.UserID(*item["UserId"].S)
+
+	if _, _, _, err := keys.ParseLeaderboardRange(rangeKey); err == nil {
+		return false, nil
+	}
+	attr, value, err := keys.ParseLegacyLeaderboardRange(rangeKey, userID)
+	if err != nil {
+		return false, err
+	}
+
+	newItem := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, av := range item {
+		newItem[name] = av
+	}
+	newItem["Range"] = &dynamodb.AttributeValue{S: aws.String(keys.LeaderboardRange(attr, value, userID))}
+
+	_, err = db.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Delete: &dynamodb.Delete{
+					TableName: aws.String(tableName),
+					Key: map[string]*dynamodb.AttributeValue{
+						"Id":    item["Id"],
+						"Range": item["Range"],
+					},
+				},
+			},
+			{
+				Put: &dynamodb.Put{
+					TableName: aws.String(tableName),
+					Item:      newItem,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to migrate leaderboard item %s: %w", rangeKey, err)
+	}
+	return true, nil
+}
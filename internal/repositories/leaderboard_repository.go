@@ -6,9 +6,19 @@ import (
 )
 
 type LeaderboardRepository interface {
-	GetLeaderboard(gameID models.GameID, attr models.AttributeName) (models.LeaderBoard, error)
-	GetBoundedLeaderboard(gameID models.GameID, attr models.AttributeName, limit int) (models.BoundedLeaderboard, error)
+	// WithScope returns a repository reading and writing the leaderboards of
+	// scope instead. The all-time scope is the default.
+	WithScope(scope models.LeaderboardScope) LeaderboardRepository
+	// GetLeaderboard, GetBoundedLeaderboard and GetLeaderboardAroundUser rank
+	// the attribute's entries in direction, which is the attribute's
+	// Game.RankDirection. Items are stored the same way in either direction.
+	GetLeaderboard(gameID models.GameID, attr models.AttributeName, direction models.SortDirection) (models.LeaderBoard, error)
+	GetBoundedLeaderboard(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, limit int, cursor string) (models.BoundedLeaderboard, error)
+	GetLeaderboardAroundUser(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, userID models.UserID, value models.AttributeStat, window int) (models.LeaderboardAroundUser, error)
 	AddLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error
+	// UpdateLeaderboardItem moves the user's entry from oldValue to value. It
+	// writes nothing when they are equal, so a user without an entry yet is
+	// added with AddLeaderboardItem instead.
 	UpdateLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, oldValue models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error
 	DeleteLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, oldValue models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error
 	DeleteLeaderboardItemsByGame(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error
@@ -1,95 +1,272 @@
 package repositories
 
 import (
-	"errors"
-	"fmt"
-
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/mquan1409/game-api/internal/keys"
 	"github.com/mquan1409/game-api/internal/models"
-	"github.com/mquan1409/game-api/internal/utils"
 )
 
 type DynamoDBLeaderboardRepository struct {
 	db        *dynamodb.DynamoDB
 	tableName string
+	scope     models.LeaderboardScope
 }
 
 func NewDynamoDBLeaderboardRepository(db *dynamodb.DynamoDB, tableName string) LeaderboardRepository {
 	return &DynamoDBLeaderboardRepository{db: db, tableName: tableName}
 }
 
-func (r *DynamoDBLeaderboardRepository) GetLeaderboard(gameID models.GameID, attr models.AttributeName) (models.LeaderBoard, error) {
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String(r.tableName),
-		KeyConditionExpression: aws.String("Id = :id AND begins_with(#range, :attr)"),
-		ExpressionAttributeNames: map[string]*string{
-			"#range": aws.String("Range"),
-		},
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":id":   {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
-			":attr": {S: aws.String(fmt.Sprintf("%s.", attr))},
-		},
-		ScanIndexForward: aws.Bool(false),
+func (r *DynamoDBLeaderboardRepository) WithScope(scope models.LeaderboardScope) LeaderboardRepository {
+	return &DynamoDBLeaderboardRepository{db: r.db, tableName: r.tableName, scope: scope}
+}
+
+// partition returns the Id of the game's leaderboard items in r's scope.
+func (r *DynamoDBLeaderboardRepository) partition(gameID models.GameID) string {
+	return keys.LeaderboardPartition(gameID, r.scope)
+}
+
+func (r *DynamoDBLeaderboardRepository) GetLeaderboard(gameID models.GameID, attr models.AttributeName, direction models.SortDirection) (models.LeaderBoard, error) {
+	items, _, err := r.queryLeaderboard(gameID, attr, direction, "", 0)
+	if err != nil {
+		return models.LeaderBoard{}, err
 	}
 
-	result, err := r.db.Query(input)
+	entries, err := r.unmarshalLeaderboardEntries(items, 0)
 	if err != nil {
 		return models.LeaderBoard{}, err
 	}
 
-	userIDs := []models.UserID{}
+	return models.NewLeaderBoard(gameID, attr, entries), nil
+}
 
-	for _, item := range result.Items {
-		userID, err := r.unmarshalLeaderboardItemFromDynamoDB(item)
-		if err != nil {
-			return models.LeaderBoard{}, err
-		}
-		userIDs = append(userIDs, userID)
+// GetBoundedLeaderboard returns up to limit entries starting after cursor, or
+// from the top when cursor is empty. NextCursor is set only when more entries
+// follow the returned page.
+func (r *DynamoDBLeaderboardRepository) GetBoundedLeaderboard(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, limit int, cursor string) (models.BoundedLeaderboard, error) {
+	if limit < 1 {
+		return models.BoundedLeaderboard{}, models.NewValidationError("limit must be at least 1")
 	}
 
-	return models.NewLeaderBoard(gameID, attr, userIDs), nil
-}
+	startRange, startRank, err := DecodeLeaderboardCursor(cursor, r.partition(gameID), attr, direction)
+	if err != nil {
+		return models.BoundedLeaderboard{}, err
+	}
 
-func (r *DynamoDBLeaderboardRepository) GetBoundedLeaderboard(gameID models.GameID, attr models.AttributeName, limit int) (models.BoundedLeaderboard, error) {
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String(r.tableName),
-		KeyConditionExpression: aws.String("Id = :id AND begins_with(#range, :attr)"),
-		ExpressionAttributeNames: map[string]*string{
-			"#range": aws.String("Range"),
-		},
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":id":   {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
-			":attr": {S: aws.String(fmt.Sprintf("%s.", attr))},
-		},
-		ScanIndexForward: aws.Bool(false),
-		Limit:            aws.Int64(int64(limit)),
+	items, more, err := r.queryLeaderboard(gameID, attr, direction, startRange, limit)
+	if err != nil {
+		return models.BoundedLeaderboard{}, err
 	}
 
-	result, err := r.db.Query(input)
+	entries, err := r.unmarshalLeaderboardEntries(items, startRank)
 	if err != nil {
 		return models.BoundedLeaderboard{}, err
 	}
 
-	userIDs := []models.UserID{}
+	leaderboard := models.NewBoundedLeaderBoard(gameID, attr, entries, limit)
+	if more {
+		lastRange := *items[len(items)-1]["Range"].S
+		leaderboard.NextCursor = EncodeLeaderboardCursor(r.partition(gameID), direction, lastRange, startRank+len(entries))
+	}
+	return leaderboard, nil
+}
+
+// GetLeaderboardAroundUser returns the user's entry for value with up to
+// window entries on each side. The rank is the number of entries at or above
+// the user's Range key, or at or below it when ranking in ascending order,
+// counted with a key-only COUNT Query.
+func (r *DynamoDBLeaderboardRepository) GetLeaderboardAroundUser(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, userID models.UserID, value models.AttributeStat, window int) (models.LeaderboardAroundUser, error) {
+	if window < 0 {
+		return models.LeaderboardAroundUser{}, models.NewValidationError("window cannot be negative")
+	}
+
+	userRange := keys.LeaderboardRange(attr, value, userID)
+	// Every Range key of the attribute sorts between these bounds
+	lowest := keys.LeaderboardAttributePrefix(attr)
+	highest := keys.LeaderboardAttributeEnd(attr)
+	// The entries ranked above the user have higher Range keys, unless the
+	// leaderboard ranks in ascending order
+	descending := direction != models.SortAscending
+
+	// The user and the entries above them, nearest first
+	above, err := r.queryLeaderboardFrom(gameID, userRange, lowest, highest, descending, window+1)
+	if err != nil {
+		return models.LeaderboardAroundUser{}, err
+	}
+	if len(above) == 0 || *above[0]["Range"].S != userRange {
+		return models.LeaderboardAroundUser{}, models.NewNotFoundError("user %s is not on the %s leaderboard for game %s", userID, attr, gameID)
+	}
+
+	// The user and the entries below them, nearest first
+	below, err := r.queryLeaderboardFrom(gameID, userRange, lowest, highest, !descending, window+1)
+	if err != nil {
+		return models.LeaderboardAroundUser{}, err
+	}
 
-	for _, item := range result.Items {
-		userID, err := r.unmarshalLeaderboardItemFromDynamoDB(item)
+	var rank int
+	if descending {
+		rank, err = r.countLeaderboardBetween(gameID, userRange, highest)
+	} else {
+		rank, err = r.countLeaderboardBetween(gameID, lowest, userRange)
+	}
+	if err != nil {
+		return models.LeaderboardAroundUser{}, err
+	}
+
+	items := []map[string]*dynamodb.AttributeValue{}
+	for i := len(above) - 1; i > 0; i-- {
+		items = append(items, above[i])
+	}
+	items = append(items, below...)
+
+	entries, err := r.unmarshalLeaderboardEntries(items, rank-len(above))
+	if err != nil {
+		return models.LeaderboardAroundUser{}, err
+	}
+
+	return models.NewLeaderboardAroundUser(gameID, attr, entries, userID, rank, window), nil
+}
+
+// queryLeaderboardFrom returns up to limit items starting with the one at
+// rangeKey, nearest first, going up to highest when up is set and down to
+// lowest otherwise.
+func (r *DynamoDBLeaderboardRepository) queryLeaderboardFrom(gameID models.GameID, rangeKey, lowest, highest string, up bool, limit int) ([]map[string]*dynamodb.AttributeValue, error) {
+	if up {
+		return r.queryLeaderboardBetween(gameID, rangeKey, highest, true, limit)
+	}
+	return r.queryLeaderboardBetween(gameID, lowest, rangeKey, false, limit)
+}
+
+// queryLeaderboardBetween returns up to limit items whose Range key lies
+// between from and to inclusive, ascending when forward is set.
+func (r *DynamoDBLeaderboardRepository) queryLeaderboardBetween(gameID models.GameID, from, to string, forward bool, limit int) ([]map[string]*dynamodb.AttributeValue, error) {
+	items := []map[string]*dynamodb.AttributeValue{}
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :id AND #range BETWEEN :from AND :to"),
+			ExpressionAttributeNames: map[string]*string{
+				"#range": aws.String("Range"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id":   {S: aws.String(r.partition(gameID))},
+				":from": {S: aws.String(from)},
+				":to":   {S: aws.String(to)},
+			},
+			ScanIndexForward:  aws.Bool(forward),
+			Limit:             aws.Int64(int64(limit - len(items))),
+			ExclusiveStartKey: startKey,
+		})
 		if err != nil {
-			return models.BoundedLeaderboard{}, err
+			return nil, err
 		}
-		userIDs = append(userIDs, userID)
+		items = append(items, result.Items...)
+
+		if len(items) >= limit || len(result.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		startKey = result.LastEvaluatedKey
 	}
+}
+
+// countLeaderboardBetween counts the items whose Range key lies between from
+// and to inclusive, following LastEvaluatedKey across Query pages.
+func (r *DynamoDBLeaderboardRepository) countLeaderboardBetween(gameID models.GameID, from, to string) (int, error) {
+	count := 0
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :id AND #range BETWEEN :from AND :to"),
+			ExpressionAttributeNames: map[string]*string{
+				"#range": aws.String("Range"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id":   {S: aws.String(r.partition(gameID))},
+				":from": {S: aws.String(from)},
+				":to":   {S: aws.String(to)},
+			},
+			Select:            aws.String(dynamodb.SelectCount),
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return 0, err
+		}
+		count += int(aws.Int64Value(result.Count))
 
-	return models.NewBoundedLeaderBoard(gameID, attr, userIDs, limit), nil
+		if len(result.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
 }
 
-func (r *DynamoDBLeaderboardRepository) AddLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error {
-	if !utils.AttributePositive(value) {
-		return errors.New("attributes must be positive")
+// queryLeaderboard reads the attribute's leaderboard in direction, starting
+// after startRange when it is set. It follows LastEvaluatedKey across the 1MB
+// Query page limit until it has limit items, or the whole leaderboard when
+// limit is 0. more reports whether items remain after the ones returned.
+func (r *DynamoDBLeaderboardRepository) queryLeaderboard(gameID models.GameID, attr models.AttributeName, direction models.SortDirection, startRange string, limit int) ([]map[string]*dynamodb.AttributeValue, bool, error) {
+	var startKey map[string]*dynamodb.AttributeValue
+	if startRange != "" {
+		startKey = map[string]*dynamodb.AttributeValue{
+			"Id":    {S: aws.String(r.partition(gameID))},
+			"Range": {S: aws.String(startRange)},
+		}
 	}
+
+	items := []map[string]*dynamodb.AttributeValue{}
+	for {
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :id AND begins_with(#range, :attr)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#range": aws.String("Range"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id":   {S: aws.String(r.partition(gameID))},
+				":attr": {S: aws.String(keys.LeaderboardAttributePrefix(attr))},
+			},
+			ScanIndexForward:  aws.Bool(direction == models.SortAscending),
+			ExclusiveStartKey: startKey,
+		}
+		if limit > 0 {
+			// Ask for one extra item to learn whether another page follows
+			input.Limit = aws.Int64(int64(limit + 1 - len(items)))
+		}
+
+		result, err := r.db.Query(input)
+		if err != nil {
+			return nil, false, err
+		}
+		items = append(items, result.Items...)
+
+		if limit > 0 && len(items) > limit {
+			return items[:limit], true, nil
+		}
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, false, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+// unmarshalLeaderboardEntries ranks items consecutively after startRank.
+func (r *DynamoDBLeaderboardRepository) unmarshalLeaderboardEntries(items []map[string]*dynamodb.AttributeValue, startRank int) ([]models.LeaderboardEntry, error) {
+	entries := []models.LeaderboardEntry{}
+	for i, item := range items {
+		entry, err := r.unmarshalLeaderboardItemFromDynamoDB(item)
+		if err != nil {
+			return nil, err
+		}
+		entry.Rank = startRank + i + 1
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *DynamoDBLeaderboardRepository) AddLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error {
 	item, err := r.marshalLeaderboardItemToDynamoDB(gameID, attr, value, userID)
 	if err != nil {
 		return err
@@ -114,15 +291,15 @@ func (r *DynamoDBLeaderboardRepository) AddLeaderboardItem(gameID models.GameID,
 }
 
 func (r *DynamoDBLeaderboardRepository) UpdateLeaderboardItem(gameID models.GameID, userID models.UserID, attr models.AttributeName, value models.AttributeStat, oldValue models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error {
+	if oldValue == value {
+		return nil
+	}
+
 	// Create a new transaction if one wasn't provided
 	localTx := tx
 	if localTx == nil {
 		localTx = &dynamodb.TransactWriteItemsInput{}
 	}
-	if oldValue == value {
-		return nil
-	}
-
 
 	// Delete the old entry
 	err := r.DeleteLeaderboardItem(gameID, userID, attr, oldValue, localTx)
@@ -149,8 +326,8 @@ func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItem(gameID models.Game
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
-			"Id":    {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
-			"Range": {S: aws.String(fmt.Sprintf("%s.%05d.%s", attr, oldValue, userID))},
+			"Id":    {S: aws.String(r.partition(gameID))},
+			"Range": {S: aws.String(keys.LeaderboardRange(attr, oldValue, userID))},
 		},
 	}
 
@@ -168,8 +345,8 @@ func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItem(gameID models.Game
 			Delete: &dynamodb.Delete{
 				TableName: aws.String(r.tableName),
 				Key: map[string]*dynamodb.AttributeValue{
-					"Id":    {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
-					"Range": {S: aws.String(fmt.Sprintf("%s.%05d.%s", attr, oldValue, userID))},
+					"Id":    {S: aws.String(r.partition(gameID))},
+					"Range": {S: aws.String(keys.LeaderboardRange(attr, oldValue, userID))},
 				},
 			},
 		})
@@ -179,56 +356,29 @@ func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItem(gameID models.Game
 	_, err = r.db.DeleteItem(&dynamodb.DeleteItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
-			"Id":    {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
-			"Range": {S: aws.String(fmt.Sprintf("%s.%05d.%s", attr, oldValue, userID))},
+			"Id":    {S: aws.String(r.partition(gameID))},
+			"Range": {S: aws.String(keys.LeaderboardRange(attr, oldValue, userID))},
 		},
 	})
 
 	return err
 }
 
+// DeleteLeaderboardItemsByGameAndUser reads the whole leaderboard partition,
+// a page at a time, to find the user's items. Callers that know the values of
+// the user's items should delete them with DeleteLeaderboardItem instead.
 func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItemsByGameAndUser(gameID models.GameID, userID models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String(r.tableName),
 		KeyConditionExpression: aws.String("Id = :id"),
 		FilterExpression:       aws.String("UserId = :userID"),
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":id":     {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
+			":id":     {S: aws.String(r.partition(gameID))},
 			":userID": {S: aws.String(string(userID))},
 		},
 	}
 
-	result, err := r.db.Query(input)
-	if err != nil {
-		return err
-	}
-
-	for _, item := range result.Items {
-		if tx != nil {
-			tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
-				Delete: &dynamodb.Delete{
-					TableName: aws.String(r.tableName),
-					Key: map[string]*dynamodb.AttributeValue{
-						"Id":    item["Id"],
-						"Range": item["Range"],
-					},
-				},
-			})
-		} else {
-			_, err = r.db.DeleteItem(&dynamodb.DeleteItemInput{
-				TableName: aws.String(r.tableName),
-				Key: map[string]*dynamodb.AttributeValue{
-					"Id":    item["Id"],
-					"Range": item["Range"],
-				},
-			})
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
+	return r.deleteQueriedItems(input, tx)
 }
 
 func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItemsByGame(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
@@ -236,41 +386,11 @@ func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItemsByGame(gameID mode
 		TableName:              aws.String(r.tableName),
 		KeyConditionExpression: aws.String("Id = :id"),
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":id": {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
+			":id": {S: aws.String(r.partition(gameID))},
 		},
 	}
 
-	result, err := r.db.Query(input)
-	if err != nil {
-		return err
-	}
-
-	for _, item := range result.Items {
-		if tx != nil {
-			tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
-				Delete: &dynamodb.Delete{
-					TableName: aws.String(r.tableName),
-					Key: map[string]*dynamodb.AttributeValue{
-						"Id":    item["Id"],
-						"Range": item["Range"],
-					},
-				},
-			})
-		} else {
-			_, err = r.db.DeleteItem(&dynamodb.DeleteItemInput{
-				TableName: aws.String(r.tableName),
-				Key: map[string]*dynamodb.AttributeValue{
-					"Id":    item["Id"],
-					"Range": item["Range"],
-				},
-			})
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
+	return r.deleteQueriedItems(input, tx)
 }
 
 func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItemsByGameAndAttribute(gameID models.GameID, attr models.AttributeName, tx *dynamodb.TransactWriteItemsInput) error {
@@ -281,55 +401,67 @@ func (r *DynamoDBLeaderboardRepository) DeleteLeaderboardItemsByGameAndAttribute
 			"#range": aws.String("Range"),
 		},
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":id":   {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
-			":attr": {S: aws.String(fmt.Sprintf("%s.", attr))},
+			":id":   {S: aws.String(r.partition(gameID))},
+			":attr": {S: aws.String(keys.LeaderboardAttributePrefix(attr))},
 		},
 	}
 
-	result, err := r.db.Query(input)
-	if err != nil {
-		return err
-	}
+	return r.deleteQueriedItems(input, tx)
+}
+
+// deleteQueriedItems deletes every item matched by input, reading them a page
+// at a time. Without a transaction each item is deleted as soon as it is read,
+// so a deletion that fails part way can be retried and only finds the rest.
+func (r *DynamoDBLeaderboardRepository) deleteQueriedItems(input *dynamodb.QueryInput, tx *dynamodb.TransactWriteItemsInput) error {
+	for {
+		result, err := r.db.Query(input)
+		if err != nil {
+			return err
+		}
 
-	for _, item := range result.Items {
-		if tx != nil {
-			tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
-				Delete: &dynamodb.Delete{
-					TableName: aws.String(r.tableName),
-					Key: map[string]*dynamodb.AttributeValue{
-						"Id":    item["Id"],
-						"Range": item["Range"],
+		for _, item := range result.Items {
+			key := map[string]*dynamodb.AttributeValue{
+				"Id":    item["Id"],
+				"Range": item["Range"],
+			}
+			if tx != nil {
+				tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
+					Delete: &dynamodb.Delete{
+						TableName: aws.String(r.tableName),
+						Key:       key,
 					},
-				},
-			})
-		} else {
+				})
+				continue
+			}
 			_, err = r.db.DeleteItem(&dynamodb.DeleteItemInput{
 				TableName: aws.String(r.tableName),
-				Key: map[string]*dynamodb.AttributeValue{
-					"Id":    item["Id"],
-					"Range": item["Range"],
-				},
+				Key:       key,
 			})
 			if err != nil {
 				return err
 			}
 		}
-	}
 
-	return nil
+		if len(result.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
 }
 
 func (r *DynamoDBLeaderboardRepository) marshalLeaderboardItemToDynamoDB(gameID models.GameID, attr models.AttributeName, value models.AttributeStat, userID models.UserID) (map[string]*dynamodb.AttributeValue, error) {
 	item := map[string]*dynamodb.AttributeValue{
-		"Id":     {S: aws.String(fmt.Sprintf("Leaderboard.%s", gameID))},
-		"Range":  {S: aws.String(fmt.Sprintf("%s.%05d.%s", attr, value, userID))},
+		"Id":     {S: aws.String(r.partition(gameID))},
+		"Range":  {S: aws.String(keys.LeaderboardRange(attr, value, userID))},
 		"UserId": {S: aws.String(string(userID))},
 	}
 
 	return item, nil
 }
 
-func (r *DynamoDBLeaderboardRepository) unmarshalLeaderboardItemFromDynamoDB(item map[string]*dynamodb.AttributeValue) (models.UserID, error) {
+// unmarshalLeaderboardItemFromDynamoDB returns the entry's user and value; the
+// value is read back from the Range key. Rank and Username are left to the caller.
+func (r *DynamoDBLeaderboardRepository) unmarshalLeaderboardItemFromDynamoDB(item map[string]*dynamodb.AttributeValue) (models.LeaderboardEntry, error) {
 	var leaderboardItem struct {
 		ID     string
 		Range  string
@@ -338,8 +470,18 @@ func (r *DynamoDBLeaderboardRepository) unmarshalLeaderboardItemFromDynamoDB(ite
 
 	err := dynamodbattribute.UnmarshalMap(item, &leaderboardItem)
 	if err != nil {
-		return "", err
+		return models.LeaderboardEntry{}, err
+	}
+
+	userID := models.UserID(leaderboardItem.UserId)
+	_, value, _, err := keys.ParseLeaderboardRange(leaderboardItem.Range)
+	if err != nil {
+		// Tolerate items not yet rewritten by MigrateLeaderboardRangeKeys
+		_, value, err = keys.ParseLegacyLeaderboardRange(leaderboardItem.Range, userID)
+		if err != nil {
+			return models.LeaderboardEntry{}, err
+		}
 	}
 
-	return models.UserID(leaderboardItem.UserId), nil
+	return models.LeaderboardEntry{UserID: userID, Value: value}, nil
 }
@@ -8,7 +8,31 @@ import (
 type MatchRepository interface {
 	GetMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) (*models.Match, error)
 	GetMatchesByGameAndDate(gameID models.GameID, dateID models.DateID) ([]*models.Match, error)
+	// GetMatchesByGame returns every match of the game in history order, i.e.
+	// by DateID and then MatchID.
+	GetMatchesByGame(gameID models.GameID) ([]*models.Match, error)
+	// GetMatchesByGameAndDateRange returns a page of at most limit of the
+	// game's matches played within dates, oldest first.
+	GetMatchesByGameAndDateRange(gameID models.GameID, dates models.DateRange, limit int, cursor string) (models.MatchPage, error)
+	// GetUserMatches returns a page of at most limit entries of the user's
+	// match history, newest first. CreateMatch, UpdateMatch and DeleteMatch
+	// keep the history of every player of the match up to date.
+	GetUserMatches(userID models.UserID, filter models.UserMatchFilter, limit int, cursor string) (models.UserMatchHistory, error)
 	CreateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error)
+	// UpdateMatch replaces the match if the stored one is still at
+	// match.Version and sets match.Version to the version written.
 	UpdateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error)
 	DeleteMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID, tx *dynamodb.TransactWriteItemsInput) error
+	// GetMatchFollowUps returns the pending follow-ups of every game and date.
+	GetMatchFollowUps() ([]*models.MatchFollowUp, error)
+	// GetMatchFollowUp returns the pending follow-up of the game's matches on
+	// the date, or an ErrNotFound error if there is none.
+	GetMatchFollowUp(gameID models.GameID, dateID models.DateID) (*models.MatchFollowUp, error)
+	// RequestMatchFollowUp adds the players and leavers to the follow-up of
+	// the game's matches on the date, creating it if there is none, and
+	// increments its version.
+	RequestMatchFollowUp(gameID models.GameID, dateID models.DateID, players, leavers []models.UserID, tx *dynamodb.TransactWriteItemsInput) error
+	// DeleteMatchFollowUp drops a finished follow-up, failing with
+	// ErrPreconditionFailed if it was requested again since followUp.Version.
+	DeleteMatchFollowUp(followUp *models.MatchFollowUp, tx *dynamodb.TransactWriteItemsInput) error
 }
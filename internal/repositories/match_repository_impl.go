@@ -5,9 +5,12 @@ import (
 	"github.com/mquan1409/game-api/internal/models"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/mquan1409/game-api/internal/keys"
 )
 
 type MatchDynamoDBRepository struct {
@@ -21,14 +24,28 @@ func NewDynamoDBMatchRepository(db *dynamodb.DynamoDB, tableName string) MatchRe
 
 
 func (r *MatchDynamoDBRepository) GetMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) (*models.Match, error) {
+	match, err := r.findMatch(gameID, matchID, dateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if match == nil {
+		return nil, models.NewNotFoundError("match %s of game %s on %s not found", matchID, gameID, dateID)
+	}
+
+	return match, nil
+}
+
+// findMatch returns the match, or nil if it doesn't exist.
+func (r *MatchDynamoDBRepository) findMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) (*models.Match, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
 			"Id": {
-				S: aws.String(fmt.Sprintf("MATCH_INFO.%s", gameID)),
+				S: aws.String(keys.MatchPartition(gameID)),
 			},
 			"Range": {
-				S: aws.String(fmt.Sprintf("%s.%s", dateID, matchID)),
+				S: aws.String(keys.MatchRange(dateID, matchID)),
 			},
 		},
 	}
@@ -39,15 +56,10 @@ func (r *MatchDynamoDBRepository) GetMatch(gameID models.GameID, matchID models.
 	}
 
 	if result.Item == nil {
-		return nil, errors.New("match not found")
-	}
-
-	match, err := r.unmarshalMatchFromDynamoDB(result.Item)
-	if err != nil {
-		return nil, err
+		return nil, nil
 	}
 
-	return match, nil
+	return r.unmarshalMatchFromDynamoDB(result.Item)
 }
 
 func (r *MatchDynamoDBRepository) GetMatchesByGameAndDate(gameID models.GameID, dateID models.DateID) ([]*models.Match, error) {
@@ -58,112 +70,440 @@ func (r *MatchDynamoDBRepository) GetMatchesByGameAndDate(gameID models.GameID,
 			"#range": aws.String("Range"),
 		},
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":gameID": {S: aws.String(fmt.Sprintf("MATCH_INFO.%s", gameID))},
-			":dateID": {S: aws.String(fmt.Sprintf("%s.", dateID))},
+			":gameID": {S: aws.String(keys.MatchPartition(gameID))},
+			":dateID": {S: aws.String(keys.DatePrefix(dateID))},
 		},
 	}
 
-	result, err := r.db.Query(input)
+	var matches []*models.Match
+	for {
+		result, err := r.db.Query(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Items {
+			match, err := r.unmarshalMatchFromDynamoDB(item)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, match)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return matches, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (r *MatchDynamoDBRepository) GetMatchesByGameAndDateRange(gameID models.GameID, dates models.DateRange, limit int, cursor string) (models.MatchPage, error) {
+	if limit < 1 {
+		return models.MatchPage{}, models.NewValidationError("limit must be at least 1")
+	}
+	startRange, err := DecodeRangeCursor(cursor)
 	if err != nil {
-		return nil, err
+		return models.MatchPage{}, err
+	}
+	// DynamoDB rejects a start key outside the key condition
+	if startRange != "" && (startRange < keys.DateRangeStart(dates.From) || startRange > keys.DateRangeEnd(dates.To)) {
+		return models.MatchPage{}, ErrInvalidCursor
 	}
 
-	var matches []*models.Match
-	for _, item := range result.Items {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		KeyConditionExpression: aws.String("Id = :gameID AND #range BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]*string{
+			"#range": aws.String("Range"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":gameID": {S: aws.String(keys.MatchPartition(gameID))},
+			":from":   {S: aws.String(keys.DateRangeStart(dates.From))},
+			":to":     {S: aws.String(keys.DateRangeEnd(dates.To))},
+		},
+	}
+	if startRange != "" {
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"Id":    {S: aws.String(keys.MatchPartition(gameID))},
+			"Range": {S: aws.String(startRange)},
+		}
+	}
+
+	// Read one item more than requested to know whether another page follows
+	var items []map[string]*dynamodb.AttributeValue
+	for len(items) <= limit {
+		input.Limit = aws.Int64(int64(limit + 1 - len(items)))
+		result, err := r.db.Query(input)
+		if err != nil {
+			return models.MatchPage{}, fmt.Errorf("failed to query matches: %w", err)
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	page := models.MatchPage{GameID: gameID, DateRange: dates, Matches: []*models.Match{}, Limit: limit}
+	if len(items) > limit {
+		items = items[:limit]
+		page.NextCursor = EncodeRangeCursor(*items[limit-1]["Range"].S)
+	}
+	for _, item := range items {
 		match, err := r.unmarshalMatchFromDynamoDB(item)
+		if err != nil {
+			return models.MatchPage{}, err
+		}
+		page.Matches = append(page.Matches, match)
+	}
+	return page, nil
+}
+
+func (r *MatchDynamoDBRepository) GetMatchesByGame(gameID models.GameID) ([]*models.Match, error) {
+	var matches []*models.Match
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :gameID"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":gameID": {S: aws.String(keys.MatchPartition(gameID))},
+			},
+			ExclusiveStartKey: startKey,
+		})
 		if err != nil {
 			return nil, err
 		}
-		matches = append(matches, match)
+
+		for _, item := range result.Items {
+			match, err := r.unmarshalMatchFromDynamoDB(item)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, match)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return matches, nil
+		}
+		startKey = result.LastEvaluatedKey
 	}
+}
 
-	return matches, nil
+// CreateMatch stores the match and adds it to the match history of each of its
+// players.
+func (r *MatchDynamoDBRepository) CreateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error) {
+	match.Version = 0
+	if err := r.putMatch(match, nil, tx); err != nil {
+		return nil, err
+	}
+	return match, nil
 }
 
+// UpdateMatch replaces the match if it is still at match.Version, together
+// with its entries in the match history of its players, removing it from the
+// history of players no longer in it.
+func (r *MatchDynamoDBRepository) UpdateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error) {
+	if match.Version == 0 {
+		return nil, models.NewValidationError("match %s of game %s on %s has no version to update", match.MatchID, match.GameID, match.DateID)
+	}
 
-func (r *MatchDynamoDBRepository) CreateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error) {
-	av, err := r.marshalMatchToDynamoDBAttributeValue(match)
+	oldMatch, err := r.findMatch(match.GameID, match.MatchID, match.DateID)
 	if err != nil {
 		return nil, err
 	}
+	if oldMatch == nil {
+		return nil, models.NewNotFoundError("match %s of game %s on %s not found", match.MatchID, match.GameID, match.DateID)
+	}
+	if err := r.putMatch(match, oldMatch, tx); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
 
-	input := &dynamodb.Put{
-		Item:      av,
-		TableName: aws.String(r.tableName),
+// DeleteMatch removes the match and its entries in the match history of its
+// players.
+func (r *MatchDynamoDBRepository) DeleteMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID, tx *dynamodb.TransactWriteItemsInput) error {
+	oldMatch, err := r.findMatch(gameID, matchID, dateID)
+	if err != nil {
+		return err
 	}
-	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Put: input})
-		return match, nil
+
+	localTx := tx
+	if localTx == nil {
+		localTx = &dynamodb.TransactWriteItemsInput{}
 	}
 
-	_, err = r.db.PutItem(&dynamodb.PutItemInput{
+	localTx.TransactItems = append(localTx.TransactItems, &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
 		TableName: aws.String(r.tableName),
-		Item:      av,
-	})
-	return match, err
+		Key: map[string]*dynamodb.AttributeValue{
+			"Id": {
+				S: aws.String(keys.MatchPartition(gameID)),
+			},
+			"Range": {
+				S: aws.String(keys.MatchRange(dateID, matchID)),
+			},
+		},
+	}})
+	if oldMatch != nil {
+		for _, userMatch := range oldMatch.UserMatches() {
+			localTx.TransactItems = append(localTx.TransactItems, r.deleteUserMatchItem(userMatch))
+		}
+	}
+
+	if tx == nil {
+		_, err = r.db.TransactWriteItems(localTx)
+		return transactionError(localTx, err)
+	}
+	return nil
 }
 
-func (r *MatchDynamoDBRepository) UpdateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error) {
-	av, err := r.marshalMatchToDynamoDBAttributeValue(match)
+// GetMatchFollowUps reads the pending follow-ups with one Query of their
+// partition, following LastEvaluatedKey.
+func (r *MatchDynamoDBRepository) GetMatchFollowUps() ([]*models.MatchFollowUp, error) {
+	followUps := []*models.MatchFollowUp{}
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id": {S: aws.String(keys.MatchFollowUpPartition)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query match follow-ups: %w", err)
+		}
+
+		for _, item := range result.Items {
+			followUp, err := unmarshalMatchFollowUp(item)
+			if err != nil {
+				return nil, err
+			}
+			followUps = append(followUps, followUp)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return followUps, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+func (r *MatchDynamoDBRepository) GetMatchFollowUp(gameID models.GameID, dateID models.DateID) (*models.MatchFollowUp, error) {
+	result, err := r.db.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(r.tableName),
+		Key:            matchFollowUpKey(gameID, dateID),
+		ConsistentRead: aws.Bool(true),
+	})
 	if err != nil {
 		return nil, err
 	}
+	if result.Item == nil {
+		return nil, models.NewNotFoundError("matches of game %s on %s have no pending follow-up", gameID, dateID)
+	}
+	return unmarshalMatchFollowUp(result.Item)
+}
 
-	input := &dynamodb.Put{
-		Item:      av,
-		TableName: aws.String(r.tableName),
+// RequestMatchFollowUp adds the players and leavers to the follow-up's string
+// sets and increments its version with an ADD update, which creates it if
+// there is none, so concurrent requests all count.
+func (r *MatchDynamoDBRepository) RequestMatchFollowUp(gameID models.GameID, dateID models.DateID, players, leavers []models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
+	updateExpression := "ADD #Version :one"
+	values := map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}}
+	// DynamoDB rejects empty sets
+	if len(players) > 0 {
+		updateExpression += ", Players :players"
+		values[":players"] = userIDSet(players)
+	}
+	if len(leavers) > 0 {
+		updateExpression += ", Leavers :leavers"
+		values[":leavers"] = userIDSet(leavers)
+	}
+	update := &dynamodb.Update{
+		TableName:                 aws.String(r.tableName),
+		Key:                       matchFollowUpKey(gameID, dateID),
+		UpdateExpression:          aws.String(updateExpression),
+		ExpressionAttributeNames:  map[string]*string{"#Version": aws.String(versionAttribute)},
+		ExpressionAttributeValues: values,
 	}
+
 	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Put: input})
-		return match, nil
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Update: update})
+		return nil
 	}
 
-	_, err = r.db.PutItem(&dynamodb.PutItemInput{
-		TableName: aws.String(r.tableName),
-		Item:      av,
+	_, err := r.db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
 	})
-	return match, err
+	return err
 }
 
-func (r *MatchDynamoDBRepository) DeleteMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID, tx *dynamodb.TransactWriteItemsInput) error {
-	input := &dynamodb.Delete{
-		TableName: aws.String(r.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"Id": {
-				S: aws.String(fmt.Sprintf("MATCH_INFO.%s", string(gameID))),
-			},
-			"Range": {
-				S: aws.String(fmt.Sprintf("%s.%s", string(dateID), string(matchID))),
-			},
-		},
+func (r *MatchDynamoDBRepository) DeleteMatchFollowUp(followUp *models.MatchFollowUp, tx *dynamodb.TransactWriteItemsInput) error {
+	deleteItem := &dynamodb.Delete{
+		TableName:                 aws.String(r.tableName),
+		Key:                       matchFollowUpKey(followUp.GameID, followUp.DateID),
+		ConditionExpression:       aws.String(versionCondition(followUp.Version)),
+		ExpressionAttributeNames:  map[string]*string{"#Version": aws.String(versionAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":version": {N: aws.String(strconv.Itoa(followUp.Version))}},
 	}
+
 	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Delete: input})
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Delete: deleteItem})
 		return nil
 	}
 
 	_, err := r.db.DeleteItem(&dynamodb.DeleteItemInput{
-		TableName: input.TableName,
-		Key:       input.Key,
+		TableName:                 deleteItem.TableName,
+		Key:                       deleteItem.Key,
+		ConditionExpression:       deleteItem.ConditionExpression,
+		ExpressionAttributeNames:  deleteItem.ExpressionAttributeNames,
+		ExpressionAttributeValues: deleteItem.ExpressionAttributeValues,
 	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return models.NewPreconditionFailedError("the follow-up of game %s on %s was requested again since version %d", followUp.GameID, followUp.DateID, followUp.Version)
+	}
 	return err
 }
 
+func matchFollowUpKey(gameID models.GameID, dateID models.DateID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Id":    {S: aws.String(keys.MatchFollowUpPartition)},
+		"Range": {S: aws.String(keys.MatchFollowUpRange(gameID, dateID))},
+	}
+}
+
+func userIDSet(userIDs []models.UserID) *dynamodb.AttributeValue {
+	set := &dynamodb.AttributeValue{SS: make([]*string, len(userIDs))}
+	for i, userID := range userIDs {
+		set.SS[i] = aws.String(string(userID))
+	}
+	return set
+}
+
+func unmarshalMatchFollowUp(item map[string]*dynamodb.AttributeValue) (*models.MatchFollowUp, error) {
+	if item["Range"] == nil || item["Range"].S == nil {
+		return nil, errors.New("match follow-up item is missing its key")
+	}
+	gameID, dateID, err := keys.ParseMatchFollowUpRange(*item["Range"].S)
+	if err != nil {
+		return nil, err
+	}
+	version, err := unmarshalVersion(item)
+	if err != nil {
+		return nil, err
+	}
+	followUp := &models.MatchFollowUp{GameID: gameID, DateID: dateID, Players: []models.UserID{}, Leavers: []models.UserID{}, Version: version}
+	// A set the follow-up never got is absent
+	if item["Players"] != nil {
+		for _, userID := range item["Players"].SS {
+			followUp.Players = append(followUp.Players, models.UserID(*userID))
+		}
+	}
+	if item["Leavers"] != nil {
+		for _, userID := range item["Leavers"].SS {
+			followUp.Leavers = append(followUp.Leavers, models.UserID(*userID))
+		}
+	}
+	return followUp, nil
+}
+
+// putMatch stages the match, guarded by its version, and its players' history
+// entries, and deletes the entries oldMatch had for players that are not in
+// match. It then sets match.Version to the version written.
+func (r *MatchDynamoDBRepository) putMatch(match *models.Match, oldMatch *models.Match, tx *dynamodb.TransactWriteItemsInput) error {
+	av, err := r.marshalMatchToDynamoDBAttributeValue(match)
+	if err != nil {
+		return err
+	}
+
+	localTx := tx
+	if localTx == nil {
+		localTx = &dynamodb.TransactWriteItemsInput{}
+	}
+
+	put := &dynamodb.Put{
+		Item:      av,
+		TableName: aws.String(r.tableName),
+	}
+	guardVersion(put, match.Version)
+	localTx.TransactItems = append(localTx.TransactItems, &dynamodb.TransactWriteItem{Put: put})
+	players := match.Players()
+	for _, userMatch := range match.UserMatches() {
+		localTx.TransactItems = append(localTx.TransactItems, &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+			Item:      marshalUserMatchToDynamoDB(userMatch),
+			TableName: aws.String(r.tableName),
+		}})
+	}
+	if oldMatch != nil {
+		for _, userMatch := range oldMatch.UserMatches() {
+			if !slices.Contains(players, userMatch.UserID) {
+				localTx.TransactItems = append(localTx.TransactItems, r.deleteUserMatchItem(userMatch))
+			}
+		}
+	}
+
+	if tx == nil {
+		if _, err := r.db.TransactWriteItems(localTx); err != nil {
+			return transactionError(localTx, err)
+		}
+	}
+	match.Version++
+	return nil
+}
+
+// parseMatchKey reads the game, date and match ID from the key of a match item.
+func parseMatchKey(item map[string]*dynamodb.AttributeValue) (models.GameID, models.DateID, models.MatchID, error) {
+	if item["Id"] == nil || item["Id"].S == nil || item["Range"] == nil || item["Range"].S == nil {
+		return "", "", "", fmt.Errorf("match item is missing its key")
+	}
+	return keys.ParseMatchKey(*item["Id"].S, *item["Range"].S)
+}
+
+// matchListAttribute returns the list stored under name in a match item.
+func matchListAttribute(item map[string]*dynamodb.AttributeValue, name string) ([]*dynamodb.AttributeValue, error) {
+	if item[name] == nil || item[name].L == nil {
+		return nil, fmt.Errorf("match item is missing its %s list", name)
+	}
+	return item[name].L, nil
+}
+
 func (r *MatchDynamoDBRepository) unmarshalMatchFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*models.Match, error) {
 	// Unmarshal basic fields
-	matchID := models.MatchID((*item["Range"].S)[11:])
-	dateID := models.DateID((*item["Range"].S)[:10])
-	gameID := models.GameID((*item["Id"].S)[11:])
+	gameID, dateID, matchID, err := parseMatchKey(item)
+	if err != nil {
+		return nil, err
+	}
 
 	// Unmarshal TeamNames
+	teamNamesAV, err := matchListAttribute(item, "TeamNames")
+	if err != nil {
+		return nil, err
+	}
 	var teamNames []string
-	for _, nameAV := range item["TeamNames"].L {
+	for _, nameAV := range teamNamesAV {
+		if nameAV == nil || nameAV.S == nil {
+			return nil, fmt.Errorf("match %s has an invalid team name", matchID)
+		}
 		teamNames = append(teamNames, *nameAV.S)
 	}
 
 	// Unmarshal TeamScores
+	teamScoresAV, err := matchListAttribute(item, "TeamScores")
+	if err != nil {
+		return nil, err
+	}
 	var teamScores []int
-	for _, scoreAV := range item["TeamScores"].L {
+	for _, scoreAV := range teamScoresAV {
+		if scoreAV == nil || scoreAV.N == nil {
+			return nil, fmt.Errorf("match %s has an invalid team score", matchID)
+		}
 		score, err := strconv.Atoi(*scoreAV.N)
 		if err != nil {
 			return nil, err
@@ -172,10 +512,20 @@ func (r *MatchDynamoDBRepository) unmarshalMatchFromDynamoDB(item map[string]*dy
 	}
 
 	// Unmarshal TeamMembers
+	teamMembersAV, err := matchListAttribute(item, "TeamMembers")
+	if err != nil {
+		return nil, err
+	}
 	var teamMembers [][]string
-	for _, teamAV := range item["TeamMembers"].L {
+	for _, teamAV := range teamMembersAV {
+		if teamAV == nil {
+			return nil, fmt.Errorf("match %s has an invalid team", matchID)
+		}
 		var team []string
 		for _, memberAV := range teamAV.L {
+			if memberAV == nil || memberAV.S == nil {
+				return nil, fmt.Errorf("match %s has an invalid team member", matchID)
+			}
 			team = append(team, *memberAV.S)
 		}
 		teamMembers = append(teamMembers, team)
@@ -183,21 +533,33 @@ func (r *MatchDynamoDBRepository) unmarshalMatchFromDynamoDB(item map[string]*dy
 
 	// Unmarshal PlayerAttributes
 	playerAttributesMap := make(map[models.UserID]models.AttributesStatsMap)
-	for userID, attributesAV := range item["PlayerAttributes"].M {
-		attributesMap := make(models.AttributesStatsMap)
-		for attrName, attrValueAV := range attributesAV.M {
-			attrValue, err := strconv.Atoi(*attrValueAV.N)
-			if err != nil {
-				return nil, err
+	if item["PlayerAttributes"] != nil {
+		for userID, attributesAV := range item["PlayerAttributes"].M {
+			if attributesAV == nil {
+				return nil, fmt.Errorf("match %s has invalid attributes for player %s", matchID, userID)
+			}
+			attributesMap := make(models.AttributesStatsMap)
+			for attrName, attrValueAV := range attributesAV.M {
+				if attrValueAV == nil || attrValueAV.N == nil {
+					return nil, fmt.Errorf("match %s has an invalid %s for player %s", matchID, attrName, userID)
+				}
+				attrValue, err := strconv.Atoi(*attrValueAV.N)
+				if err != nil {
+					return nil, err
+				}
+				attributesMap[models.AttributeName(attrName)] = models.AttributeStat(attrValue)
 			}
-			attributesMap[models.AttributeName(attrName)] = models.AttributeStat(attrValue)
+			playerAttributesMap[models.UserID(userID)] = attributesMap
 		}
-		playerAttributesMap[models.UserID(userID)] = attributesMap
 	}
 
-	// Use NewMatch constructor without playerAttributesMap
+	// NewMatch rejects stored items with an invalid date or teams, which are
+	// internal errors rather than invalid input
 	match, err := models.NewMatch(matchID, dateID, gameID, teamNames, teamScores, teamMembers, playerAttributesMap)
 	if err != nil {
+		return nil, fmt.Errorf("invalid match item %s/%s: %v", *item["Id"].S, *item["Range"].S, err)
+	}
+	if match.Version, err = unmarshalVersion(item); err != nil {
 		return nil, err
 	}
 
@@ -208,8 +570,8 @@ func (r *MatchDynamoDBRepository) marshalMatchToDynamoDBAttributeValue(match *mo
 	av := make(map[string]*dynamodb.AttributeValue)
 
 	// Set Id and Range
-	av["Id"] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("MATCH_INFO.%s", match.GameID))}
-	av["Range"] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("%s.%s", match.DateID, match.MatchID))}
+	av["Id"] = &dynamodb.AttributeValue{S: aws.String(keys.MatchPartition(match.GameID))}
+	av["Range"] = &dynamodb.AttributeValue{S: aws.String(keys.MatchRange(match.DateID, match.MatchID))}
 
 	// Marshal TeamNames
 	teamNames, err := dynamodbattribute.MarshalList(match.TeamNames)
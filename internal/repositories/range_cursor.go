@@ -0,0 +1,22 @@
+package repositories
+
+import "encoding/base64"
+
+// EncodeRangeCursor returns the opaque token handed to clients for the page
+// that continues after the item stored under rangeKey.
+func EncodeRangeCursor(rangeKey string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(rangeKey))
+}
+
+// DecodeRangeCursor reverses EncodeRangeCursor. An empty cursor means the
+// first page and decodes to an empty Range key.
+func DecodeRangeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return string(data), nil
+}
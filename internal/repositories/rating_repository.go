@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+type RatingRepository interface {
+	GetRating(gameID models.GameID, userID models.UserID) (*models.Rating, error)
+	GetRatingsByGame(gameID models.GameID) ([]*models.Rating, error)
+	UpdateRating(rating *models.Rating, tx *dynamodb.TransactWriteItemsInput) error
+	DeleteRating(gameID models.GameID, userID models.UserID, tx *dynamodb.TransactWriteItemsInput) error
+	// GetRatingRecomputes returns the pending recomputes of every game.
+	GetRatingRecomputes() ([]*models.RatingRecompute, error)
+	// GetRatingRecompute returns the game's pending recompute, or an
+	// ErrNotFound error if there is none.
+	GetRatingRecompute(gameID models.GameID) (*models.RatingRecompute, error)
+	// RequestRatingRecompute records that the game's ratings must be
+	// recomputed, adding a request to the pending recompute if there is one.
+	RequestRatingRecompute(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error
+	// CheckNoRatingRecompute makes tx fail with ErrPreconditionFailed if a
+	// recompute of the game's ratings is pending when it runs.
+	CheckNoRatingRecompute(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error
+	// DeleteRatingRecompute drops a finished recompute, failing with
+	// ErrPreconditionFailed if it was requested again since it was read.
+	DeleteRatingRecompute(recompute *models.RatingRecompute, tx *dynamodb.TransactWriteItemsInput) error
+}
@@ -0,0 +1,313 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+type DynamoDBRatingRepository struct {
+	db        *dynamodb.DynamoDB
+	tableName string
+}
+
+func NewDynamoDBRatingRepository(db *dynamodb.DynamoDB, tableName string) RatingRepository {
+	return &DynamoDBRatingRepository{db: db, tableName: tableName}
+}
+
+func (r *DynamoDBRatingRepository) GetRating(gameID models.GameID, userID models.UserID) (*models.Rating, error) {
+	result, err := r.db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.ratingKey(gameID, userID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, models.NewNotFoundError("user %s has no rating in game %s", userID, gameID)
+	}
+
+	return unmarshalRatingFromDynamoDB(result.Item)
+}
+
+// GetRatingsByGame returns the ratings of every player of the game ordered by
+// UserID.
+func (r *DynamoDBRatingRepository) GetRatingsByGame(gameID models.GameID) ([]*models.Rating, error) {
+	ratings := []*models.Rating{}
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id": {S: aws.String(keys.RatingPartition(gameID))},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query ratings: %w", err)
+		}
+
+		for _, item := range result.Items {
+			rating, err := unmarshalRatingFromDynamoDB(item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal rating: %w", err)
+			}
+			ratings = append(ratings, rating)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return ratings, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+func (r *DynamoDBRatingRepository) UpdateRating(rating *models.Rating, tx *dynamodb.TransactWriteItemsInput) error {
+	putItem := &dynamodb.Put{
+		TableName: aws.String(r.tableName),
+		Item:      marshalRatingToDynamoDB(rating),
+	}
+
+	if tx != nil {
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Put: putItem})
+		return nil
+	}
+
+	_, err := r.db.PutItem(&dynamodb.PutItemInput{
+		TableName: putItem.TableName,
+		Item:      putItem.Item,
+	})
+	return err
+}
+
+func (r *DynamoDBRatingRepository) DeleteRating(gameID models.GameID, userID models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
+	deleteItem := &dynamodb.Delete{
+		TableName: aws.String(r.tableName),
+		Key:       r.ratingKey(gameID, userID),
+	}
+
+	if tx != nil {
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Delete: deleteItem})
+		return nil
+	}
+
+	_, err := r.db.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: deleteItem.TableName,
+		Key:       deleteItem.Key,
+	})
+	return err
+}
+
+// GetRatingRecomputes reads the pending recomputes with one Query of their
+// partition, following LastEvaluatedKey.
+func (r *DynamoDBRatingRepository) GetRatingRecomputes() ([]*models.RatingRecompute, error) {
+	recomputes := []*models.RatingRecompute{}
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id": {S: aws.String(keys.RatingRecomputePartition)},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query rating recomputes: %w", err)
+		}
+
+		for _, item := range result.Items {
+			recompute, err := unmarshalRatingRecompute(item)
+			if err != nil {
+				return nil, err
+			}
+			recomputes = append(recomputes, recompute)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return recomputes, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+func (r *DynamoDBRatingRepository) GetRatingRecompute(gameID models.GameID) (*models.RatingRecompute, error) {
+	result, err := r.db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       ratingRecomputeKey(gameID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, models.NewNotFoundError("game %s has no pending rating recompute", gameID)
+	}
+	return unmarshalRatingRecompute(result.Item)
+}
+
+// RequestRatingRecompute increments the recompute's version with an ADD
+// update, which creates it if there is none, so concurrent requests all
+// count.
+func (r *DynamoDBRatingRepository) RequestRatingRecompute(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	update := &dynamodb.Update{
+		TableName:                 aws.String(r.tableName),
+		Key:                       ratingRecomputeKey(gameID),
+		UpdateExpression:          aws.String("ADD #Version :one"),
+		ExpressionAttributeNames:  map[string]*string{"#Version": aws.String(versionAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}},
+	}
+
+	if tx != nil {
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Update: update})
+		return nil
+	}
+
+	_, err := r.db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+	})
+	return err
+}
+
+// CheckNoRatingRecompute stages a condition check on the version attribute,
+// which every recompute has, so a failure reads as ErrPreconditionFailed.
+// Without tx, the recompute is read instead.
+func (r *DynamoDBRatingRepository) CheckNoRatingRecompute(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	if tx == nil {
+		_, err := r.GetRatingRecompute(gameID)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return models.NewPreconditionFailedError("the ratings of game %s are being recomputed", gameID)
+	}
+
+	tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{
+		TableName:                aws.String(r.tableName),
+		Key:                      ratingRecomputeKey(gameID),
+		ConditionExpression:      aws.String("attribute_not_exists(#Version)"),
+		ExpressionAttributeNames: map[string]*string{"#Version": aws.String(versionAttribute)},
+	}})
+	return nil
+}
+
+func (r *DynamoDBRatingRepository) DeleteRatingRecompute(recompute *models.RatingRecompute, tx *dynamodb.TransactWriteItemsInput) error {
+	deleteItem := &dynamodb.Delete{
+		TableName:                 aws.String(r.tableName),
+		Key:                       ratingRecomputeKey(recompute.GameID),
+		ConditionExpression:       aws.String(versionCondition(recompute.Version)),
+		ExpressionAttributeNames:  map[string]*string{"#Version": aws.String(versionAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":version": {N: aws.String(strconv.Itoa(recompute.Version))}},
+	}
+
+	if tx != nil {
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Delete: deleteItem})
+		return nil
+	}
+
+	_, err := r.db.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                 deleteItem.TableName,
+		Key:                       deleteItem.Key,
+		ConditionExpression:       deleteItem.ConditionExpression,
+		ExpressionAttributeNames:  deleteItem.ExpressionAttributeNames,
+		ExpressionAttributeValues: deleteItem.ExpressionAttributeValues,
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return models.NewPreconditionFailedError("the ratings of game %s were requested again since version %d", recompute.GameID, recompute.Version)
+	}
+	return err
+}
+
+func ratingRecomputeKey(gameID models.GameID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Id":    {S: aws.String(keys.RatingRecomputePartition)},
+		"Range": {S: aws.String(keys.GameRange(gameID))},
+	}
+}
+
+func unmarshalRatingRecompute(item map[string]*dynamodb.AttributeValue) (*models.RatingRecompute, error) {
+	if item["Range"] == nil || item["Range"].S == nil {
+		return nil, errors.New("rating recompute item is missing its key")
+	}
+	gameID, err := keys.ParseGameRange(*item["Range"].S)
+	if err != nil {
+		return nil, err
+	}
+	version, err := unmarshalVersion(item)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RatingRecompute{GameID: gameID, Version: version}, nil
+}
+
+func (r *DynamoDBRatingRepository) ratingKey(gameID models.GameID, userID models.UserID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Id":    {S: aws.String(keys.RatingPartition(gameID))},
+		"Range": {S: aws.String(keys.RatingRange(userID))},
+	}
+}
+
+func marshalRatingToDynamoDB(rating *models.Rating) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Id":            {S: aws.String(keys.RatingPartition(rating.GameID))},
+		"Range":         {S: aws.String(keys.RatingRange(rating.UserID))},
+		"GameId":        {S: aws.String(string(rating.GameID))},
+		"Mu":            {N: aws.String(strconv.FormatFloat(rating.Mu, 'g', -1, 64))},
+		"Sigma":         {N: aws.String(strconv.FormatFloat(rating.Sigma, 'g', -1, 64))},
+		"Volatility":    {N: aws.String(strconv.FormatFloat(rating.Volatility, 'g', -1, 64))},
+		"Value":         {N: aws.String(strconv.Itoa(int(rating.Value)))},
+		"MatchesPlayed": {N: aws.String(strconv.Itoa(rating.MatchesPlayed))},
+		"LastDateId":    {S: aws.String(string(rating.LastDateID))},
+		"LastMatchId":   {S: aws.String(string(rating.LastMatchID))},
+	}
+}
+
+func unmarshalRatingFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*models.Rating, error) {
+	for _, name := range []string{"Range", "GameId", "LastDateId", "LastMatchId"} {
+		if attr, ok := item[name]; !ok || attr.S == nil {
+			return nil, fmt.Errorf("error %s is missing or invalid", name)
+		}
+	}
+
+	numbers := make(map[string]float64)
+	for _, name := range []string{"Mu", "Sigma", "Volatility", "Value", "MatchesPlayed"} {
+		attr, ok := item[name]
+		if !ok || attr.N == nil {
+			return nil, fmt.Errorf("error %s is missing or invalid", name)
+		}
+		value, err := strconv.ParseFloat(*attr.N, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", name, err)
+		}
+		numbers[name] = value
+	}
+
+	userID, err := keys.ParseRatingRange(*item["Range"].S)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Rating{
+		GameID:        models.GameID(*item["GameId"].S),
+		UserID:        userID,
+		Mu:            numbers["Mu"],
+		Sigma:         numbers["Sigma"],
+		Volatility:    numbers["Volatility"],
+		Value:         models.AttributeStat(numbers["Value"]),
+		MatchesPlayed: int(numbers["MatchesPlayed"]),
+		LastDateID:    models.DateID(*item["LastDateId"].S),
+		LastMatchID:   models.MatchID(*item["LastMatchId"].S),
+	}, nil
+}
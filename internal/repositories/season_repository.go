@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+type SeasonRepository interface {
+	GetSeason(gameID models.GameID, seasonID models.SeasonID) (*models.Season, error)
+	GetSeasonsByGame(gameID models.GameID) ([]*models.Season, error)
+	CreateSeason(season *models.Season, tx *dynamodb.TransactWriteItemsInput) (*models.Season, error)
+	UpdateSeason(season *models.Season, tx *dynamodb.TransactWriteItemsInput) (*models.Season, error)
+	DeleteSeason(gameID models.GameID, seasonID models.SeasonID, tx *dynamodb.TransactWriteItemsInput) error
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+type DynamoDBSeasonRepository struct {
+	db        *dynamodb.DynamoDB
+	tableName string
+}
+
+func NewDynamoDBSeasonRepository(db *dynamodb.DynamoDB, tableName string) SeasonRepository {
+	return &DynamoDBSeasonRepository{db: db, tableName: tableName}
+}
+
+func (r *DynamoDBSeasonRepository) GetSeason(gameID models.GameID, seasonID models.SeasonID) (*models.Season, error) {
+	result, err := r.db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.seasonKey(gameID, seasonID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, models.NewNotFoundError("season %s of game %s not found", seasonID, gameID)
+	}
+
+	return unmarshalSeasonFromDynamoDB(result.Item)
+}
+
+// GetSeasonsByGame returns the game's seasons ordered by SeasonID.
+func (r *DynamoDBSeasonRepository) GetSeasonsByGame(gameID models.GameID) ([]*models.Season, error) {
+	seasons := []*models.Season{}
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.db.Query(&dynamodb.QueryInput{
+			TableName:              aws.String(r.tableName),
+			KeyConditionExpression: aws.String("Id = :id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id": {S: aws.String(keys.SeasonPartition(gameID))},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query seasons: %w", err)
+		}
+
+		for _, item := range result.Items {
+			season, err := unmarshalSeasonFromDynamoDB(item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal season: %w", err)
+			}
+			seasons = append(seasons, season)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return seasons, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+func (r *DynamoDBSeasonRepository) CreateSeason(season *models.Season, tx *dynamodb.TransactWriteItemsInput) (*models.Season, error) {
+	return season, r.putSeason(season, itemNotExistsCondition, models.NewConflictError("season %s already exists", season.SeasonID), tx)
+}
+
+func (r *DynamoDBSeasonRepository) UpdateSeason(season *models.Season, tx *dynamodb.TransactWriteItemsInput) (*models.Season, error) {
+	return season, r.putSeason(season, itemExistsCondition, models.NewNotFoundError("season %s of game %s not found", season.SeasonID, season.GameID), tx)
+}
+
+func (r *DynamoDBSeasonRepository) DeleteSeason(gameID models.GameID, seasonID models.SeasonID, tx *dynamodb.TransactWriteItemsInput) error {
+	deleteItem := &dynamodb.Delete{
+		TableName: aws.String(r.tableName),
+		Key:       r.seasonKey(gameID, seasonID),
+	}
+
+	if tx != nil {
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Delete: deleteItem})
+		return nil
+	}
+
+	_, err := r.db.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: deleteItem.TableName,
+		Key:       deleteItem.Key,
+	})
+	return err
+}
+
+// putSeason writes the season guarded by condition, returning failed when
+// the condition doesn't hold.
+func (r *DynamoDBSeasonRepository) putSeason(season *models.Season, condition string, failed error, tx *dynamodb.TransactWriteItemsInput) error {
+	putItem := &dynamodb.Put{
+		TableName:           aws.String(r.tableName),
+		Item:                marshalSeasonToDynamoDB(season),
+		ConditionExpression: aws.String(condition),
+	}
+
+	err := conditionalPut(r.db, putItem, tx, failed)
+	if err != nil {
+		return fmt.Errorf("failed to put season: %w", err)
+	}
+	return nil
+}
+
+func (r *DynamoDBSeasonRepository) seasonKey(gameID models.GameID, seasonID models.SeasonID) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Id":    {S: aws.String(keys.SeasonPartition(gameID))},
+		"Range": {S: aws.String(keys.SeasonRange(seasonID))},
+	}
+}
+
+func marshalSeasonToDynamoDB(season *models.Season) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"Id":        {S: aws.String(keys.SeasonPartition(season.GameID))},
+		"Range":     {S: aws.String(keys.SeasonRange(season.SeasonID))},
+		"GameId":    {S: aws.String(string(season.GameID))},
+		"Name":      {S: aws.String(season.Name)},
+		"StartDate": {S: aws.String(string(season.StartDate))},
+		"EndDate":   {S: aws.String(string(season.EndDate))},
+		"Closed":    {BOOL: aws.Bool(season.Closed)},
+	}
+}
+
+func unmarshalSeasonFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*models.Season, error) {
+	for _, name := range []string{"Range", "GameId", "Name", "StartDate", "EndDate"} {
+		if attr, ok := item[name]; !ok || attr.S == nil {
+			return nil, fmt.Errorf("error %s is missing or invalid", name)
+		}
+	}
+
+	seasonID, err := keys.ParseSeasonRange(*item["Range"].S)
+	if err != nil {
+		return nil, err
+	}
+	season, err := models.NewSeason(
+		models.GameID(*item["GameId"].S),
+		seasonID,
+		*item["Name"].S,
+		models.DateID(*item["StartDate"].S),
+		models.DateID(*item["EndDate"].S),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if closed, ok := item["Closed"]; ok && closed.BOOL != nil {
+		season.Closed = *closed.BOOL
+	}
+	return season, nil
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// MaxTransactionItems is the largest number of actions DynamoDB accepts in a
+// single TransactWriteItems call.
+const MaxTransactionItems = 100
+
+type TransactionRepository interface {
+	ExecuteTransaction(tx *dynamodb.TransactWriteItemsInput) error
+}
+
+// CheckTransaction rejects the transactions DynamoDB refuses before running
+// them: those with more than MaxTransactionItems writes, and those with more
+// than one write to the same item.
+func CheckTransaction(tx *dynamodb.TransactWriteItemsInput) error {
+	if len(tx.TransactItems) > MaxTransactionItems {
+		return models.NewValidationError("transaction contains %d writes, exceeding the limit of %d", len(tx.TransactItems), MaxTransactionItems)
+	}
+	written := make(map[[2]string]bool, len(tx.TransactItems))
+	for _, item := range tx.TransactItems {
+		id, rangeKey := transactItemKey(item)
+		if written[[2]string{id, rangeKey}] {
+			return models.NewValidationError("transaction contains more than one write to item %s/%s", id, rangeKey)
+		}
+		written[[2]string{id, rangeKey}] = true
+	}
+	return nil
+}
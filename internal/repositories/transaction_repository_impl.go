@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type DynamoDBTransactionRepository struct {
+	db *dynamodb.DynamoDB
+}
+
+func NewDynamoDBTransactionRepository(db *dynamodb.DynamoDB) TransactionRepository {
+	return &DynamoDBTransactionRepository{db: db}
+}
+
+// ExecuteTransaction submits every write collected in tx as one all-or-nothing
+// TransactWriteItems call. Transactions larger than MaxTransactionItems are
+// rejected rather than split, since splitting would lose atomicity.
+func (r *DynamoDBTransactionRepository) ExecuteTransaction(tx *dynamodb.TransactWriteItemsInput) error {
+	if tx == nil || len(tx.TransactItems) == 0 {
+		return nil
+	}
+	if err := CheckTransaction(tx); err != nil {
+		return err
+	}
+
+	_, err := r.db.TransactWriteItems(tx)
+	if err != nil {
+		return fmt.Errorf("failed to execute transaction: %w", transactionError(tx, err))
+	}
+	return nil
+}
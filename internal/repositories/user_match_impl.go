@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// GetUserMatches returns a page of the user's match history, newest first. A
+// game filter is applied after reading, so the query keeps reading until the
+// page is full or the history is exhausted.
+func (r *MatchDynamoDBRepository) GetUserMatches(userID models.UserID, filter models.UserMatchFilter, limit int, cursor string) (models.UserMatchHistory, error) {
+	if limit < 1 {
+		return models.UserMatchHistory{}, models.NewValidationError("limit must be at least 1")
+	}
+	startRange, err := DecodeRangeCursor(cursor)
+	if err != nil {
+		return models.UserMatchHistory{}, err
+	}
+	// DynamoDB rejects a start key outside the key condition
+	if startRange != "" && !userMatchRangeInFilter(startRange, filter) {
+		return models.UserMatchHistory{}, ErrInvalidCursor
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName: aws.String(r.tableName),
+		ExpressionAttributeNames: map[string]*string{
+			"#range": aws.String("Range"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {S: aws.String(keys.UserMatchPartition(userID))},
+		},
+		ScanIndexForward: aws.Bool(false),
+		// The match service decides from the history whether a player still
+		// has matches of a game right after deleting one
+		ConsistentRead: aws.Bool(true),
+	}
+
+	keyCondition := "Id = :id"
+	switch {
+	case filter.From != "" && filter.To != "":
+		keyCondition += " AND #range BETWEEN :from AND :to"
+	case filter.From != "":
+		keyCondition += " AND #range >= :from"
+	case filter.To != "":
+		keyCondition += " AND #range <= :to"
+	default:
+		input.ExpressionAttributeNames = nil
+	}
+	input.KeyConditionExpression = aws.String(keyCondition)
+	if filter.From != "" {
+		input.ExpressionAttributeValues[":from"] = &dynamodb.AttributeValue{S: aws.String(keys.DateRangeStart(filter.From))}
+	}
+	if filter.To != "" {
+		input.ExpressionAttributeValues[":to"] = &dynamodb.AttributeValue{S: aws.String(keys.DateRangeEnd(filter.To))}
+	}
+	if filter.GameID != "" {
+		input.FilterExpression = aws.String("GameId = :gameId")
+		input.ExpressionAttributeValues[":gameId"] = &dynamodb.AttributeValue{S: aws.String(string(filter.GameID))}
+	}
+	if startRange != "" {
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"Id":    {S: aws.String(keys.UserMatchPartition(userID))},
+			"Range": {S: aws.String(startRange)},
+		}
+	}
+
+	// Read one item more than requested to know whether another page follows
+	var items []map[string]*dynamodb.AttributeValue
+	for len(items) <= limit {
+		input.Limit = aws.Int64(int64(limit + 1 - len(items)))
+		result, err := r.db.Query(input)
+		if err != nil {
+			return models.UserMatchHistory{}, fmt.Errorf("failed to query match history: %w", err)
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	history := models.UserMatchHistory{UserID: userID, Matches: []*models.UserMatch{}, Limit: limit}
+	if len(items) > limit {
+		items = items[:limit]
+		history.NextCursor = EncodeRangeCursor(*items[limit-1]["Range"].S)
+	}
+	for _, item := range items {
+		userMatch, err := unmarshalUserMatchFromDynamoDB(item)
+		if err != nil {
+			return models.UserMatchHistory{}, err
+		}
+		history.Matches = append(history.Matches, userMatch)
+	}
+	return history, nil
+}
+
+func (r *MatchDynamoDBRepository) deleteUserMatchItem(userMatch *models.UserMatch) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Id":    {S: aws.String(keys.UserMatchPartition(userMatch.UserID))},
+			"Range": {S: aws.String(keys.UserMatchRange(userMatch.DateID, userMatch.GameID, userMatch.MatchID))},
+		},
+	}}
+}
+
+// userMatchRangeInFilter reports whether rangeKey lies within the dates of
+// filter.
+func userMatchRangeInFilter(rangeKey string, filter models.UserMatchFilter) bool {
+	if filter.From != "" && rangeKey < keys.DateRangeStart(filter.From) {
+		return false
+	}
+	return filter.To == "" || rangeKey <= keys.DateRangeEnd(filter.To)
+}
+
+func marshalUserMatchToDynamoDB(userMatch *models.UserMatch) map[string]*dynamodb.AttributeValue {
+	attributes := make(map[string]*dynamodb.AttributeValue)
+	for attrName, attrValue := range userMatch.Attributes {
+		attributes[string(attrName)] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(int(attrValue)))}
+	}
+
+	return map[string]*dynamodb.AttributeValue{
+		"Id":         {S: aws.String(keys.UserMatchPartition(userMatch.UserID))},
+		"Range":      {S: aws.String(keys.UserMatchRange(userMatch.DateID, userMatch.GameID, userMatch.MatchID))},
+		"UserId":     {S: aws.String(string(userMatch.UserID))},
+		"GameId":     {S: aws.String(string(userMatch.GameID))},
+		"DateId":     {S: aws.String(string(userMatch.DateID))},
+		"MatchId":    {S: aws.String(string(userMatch.MatchID))},
+		"TeamName":   {S: aws.String(userMatch.TeamName)},
+		"Result":     {S: aws.String(string(userMatch.Result))},
+		"Attributes": {M: attributes},
+	}
+}
+
+func unmarshalUserMatchFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*models.UserMatch, error) {
+	for _, name := range []string{"UserId", "GameId", "DateId", "MatchId", "TeamName", "Result"} {
+		if attr, ok := item[name]; !ok || attr.S == nil {
+			return nil, fmt.Errorf("error %s is missing or invalid", name)
+		}
+	}
+
+	attributes := make(models.AttributesStatsMap)
+	if attributesAV, ok := item["Attributes"]; ok {
+		for attrName, attrValue := range attributesAV.M {
+			value, err := strconv.Atoi(*attrValue.N)
+			if err != nil {
+				return nil, err
+			}
+			attributes[models.AttributeName(attrName)] = models.AttributeStat(value)
+		}
+	}
+
+	return &models.UserMatch{
+		UserID:     models.UserID(*item["UserId"].S),
+		GameID:     models.GameID(*item["GameId"].S),
+		DateID:     models.DateID(*item["DateId"].S),
+		MatchID:    models.MatchID(*item["MatchId"].S),
+		TeamName:   *item["TeamName"].S,
+		Result:     models.MatchResult(*item["Result"].S),
+		Attributes: attributes,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/keys"
+)
+
+// BackfillUserMatches writes the match history entries of every stored match,
+// for tables holding matches created before the history was kept. Entries are
+// overwritten with the same content, so the backfill can be re-run safely. It
+// returns the number of entries written.
+func BackfillUserMatches(db *dynamodb.DynamoDB, tableName string) (int, error) {
+	repository := &MatchDynamoDBRepository{db: db, tableName: tableName}
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("begins_with(Id, :prefix)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {S: aws.String(keys.MatchPartitionPrefix)},
+		},
+	}
+
+	written := 0
+	for {
+		output, err := db.Scan(input)
+		if err != nil {
+			return written, fmt.Errorf("failed to scan match items: %w", err)
+		}
+
+		for _, item := range output.Items {
+			match, err := repository.unmarshalMatchFromDynamoDB(item)
+			if err != nil {
+				return written, err
+			}
+			for _, userMatch := range match.UserMatches() {
+				_, err := db.PutItem(&dynamodb.PutItemInput{
+					TableName: aws.String(tableName),
+					Item:      marshalUserMatchToDynamoDB(userMatch),
+				})
+				if err != nil {
+					return written, fmt.Errorf("failed to write match history of %s: %w", userMatch.UserID, err)
+				}
+				written++
+			}
+		}
+
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	return written, nil
+}
@@ -8,8 +8,19 @@ import (
 type UserRepository interface {
 	GetUser(id models.UserID) (*models.User, error)
 	GetUserBasicsByPrefix(prefix string) ([]*models.UserBasic, error)
+	GetUserBasics(ids []models.UserID) ([]*models.UserBasic, error)
 	CreateUser(user *models.User, tx *dynamodb.TransactWriteItemsInput) (*models.User, error)
+	// UpdateUser replaces the user if the stored one is still at user.Version
+	// and sets user.Version to the version written.
 	UpdateUser(user *models.User, tx *dynamodb.TransactWriteItemsInput) (*models.User, error)
+	// AddGamePlayed adds the game to the user's GamesPlayed, which it leaves
+	// unchanged if the game is already there. It fails with ErrNotFound if
+	// the user doesn't exist. Concurrent adds and removes all apply, and each
+	// increments the user's version.
+	AddGamePlayed(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error
+	// RemoveGamePlayed removes the game from the user's GamesPlayed like
+	// AddGamePlayed adds it.
+	RemoveGamePlayed(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error
 	DeleteUser(id *models.UserID, tx *dynamodb.TransactWriteItemsInput) error
 }
 
@@ -5,8 +5,10 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/mquan1409/game-api/internal/keys"
 	"github.com/mquan1409/game-api/internal/models"
 )
 
@@ -21,17 +23,17 @@ func NewDynamoDBUserRepository(db *dynamodb.DynamoDB, tableName string) UserRepo
 
 func (r *DynamoDBUserRepository) GetUser(id models.UserID) (*models.User, error) {
 	if id == "" {
-		return nil, errors.New("id cannot be empty")
+		return nil, models.NewValidationError("id cannot be empty")
 	}
 	
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
 			"Id": {
-				S: aws.String("USER_INFO-prefix:" + string(id[:1])),
+				S: aws.String(keys.UserPartition(id)),
 			},
 			"Range": {
-				S: aws.String(string(id)),
+				S: aws.String(keys.UserRange(id)),
 			},
 		},
 	}
@@ -43,7 +45,7 @@ func (r *DynamoDBUserRepository) GetUser(id models.UserID) (*models.User, error)
 	}
 
 	if result.Item == nil {
-		return nil, errors.New("user not found")
+		return nil, models.NewNotFoundError("user %s not found", id)
 	}
 
 	return r.unmarshalUserFromDynamoDB(result.Item)
@@ -51,11 +53,11 @@ func (r *DynamoDBUserRepository) GetUser(id models.UserID) (*models.User, error)
 
 func (r *DynamoDBUserRepository) GetUserBasicsByPrefix(prefix string) ([]*models.UserBasic, error) {
 	if prefix == "" {
-		return nil, errors.New("prefix cannot be empty")
+		return nil, models.NewValidationError("prefix cannot be empty")
 	}
 
-	keyCondition := expression.Key("Id").Equal(expression.Value("USER_INFO-prefix:" + string(prefix[:1])))
-	keyCondition = keyCondition.And(expression.Key("Range").BeginsWith(prefix))
+	keyCondition := expression.Key("Id").Equal(expression.Value(keys.UserPartition(models.UserID(prefix))))
+	keyCondition = keyCondition.And(expression.Key("Range").BeginsWith(keys.UserRange(models.UserID(prefix))))
 
 	proj := expression.NamesList(expression.Name("Id"), expression.Name("Range"), expression.Name("Username"))
 
@@ -89,9 +91,66 @@ func (r *DynamoDBUserRepository) GetUserBasicsByPrefix(prefix string) ([]*models
 	return userBasics, nil
 }
 
+// maxBatchGetKeys is the most keys DynamoDB accepts in one BatchGetItem call.
+const maxBatchGetKeys = 100
+
+// GetUserBasics returns the UserBasic of every id that exists, in no
+// particular order. Unknown ids are skipped rather than reported.
+func (r *DynamoDBUserRepository) GetUserBasics(ids []models.UserID) ([]*models.UserBasic, error) {
+	userBasics := []*models.UserBasic{}
+	seen := make(map[models.UserID]bool)
+	itemKeys := []map[string]*dynamodb.AttributeValue{}
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		itemKeys = append(itemKeys, map[string]*dynamodb.AttributeValue{
+			"Id":    {S: aws.String(keys.UserPartition(id))},
+			"Range": {S: aws.String(keys.UserRange(id))},
+		})
+	}
+
+	for start := 0; start < len(itemKeys); start += maxBatchGetKeys {
+		end := start + maxBatchGetKeys
+		if end > len(itemKeys) {
+			end = len(itemKeys)
+		}
+
+		requestItems := map[string]*dynamodb.KeysAndAttributes{
+			r.tableName: {
+				Keys:                     itemKeys[start:end],
+				ProjectionExpression:     aws.String("#id, #range, Username"),
+				ExpressionAttributeNames: map[string]*string{"#id": aws.String("Id"), "#range": aws.String("Range")},
+			},
+		}
+
+		// DynamoDB may return part of the batch as UnprocessedKeys; keep asking
+		// for those until it has served them all
+		for len(requestItems) > 0 {
+			result, err := r.db.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: requestItems})
+			if err != nil {
+				return nil, fmt.Errorf("failed to batch get users: %w", err)
+			}
+
+			for _, item := range result.Responses[r.tableName] {
+				userBasic, err := r.unmarshalUserBasicFromDynamoDB(item)
+				if err != nil {
+					return nil, fmt.Errorf("failed to unmarshal user basic: %w", err)
+				}
+				userBasics = append(userBasics, userBasic)
+			}
+
+			requestItems = result.UnprocessedKeys
+		}
+	}
+
+	return userBasics, nil
+}
+
 func (r *DynamoDBUserRepository) CreateUser(user *models.User, tx *dynamodb.TransactWriteItemsInput) (*models.User, error) {
 	if user.UserID == "" {
-		return nil, errors.New("user ID cannot be empty")
+		return nil, models.NewValidationError("user ID cannot be empty")
 	}
 
 	item, err := r.marshalUserToDynamoDBAttributeValue(user)
@@ -103,28 +162,23 @@ func (r *DynamoDBUserRepository) CreateUser(user *models.User, tx *dynamodb.Tran
 		TableName: aws.String(r.tableName),
 		Item:      item,
 	}
+	guardVersion(putItem, 0)
 
-	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
-			Put: putItem,
-		})
-		return user, nil
-	}
-
-	_, err = r.db.PutItem(&dynamodb.PutItemInput{
-		Item:      putItem.Item,
-		TableName: putItem.TableName,
-	})
+	err = conditionalPut(r.db, putItem, tx, models.NewConflictError("user %s already exists", user.UserID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	user.Version = 1
 	return user, nil
 }
 
 func (r *DynamoDBUserRepository) UpdateUser(user *models.User, tx *dynamodb.TransactWriteItemsInput) (*models.User, error) {
 	if user.UserID == "" {
-		return nil, errors.New("user ID cannot be empty")
+		return nil, models.NewValidationError("user ID cannot be empty")
+	}
+	if user.Version == 0 {
+		return nil, models.NewValidationError("user %s has no version to update", user.UserID)
 	}
 
 	item, err := r.marshalUserToDynamoDBAttributeValue(user)
@@ -136,28 +190,20 @@ func (r *DynamoDBUserRepository) UpdateUser(user *models.User, tx *dynamodb.Tran
 		TableName: aws.String(r.tableName),
 		Item:      item,
 	}
+	guardVersion(putItem, user.Version)
 
-	if tx != nil {
-		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{
-			Put: putItem,
-		})
-		return user, nil
-	}
-
-	_, err = r.db.PutItem(&dynamodb.PutItemInput{
-		Item:      putItem.Item,
-		TableName: putItem.TableName,
-	})
+	err = conditionalPut(r.db, putItem, tx, models.NewPreconditionFailedError("user %s changed since version %d", user.UserID, user.Version))
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	user.Version++
 	return user, nil
 }
 
 func (r *DynamoDBUserRepository) DeleteUser(id *models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
 	if *id == "" {
-		return errors.New("id cannot be empty")
+		return models.NewValidationError("id cannot be empty")
 	}
 
 	deleteInput := r.createDeleteInput(*id)
@@ -180,15 +226,75 @@ func (r *DynamoDBUserRepository) DeleteUser(id *models.UserID, tx *dynamodb.Tran
 	return nil
 }
 
+// AddGamePlayed adds the game with an ADD update of the GamesPlayed string
+// set, so concurrent updates of the set never overwrite each other.
+func (r *DynamoDBUserRepository) AddGamePlayed(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	return r.updateGamesPlayed(userID, gameID, "ADD GamesPlayed :games, #Version :one", tx)
+}
+
+// RemoveGamePlayed removes the game with a DELETE update of the GamesPlayed
+// string set.
+func (r *DynamoDBUserRepository) RemoveGamePlayed(userID models.UserID, gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	return r.updateGamesPlayed(userID, gameID, "DELETE GamesPlayed :games ADD #Version :one", tx)
+}
+
+// updateGamesPlayed updates the user's GamesPlayed set with gameID as :games.
+// Every update expression also increments the version, so a user read before
+// the update can't be written back over it.
+func (r *DynamoDBUserRepository) updateGamesPlayed(userID models.UserID, gameID models.GameID, updateExpression string, tx *dynamodb.TransactWriteItemsInput) error {
+	if userID == "" {
+		return models.NewValidationError("user ID cannot be empty")
+	}
+
+	update := &dynamodb.Update{
+		TableName: aws.String(r.tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Id":    {S: aws.String(keys.UserPartition(userID))},
+			"Range": {S: aws.String(keys.UserRange(userID))},
+		},
+		UpdateExpression:    aws.String(updateExpression),
+		ConditionExpression: aws.String(itemExistsCondition),
+		ExpressionAttributeNames: map[string]*string{
+			"#Version": aws.String(versionAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":games": {SS: []*string{aws.String(string(gameID))}},
+			":one":   {N: aws.String("1")},
+		},
+	}
+
+	if tx != nil {
+		tx.TransactItems = append(tx.TransactItems, &dynamodb.TransactWriteItem{Update: update})
+		return nil
+	}
+
+	_, err := r.db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ConditionExpression:       update.ConditionExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return models.NewNotFoundError("user %s not found", userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update games played of user %s: %w", userID, err)
+	}
+	return nil
+}
+
 func (r *DynamoDBUserRepository) createDeleteInput(id models.UserID) *dynamodb.Delete {
 	return &dynamodb.Delete{
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
 			"Id": {
-				S: aws.String("USER_INFO-prefix:" + string(id[:1])),
+				S: aws.String(keys.UserPartition(id)),
 			},
 			"Range": {
-				S: aws.String(string(id)),
+				S: aws.String(keys.UserRange(id)),
 			},
 		},
 	}
@@ -196,13 +302,16 @@ func (r *DynamoDBUserRepository) createDeleteInput(id models.UserID) *dynamodb.D
 
 func (r *DynamoDBUserRepository) marshalUserToDynamoDBAttributeValue(user *models.User) (map[string]*dynamodb.AttributeValue, error) {
 	av := make(map[string]*dynamodb.AttributeValue)
-	av["Id"] = &dynamodb.AttributeValue{S: aws.String(fmt.Sprintf("USER_INFO-prefix:%s", string(user.UserID[:1])))}
-	av["Range"] = &dynamodb.AttributeValue{S: aws.String(string(user.UserID))}
+	av["Id"] = &dynamodb.AttributeValue{S: aws.String(keys.UserPartition(user.UserID))}
+	av["Range"] = &dynamodb.AttributeValue{S: aws.String(keys.UserRange(user.UserID))}
 	av["Username"] = &dynamodb.AttributeValue{S: aws.String(user.Username)}
-	av["GamesPlayed"] = &dynamodb.AttributeValue{L: make([]*dynamodb.AttributeValue, len(user.GamesPlayed))}
-
-	for i, gameID := range user.GamesPlayed {
-		av["GamesPlayed"].L[i] = &dynamodb.AttributeValue{S: aws.String(string(gameID))}
+	// GamesPlayed is a string set so AddGamePlayed and RemoveGamePlayed can
+	// update it in place. Sets cannot be empty, so an empty one is omitted
+	if len(user.GamesPlayed) > 0 {
+		av["GamesPlayed"] = &dynamodb.AttributeValue{SS: make([]*string, len(user.GamesPlayed))}
+		for i, gameID := range user.GamesPlayed {
+			av["GamesPlayed"].SS[i] = aws.String(string(gameID))
+		}
 	}
 	av["Email"] = &dynamodb.AttributeValue{S: aws.String(user.Email)}
 
@@ -216,7 +325,10 @@ func (r *DynamoDBUserRepository) unmarshalUserFromDynamoDB(item map[string]*dyna
 
 	// Unmarshal UserID
 	if idAttr, ok := item["Range"]; ok && idAttr.S != nil {
-		userID = models.UserID(*idAttr.S)
+		var err error
+		if userID, err = keys.ParseUserRange(*idAttr.S); err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, errors.New("error UserID is missing or invalid")
 	}
@@ -235,8 +347,11 @@ func (r *DynamoDBUserRepository) unmarshalUserFromDynamoDB(item map[string]*dyna
 		return nil, errors.New("error Email is missing or invalid")
 	}
 
-	// Unmarshal GamesPlayed
-	if gamesPlayedAttr, ok := item["GamesPlayed"]; ok && gamesPlayedAttr.L != nil {
+	// Unmarshal GamesPlayed, a list in items written before it was a set
+	if gamesPlayedAttr, ok := item["GamesPlayed"]; ok {
+		for _, gameID := range gamesPlayedAttr.SS {
+			gamesPlayed = append(gamesPlayed, models.GameID(*gameID))
+		}
 		for _, gameIDAttr := range gamesPlayedAttr.L {
 			if gameIDAttr.S != nil {
 				gamesPlayed = append(gamesPlayed, models.GameID(*gameIDAttr.S))
@@ -244,7 +359,14 @@ func (r *DynamoDBUserRepository) unmarshalUserFromDynamoDB(item map[string]*dyna
 		}
 	}
 
-	return models.NewUser(userID, username, email, gamesPlayed)
+	user, err := models.NewUser(userID, username, email, gamesPlayed)
+	if err != nil {
+		return nil, err
+	}
+	if user.Version, err = unmarshalVersion(item); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
 func (r *DynamoDBUserRepository) unmarshalUserBasicFromDynamoDB(item map[string]*dynamodb.AttributeValue) (*models.UserBasic, error) {
@@ -253,7 +375,10 @@ func (r *DynamoDBUserRepository) unmarshalUserBasicFromDynamoDB(item map[string]
 
 	// Unmarshal UserID
 	if idAttr, ok := item["Range"]; ok && idAttr.S != nil {
-		userID = models.UserID(*idAttr.S)
+		var err error
+		if userID, err = keys.ParseUserRange(*idAttr.S); err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, errors.New("error UserID is missing or invalid")
 	}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// GameDeletionService carries out the cascade deletes that
+// GameService.DeleteGame starts.
+type GameDeletionService interface {
+	// DeletePendingGames works on every game being deleted until deadline and
+	// returns the games it finished deleting. A game it didn't finish keeps
+	// its progress, and the next call resumes from there.
+	DeletePendingGames(deadline time.Time) ([]models.GameID, error)
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+// gameDeletionPageSize is the number of games listed, and of matches deleted,
+// between two saves of a deletion's progress.
+const gameDeletionPageSize = 25
+
+// allDates holds every match date.
+var allDates = models.DateRange{From: "0001-01-01", To: "9999-12-31"}
+
+type GameDeletionServiceImpl struct {
+	gameRepository        repositories.GameRepository
+	matchRepository       repositories.MatchRepository
+	userRepository        repositories.UserRepository
+	gameStatRepository    repositories.GameStatRepository
+	leaderboardRepository repositories.LeaderboardRepository
+	seasonRepository      repositories.SeasonRepository
+	ratingUpdater         *ratingUpdater
+}
+
+func NewGameDeletionServiceImpl(
+	gameRepository repositories.GameRepository,
+	matchRepository repositories.MatchRepository,
+	userRepository repositories.UserRepository,
+	gameStatRepository repositories.GameStatRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	seasonRepository repositories.SeasonRepository,
+	ratingRepository repositories.RatingRepository,
+	transactionRepository repositories.TransactionRepository,
+) GameDeletionService {
+	return &GameDeletionServiceImpl{
+		gameRepository:        gameRepository,
+		matchRepository:       matchRepository,
+		userRepository:        userRepository,
+		gameStatRepository:    gameStatRepository,
+		leaderboardRepository: leaderboardRepository,
+		seasonRepository:      seasonRepository,
+		ratingUpdater:         newRatingUpdater(matchRepository, ratingRepository, leaderboardRepository, transactionRepository),
+	}
+}
+
+// DeletePendingGames moves each game being deleted forward by at least one
+// page of work, and keeps going while deadline hasn't passed. A game whose
+// progress can't be saved because it changed is being deleted by another
+// worker, and is left to it.
+func (s *GameDeletionServiceImpl) DeletePendingGames(deadline time.Time) ([]models.GameID, error) {
+	var deleted []models.GameID
+	cursor := ""
+	for {
+		page, err := s.gameRepository.GetGames(models.GameFilter{Status: models.GameStatusDeleting}, gameDeletionPageSize, cursor)
+		if err != nil {
+			return deleted, err
+		}
+		for _, game := range page.Games {
+			done, err := s.deleteGame(game, deadline)
+			if errors.Is(err, models.ErrPreconditionFailed) {
+				continue
+			}
+			if err != nil {
+				return deleted, fmt.Errorf("failed to delete game %s: %w", game.GameID, err)
+			}
+			if !done {
+				return deleted, nil
+			}
+			deleted = append(deleted, game.GameID)
+		}
+		if page.NextCursor == "" {
+			return deleted, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// deleteGame runs the steps of the game's deletion until the game is gone or
+// deadline has passed, and reports whether the game is gone.
+func (s *GameDeletionServiceImpl) deleteGame(game *models.Game, deadline time.Time) (bool, error) {
+	if game.Deletion == nil {
+		game.Deletion = &models.GameDeletion{Step: models.GameDeletionMatches}
+	}
+	for {
+		switch game.Deletion.Step {
+		case models.GameDeletionMatches:
+			if err := s.deleteMatchPage(game); err != nil {
+				return false, err
+			}
+		case models.GameDeletionLeaderboards:
+			return true, s.deleteLeaderboards(game)
+		default:
+			return false, fmt.Errorf("unknown deletion step %q", game.Deletion.Step)
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+	}
+}
+
+// deleteMatchPage deletes the game's first page of matches with the GameStats
+// and GamesPlayed entries of their players, and the leaderboards of the
+// periods they were played in, then saves the progress. The progress is saved
+// with the game's version, so two workers never delete the same game.
+func (s *GameDeletionServiceImpl) deleteMatchPage(game *models.Game) error {
+	page, err := s.matchRepository.GetMatchesByGameAndDateRange(game.GameID, allDates, gameDeletionPageSize, "")
+	if err != nil {
+		return err
+	}
+	if len(page.Matches) == 0 {
+		game.Deletion.Step = models.GameDeletionLeaderboards
+		_, err := s.gameRepository.UpdateGame(game, nil)
+		return err
+	}
+
+	seasons, err := s.seasonRepository.GetSeasonsByGame(game.GameID)
+	if err != nil {
+		return err
+	}
+	var periodScopes []models.LeaderboardScope
+	for _, match := range page.Matches {
+		scopes, err := models.PeriodScopes(match.DateID)
+		if err != nil {
+			return err
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(periodScopes, scope) {
+				periodScopes = append(periodScopes, scope)
+			}
+		}
+		// A GameStat exists in the scope of any season the match counted
+		// towards, closed since or not
+		scopes = append(scopes, models.AllTimeScope)
+		for _, season := range seasons {
+			if season.Contains(match.DateID) {
+				scopes = append(scopes, season.Scope())
+			}
+		}
+		for _, userID := range match.Players() {
+			// Anonymous players have no GameStats and no user
+			if models.IsAnonymousPlayer(userID) {
+				continue
+			}
+			if err := s.deleteUserData(userID, game.GameID, scopes); err != nil {
+				return err
+			}
+		}
+	}
+	for _, scope := range periodScopes {
+		if err := s.leaderboardRepository.WithScope(scope).DeleteLeaderboardItemsByGame(game.GameID, nil); err != nil {
+			return err
+		}
+	}
+	for _, match := range page.Matches {
+		if err := s.matchRepository.DeleteMatch(game.GameID, match.MatchID, match.DateID, nil); err != nil {
+			return err
+		}
+	}
+
+	game.Deletion.MatchesDeleted += len(page.Matches)
+	_, err = s.gameRepository.UpdateGame(game, nil)
+	return err
+}
+
+// deleteLeaderboards deletes what the game's matches left behind: the
+// GameStats of the players still on its all-time and season leaderboards, the
+// leaderboards themselves, its seasons and its ratings, and then the game.
+func (s *GameDeletionServiceImpl) deleteLeaderboards(game *models.Game) error {
+	seasons, err := s.seasonRepository.GetSeasonsByGame(game.GameID)
+	if err != nil {
+		return err
+	}
+	// The all-time scope goes last, as the ratings are deleted from its
+	// leaderboard
+	scopes := make([]models.LeaderboardScope, 0, len(seasons)+1)
+	for _, season := range seasons {
+		scopes = append(scopes, season.Scope())
+	}
+	scopes = append(scopes, models.AllTimeScope)
+
+	for _, scope := range scopes {
+		leaderboardRepository := s.leaderboardRepository.WithScope(scope)
+		userIDs := make(map[models.UserID]bool)
+		for _, attr := range game.RankedAttributes {
+			leaderboard, err := leaderboardRepository.GetLeaderboard(game.GameID, attr, game.RankDirection(attr))
+			if err != nil {
+				return err
+			}
+			for _, userID := range leaderboard.UserIDs() {
+				userIDs[userID] = true
+			}
+		}
+		for userID := range userIDs {
+			if err := s.deleteUserData(userID, game.GameID, []models.LeaderboardScope{scope}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, season := range seasons {
+		if err := s.leaderboardRepository.WithScope(season.Scope()).DeleteLeaderboardItemsByGame(game.GameID, nil); err != nil {
+			return err
+		}
+		if err := s.seasonRepository.DeleteSeason(game.GameID, season.SeasonID, nil); err != nil {
+			return err
+		}
+	}
+	// With no matches left, recomputing deletes every rating
+	if err := s.ratingUpdater.recompute(&models.Game{GameID: game.GameID}); err != nil {
+		return err
+	}
+	if err := s.leaderboardRepository.DeleteLeaderboardItemsByGame(game.GameID, nil); err != nil {
+		return err
+	}
+	return s.gameRepository.DeleteGame(game.GameID, nil)
+}
+
+// deleteUserData deletes the user's GameStats of the game in scopes and
+// removes the game from their GamesPlayed.
+func (s *GameDeletionServiceImpl) deleteUserData(userID models.UserID, gameID models.GameID, scopes []models.LeaderboardScope) error {
+	for _, scope := range scopes {
+		if err := s.gameStatRepository.WithScope(scope).DeleteGameStat(userID, gameID, nil); err != nil {
+			return err
+		}
+	}
+	err := s.userRepository.RemoveGamePlayed(userID, gameID, nil)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	return err
+}
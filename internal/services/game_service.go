@@ -6,9 +6,12 @@ import (
 
 type GameService interface {
 	GetGame(id models.GameID) (*models.Game, error)
-	GetGameLeaderboard(gameID models.GameID, attribute models.AttributeName) (*models.LeaderBoard, error)
-	GetBoundedGameLeaderboard(gameID models.GameID, attribute models.AttributeName, limit int) (*models.BoundedLeaderboard, error)
+	GetGames(filter models.GameFilter, limit int, cursor string) (*models.GamePage, error)
+	GetBoundedGameLeaderboard(gameID models.GameID, attribute models.AttributeName, scope models.LeaderboardScope, limit int, cursor string) (*models.BoundedLeaderboard, error)
+	GetLeaderboardAroundUser(gameID models.GameID, attribute models.AttributeName, scope models.LeaderboardScope, userID models.UserID, window int) (*models.LeaderboardAroundUser, error)
 	CreateGame(game *models.Game) (*models.Game, error)
 	UpdateGame(game *models.Game) (*models.Game, error)
-	DeleteGame(id models.GameID) error
+	ArchiveGame(id models.GameID) (*models.Game, error)
+	UnarchiveGame(id models.GameID) (*models.Game, error)
+	DeleteGame(id models.GameID) (*models.Game, error)
 }
\ No newline at end of file
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
 	"github.com/mquan1409/game-api/internal/utils"
@@ -9,12 +13,30 @@ import (
 type GameServiceImpl struct {
 	gameRepository        repositories.GameRepository
 	leaderboardRepository repositories.LeaderboardRepository
+	userRepository        repositories.UserRepository
+	gameStatRepository    repositories.GameStatRepository
+	ratingRepository      repositories.RatingRepository
+	transactionRepository repositories.TransactionRepository
+	ratingUpdater         *ratingUpdater
 }
 
-func NewGameServiceImpl(gameRepository repositories.GameRepository, leaderboardRepository repositories.LeaderboardRepository) GameService {
+func NewGameServiceImpl(
+	gameRepository repositories.GameRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	userRepository repositories.UserRepository,
+	gameStatRepository repositories.GameStatRepository,
+	matchRepository repositories.MatchRepository,
+	ratingRepository repositories.RatingRepository,
+	transactionRepository repositories.TransactionRepository,
+) GameService {
 	return &GameServiceImpl{
 		gameRepository:        gameRepository,
 		leaderboardRepository: leaderboardRepository,
+		userRepository:        userRepository,
+		gameStatRepository:    gameStatRepository,
+		ratingRepository:      ratingRepository,
+		transactionRepository: transactionRepository,
+		ratingUpdater:         newRatingUpdater(matchRepository, ratingRepository, leaderboardRepository, transactionRepository),
 	}
 }
 
@@ -22,46 +44,251 @@ func (s *GameServiceImpl) GetGame(id models.GameID) (*models.Game, error) {
 	return s.gameRepository.GetGame(id)
 }
 
-func (s *GameServiceImpl) GetGameLeaderboard(gameID models.GameID, attribute models.AttributeName) (*models.LeaderBoard, error) {
-	leaderboard, err := s.leaderboardRepository.GetLeaderboard(gameID, attribute)
+func (s *GameServiceImpl) GetGames(filter models.GameFilter, limit int, cursor string) (*models.GamePage, error) {
+	page, err := s.gameRepository.GetGames(filter, limit, cursor)
 	if err != nil {
 		return nil, err
 	}
-	return &leaderboard, nil
+	return &page, nil
 }
 
-func (s *GameServiceImpl) GetBoundedGameLeaderboard(gameID models.GameID, attribute models.AttributeName, limit int) (*models.BoundedLeaderboard, error) {
-	boundedLeaderboard, err := s.leaderboardRepository.GetBoundedLeaderboard(gameID, attribute, limit)
+func (s *GameServiceImpl) GetBoundedGameLeaderboard(gameID models.GameID, attribute models.AttributeName, scope models.LeaderboardScope, limit int, cursor string) (*models.BoundedLeaderboard, error) {
+	game, err := s.gameRepository.GetGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+	// Attributes no longer ranked keep their entries in scopes other than
+	// all-time
+	if !game.HasRatingLeaderboard(attribute) && !slices.Contains(game.RankedAttributes, attribute) {
+		return nil, models.NewNotFoundError("attribute %s is not ranked for game %s", attribute, gameID)
+	}
+	boundedLeaderboard, err := s.leaderboardRepository.WithScope(scope).GetBoundedLeaderboard(gameID, attribute, game.RankDirection(attribute), limit, cursor)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.fillUsernames(boundedLeaderboard.Entries); err != nil {
+		return nil, err
+	}
 	return &boundedLeaderboard, nil
 }
 
+// GetLeaderboardAroundUser locates the user on the leaderboard by their
+// GameStat value, or their rating on the rating leaderboard, and returns them
+// with up to window neighbours on each side.
+func (s *GameServiceImpl) GetLeaderboardAroundUser(gameID models.GameID, attribute models.AttributeName, scope models.LeaderboardScope, userID models.UserID, window int) (*models.LeaderboardAroundUser, error) {
+	game, err := s.gameRepository.GetGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	var value models.AttributeStat
+	if game.HasRatingLeaderboard(attribute) {
+		if scope != models.AllTimeScope {
+			return nil, models.NewNotFoundError("the %s leaderboard of game %s is only kept for all time", attribute, gameID)
+		}
+		rating, err := s.ratingRepository.GetRating(gameID, userID)
+		if err != nil {
+			return nil, err
+		}
+		value = rating.Value
+	} else {
+		if !slices.Contains(game.RankedAttributes, attribute) {
+			return nil, models.NewNotFoundError("attribute %s is not ranked for game %s", attribute, gameID)
+		}
+		gameStat, err := s.gameStatRepository.WithScope(scope).GetGameStat(userID, gameID)
+		if err != nil {
+			return nil, err
+		}
+		value = gameStat.GameAttributes[attribute]
+	}
+
+	leaderboard, err := s.leaderboardRepository.WithScope(scope).GetLeaderboardAroundUser(gameID, attribute, game.RankDirection(attribute), userID, value, window)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillUsernames(leaderboard.Entries); err != nil {
+		return nil, err
+	}
+	return &leaderboard, nil
+}
+
+// fillUsernames looks up the entries' users in one batch and sets their
+// Username. Entries whose user no longer exists keep an empty Username.
+func (s *GameServiceImpl) fillUsernames(entries []models.LeaderboardEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	userIDs := make([]models.UserID, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+	}
+
+	userBasics, err := s.userRepository.GetUserBasics(userIDs)
+	if err != nil {
+		return err
+	}
+
+	usernames := make(map[models.UserID]string)
+	for _, userBasic := range userBasics {
+		usernames[userBasic.UserID] = userBasic.Username
+	}
+	for i := range entries {
+		entries[i].Username = usernames[entries[i].UserID]
+	}
+	return nil
+}
+
 func (s *GameServiceImpl) CreateGame(game *models.Game) (*models.Game, error) {
+	if err := game.ValidateRatingSystem(); err != nil {
+		return nil, err
+	}
+	if err := game.ValidateAggregations(); err != nil {
+		return nil, err
+	}
+	if err := game.ValidateRankedAttributes(); err != nil {
+		return nil, err
+	}
+	if err := game.ValidateRankDirections(); err != nil {
+		return nil, err
+	}
+	game.Status = models.GameStatusActive
+	game.Deletion = nil
+	game.RemovedAggregations = nil
 	return s.gameRepository.CreateGame(game, nil)
 }
 
+// UpdateGame replaces the game, which must still be at game.Version unless it
+// is 0 and must be active, and once it is written removes the all-time
+// leaderboards of attributes that are no longer ranked. Their leaderboards in
+// other scopes can no longer be read. Changing the rating system requests a recompute of
+// every rating of the game with the new one. The aggregation mode of an
+// attribute the game has, or had before it was removed, cannot change, as its
+// GameStats were aggregated with the old one.
 func (s *GameServiceImpl) UpdateGame(game *models.Game) (*models.Game, error) {
+	if err := game.ValidateRatingSystem(); err != nil {
+		return nil, err
+	}
+	if err := game.ValidateAggregations(); err != nil {
+		return nil, err
+	}
+	if err := game.ValidateRankedAttributes(); err != nil {
+		return nil, err
+	}
+	if err := game.ValidateRankDirections(); err != nil {
+		return nil, err
+	}
 	oldGame, err := s.gameRepository.GetGame(game.GameID)
 	if err != nil {
 		return nil, err
 	}
-	deletedAttributes := utils.Minus(oldGame.RankedAttributes, game.RankedAttributes)
-	for _, attribute := range deletedAttributes {
+	if err := matchVersion(fmt.Sprintf("game %s", game.GameID), game.Version, oldGame.Version); err != nil {
+		return nil, err
+	}
+	if err := oldGame.CheckWritable(); err != nil {
+		return nil, err
+	}
+	removedAggregations := make(map[models.AttributeName]models.AggregationMode)
+	for attr, mode := range oldGame.RemovedAggregations {
+		removedAggregations[attr] = mode
+	}
+	for _, attr := range oldGame.Attributes {
+		if !slices.Contains(game.Attributes, attr) {
+			removedAggregations[attr] = oldGame.Aggregation(attr)
+		}
+	}
+	for _, attr := range game.Attributes {
+		oldMode, ok := removedAggregations[attr]
+		if slices.Contains(oldGame.Attributes, attr) {
+			oldMode, ok = oldGame.Aggregation(attr), true
+		}
+		if ok && game.Aggregation(attr) != oldMode {
+			return nil, models.NewValidationError("the aggregation mode of attribute %s of game %s cannot change from %s to %s", attr, game.GameID, oldMode, game.Aggregation(attr))
+		}
+		delete(removedAggregations, attr)
+	}
+	game.RemovedAggregations = nil
+	if len(removedAggregations) > 0 {
+		game.RemovedAggregations = removedAggregations
+	}
+	game.Version = oldGame.Version
+	game.Status = oldGame.Status
+	game.Deletion = oldGame.Deletion
+	// Entries left behind when an attribute was last unranked can't be read
+	// until it is ranked again, so they are cleared before it is
+	for _, attribute := range utils.Minus(game.RankedAttributes, oldGame.RankedAttributes) {
 		err := s.leaderboardRepository.DeleteLeaderboardItemsByGameAndAttribute(game.GameID, attribute, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	tx := &dynamodb.TransactWriteItemsInput{}
+	updatedGame, err := s.gameRepository.UpdateGame(game, tx)
+	if err != nil {
+		return nil, err
+	}
+	if game.RatingSystem != oldGame.RatingSystem {
+		if err := s.ratingUpdater.requestRecompute(game.GameID, tx); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.transactionRepository.ExecuteTransaction(tx); err != nil {
+		return nil, err
+	}
+
+	// The game is updated by now, so a failed cleanup is not reported: the
+	// entries left can't be read and are cleared if the attribute is ranked
+	// again
+	for _, attribute := range utils.Minus(oldGame.RankedAttributes, game.RankedAttributes) {
+		_ = s.leaderboardRepository.DeleteLeaderboardItemsByGameAndAttribute(game.GameID, attribute, nil)
+	}
+	return updatedGame, nil
+}
+
+// ArchiveGame makes the game read-only: its matches, seasons and definition
+// can no longer change, while everything stays readable.
+func (s *GameServiceImpl) ArchiveGame(id models.GameID) (*models.Game, error) {
+	return s.setStatus(id, models.GameStatusArchived)
+}
+
+// UnarchiveGame makes an archived game writable again.
+func (s *GameServiceImpl) UnarchiveGame(id models.GameID) (*models.Game, error) {
+	return s.setStatus(id, models.GameStatusActive)
+}
+
+// setStatus moves the game between active and archived. A game being deleted
+// stays so.
+func (s *GameServiceImpl) setStatus(id models.GameID, status models.GameStatus) (*models.Game, error) {
+	game, err := s.gameRepository.GetGame(id)
+	if err != nil {
+		return nil, err
+	}
+	if game.Status == models.GameStatusDeleting {
+		return nil, game.CheckWritable()
+	}
+	if game.Status == status {
+		return game, nil
+	}
+
+	game.Status = status
 	return s.gameRepository.UpdateGame(game, nil)
 }
 
-func (s *GameServiceImpl) DeleteGame(id models.GameID) error {
-	err := s.leaderboardRepository.DeleteLeaderboardItemsByGame(id, nil)
+// DeleteGame starts the cascade delete of the game, which a
+// GameDeletionService carries out in the background, and returns the game
+// with its deletion progress. The game is read-only from then on. Deleting a
+// game that is already being deleted changes nothing.
+func (s *GameServiceImpl) DeleteGame(id models.GameID) (*models.Game, error) {
+	game, err := s.gameRepository.GetGame(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return s.gameRepository.DeleteGame(id, nil)
+	if game.Status == models.GameStatusDeleting {
+		return game, nil
+	}
+
+	game.Status = models.GameStatusDeleting
+	game.Deletion = &models.GameDeletion{Step: models.GameDeletionMatches}
+	return s.gameRepository.UpdateGame(game, nil)
 }
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// MatchFollowUpService finishes the follow-ups of match writes that could
+// not be carried out right after the write.
+type MatchFollowUpService interface {
+	// RunPendingFollowUps carries out every pending follow-up until deadline
+	// and returns those it finished. A follow-up requested again meanwhile is
+	// left pending for the next call.
+	RunPendingFollowUps(deadline time.Time) ([]*models.MatchFollowUp, error)
+}
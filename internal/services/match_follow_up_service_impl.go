@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type MatchFollowUpServiceImpl struct {
+	matchRepository repositories.MatchRepository
+	matchService    *MatchServiceImpl
+}
+
+func NewMatchFollowUpServiceImpl(
+	matchRepository repositories.MatchRepository,
+	gameRepository repositories.GameRepository,
+	userRepository repositories.UserRepository,
+	gameStatRepository repositories.GameStatRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	seasonRepository repositories.SeasonRepository,
+	ratingRepository repositories.RatingRepository,
+	transactionRepository repositories.TransactionRepository,
+) MatchFollowUpService {
+	return &MatchFollowUpServiceImpl{
+		matchRepository: matchRepository,
+		matchService:    newMatchServiceImpl(matchRepository, gameRepository, userRepository, gameStatRepository, leaderboardRepository, seasonRepository, ratingRepository, transactionRepository),
+	}
+}
+
+// RunPendingFollowUps carries out each pending follow-up in turn like the
+// match writes do, see MatchServiceImpl.runFollowUp, stopping at the first
+// one that fails.
+func (s *MatchFollowUpServiceImpl) RunPendingFollowUps(deadline time.Time) ([]*models.MatchFollowUp, error) {
+	followUps, err := s.matchRepository.GetMatchFollowUps()
+	if err != nil {
+		return nil, err
+	}
+
+	var finished []*models.MatchFollowUp
+	for _, followUp := range followUps {
+		if time.Now().After(deadline) {
+			return finished, nil
+		}
+		if err := s.matchService.runFollowUp(followUp); err != nil {
+			return finished, fmt.Errorf("failed to follow up the matches of game %s on %s: %w", followUp.GameID, followUp.DateID, err)
+		}
+		finished = append(finished, followUp)
+	}
+	return finished, nil
+}
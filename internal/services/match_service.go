@@ -7,6 +7,7 @@ import (
 type MatchService interface {
 	GetMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) (*models.Match, error)
 	GetMatchesByGameAndDate(gameID models.GameID, dateID models.DateID) ([]*models.Match, error)
+	GetMatchesByGameAndDateRange(gameID models.GameID, dates models.DateRange, limit int, cursor string) (*models.MatchPage, error)
 	CreateMatch(match *models.Match) (*models.Match, error)
 	UpdateMatch(match *models.Match) (*models.Match, error)
 	DeleteMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) error
@@ -1,28 +1,59 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
 )
 
 type MatchServiceImpl struct {
-	matchRepository      repositories.MatchRepository
-	gameRepository       repositories.GameRepository
-	gameStatRepository   repositories.GameStatRepository
+	matchRepository       repositories.MatchRepository
+	gameRepository        repositories.GameRepository
+	userRepository        repositories.UserRepository
+	gameStatRepository    repositories.GameStatRepository
 	leaderboardRepository repositories.LeaderboardRepository
+	seasonRepository      repositories.SeasonRepository
+	transactionRepository repositories.TransactionRepository
+	ratingUpdater         *ratingUpdater
 }
 
 func NewMatchServiceImpl(
 	matchRepository repositories.MatchRepository,
 	gameRepository repositories.GameRepository,
+	userRepository repositories.UserRepository,
 	gameStatRepository repositories.GameStatRepository,
 	leaderboardRepository repositories.LeaderboardRepository,
+	seasonRepository repositories.SeasonRepository,
+	ratingRepository repositories.RatingRepository,
+	transactionRepository repositories.TransactionRepository,
 ) MatchService {
+	return newMatchServiceImpl(matchRepository, gameRepository, userRepository, gameStatRepository, leaderboardRepository, seasonRepository, ratingRepository, transactionRepository)
+}
+
+func newMatchServiceImpl(
+	matchRepository repositories.MatchRepository,
+	gameRepository repositories.GameRepository,
+	userRepository repositories.UserRepository,
+	gameStatRepository repositories.GameStatRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	seasonRepository repositories.SeasonRepository,
+	ratingRepository repositories.RatingRepository,
+	transactionRepository repositories.TransactionRepository,
+) *MatchServiceImpl {
 	return &MatchServiceImpl{
-		matchRepository:      matchRepository,
-		gameRepository:       gameRepository,
-		gameStatRepository:   gameStatRepository,
+		matchRepository:       matchRepository,
+		gameRepository:        gameRepository,
+		userRepository:        userRepository,
+		gameStatRepository:    gameStatRepository,
 		leaderboardRepository: leaderboardRepository,
+		seasonRepository:      seasonRepository,
+		transactionRepository: transactionRepository,
+		ratingUpdater:         newRatingUpdater(matchRepository, ratingRepository, leaderboardRepository, transactionRepository),
 	}
 }
 
@@ -34,115 +65,264 @@ func (s *MatchServiceImpl) GetMatchesByGameAndDate(gameID models.GameID, dateID
 	return s.matchRepository.GetMatchesByGameAndDate(gameID, dateID)
 }
 
+func (s *MatchServiceImpl) GetMatchesByGameAndDateRange(gameID models.GameID, dates models.DateRange, limit int, cursor string) (*models.MatchPage, error) {
+	page, err := s.matchRepository.GetMatchesByGameAndDateRange(gameID, dates, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// validateMatch checks the match against its game and that all of its players
+// exist, reporting every problem found in one validation error. The anonymous
+// players of oldMatch, the stored match an update replaces, stand for deleted
+// users and may stay.
+func (s *MatchServiceImpl) validateMatch(game *models.Game, match *models.Match, oldMatch *models.Match) error {
+	problems := match.Problems(game)
+
+	players := match.Players()
+	userBasics, err := s.userRepository.GetUserBasics(players)
+	if err != nil {
+		return err
+	}
+	exists := make(map[models.UserID]bool)
+	for _, userBasic := range userBasics {
+		exists[userBasic.UserID] = true
+	}
+	if oldMatch != nil {
+		for _, userID := range oldMatch.Players() {
+			if models.IsAnonymousPlayer(userID) {
+				exists[userID] = true
+			}
+		}
+	}
+	for _, userID := range players {
+		if userID != "" && !exists[userID] {
+			problems = append(problems, fmt.Sprintf("user %s does not exist", userID))
+		}
+	}
+
+	if len(problems) > 0 {
+		return models.NewValidationProblemsError(problems, "invalid match %s of game %s", match.MatchID, match.GameID)
+	}
+	return nil
+}
+
+// maxMatchWriteAttempts bounds how often a match write is retried after a
+// concurrent write changed a GameStat its leaderboard moves were based on.
+const maxMatchWriteAttempts = 5
+
+// retryOnPreconditionFailed runs write again while it fails with
+// ErrPreconditionFailed, at most maxMatchWriteAttempts times in all. write
+// must end with the transaction that can fail that way: work done once it
+// has committed would be done twice, or fail as already done.
+func retryOnPreconditionFailed(write func() error) error {
+	var err error
+	for attempt := 0; attempt < maxMatchWriteAttempts; attempt++ {
+		if err = write(); !errors.Is(err, models.ErrPreconditionFailed) {
+			return err
+		}
+	}
+	return err
+}
+
+// CreateMatch stores the match, applies its player attributes to all-time
+// GameStats and Leaderboards and adds the game to its players' GamesPlayed in
+// a single transaction, so either all of them are written or none are. The
+// players' ratings are updated in the same transaction, unless the match
+// predates one they have already played, in which case the transaction
+// requests a recompute of the game's ratings instead. The transaction is
+// rebuilt and retried if a concurrent write to a GameStat it reads wins the
+// race. It also requests a follow-up updating the period and season scopes
+// of the match's players, which is carried out once it has committed; see
+// runFollowUp.
 func (s *MatchServiceImpl) CreateMatch(match *models.Match) (*models.Match, error) {
+	var createdMatch *models.Match
+	err := retryOnPreconditionFailed(func() (err error) {
+		createdMatch, err = s.createMatch(match)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.followUp(match.GameID, match.DateID)
+	return createdMatch, nil
+}
+
+func (s *MatchServiceImpl) createMatch(match *models.Match) (*models.Match, error) {
 	game, err := s.gameRepository.GetGame(match.GameID)
 	if err != nil {
 		return nil, err
 	}
+	if err := game.CheckWritable(); err != nil {
+		return nil, err
+	}
+	if err := s.validateMatch(game, match, nil); err != nil {
+		return nil, err
+	}
+	// The match is also written conditionally; checking first avoids
+	// computing its deltas and gives a clearer error
+	if _, err := s.matchRepository.GetMatch(match.GameID, match.MatchID, match.DateID); err == nil {
+		return nil, models.NewConflictError("match %s of game %s on %s already exists", match.MatchID, match.GameID, match.DateID)
+	} else if !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+
+	tx := &dynamodb.TransactWriteItemsInput{}
 
-	createdMatch, err := s.matchRepository.CreateMatch(match, nil)
+	createdMatch, err := s.matchRepository.CreateMatch(match, tx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update GameStats and Leaderboards for each player
 	for userID, attributes := range match.PlayerAttributesMap {
-		gameStat, err := s.gameStatRepository.GetGameStat(userID, match.GameID)
-		if err != nil {
-			// If GameStat doesn't exist, create a new one with all attributes initialized to 0
-			initialAttributes := models.AttributesStatsMap{}
-			for _, attr := range game.Attributes {
-				initialAttributes[attr] = 0
-			}
-			gameStat, err = models.NewGameStat(userID, match.GameID, initialAttributes)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		// Update Leaderboards only for ranked attributes
-		for _, attr := range game.RankedAttributes {
-			if value, exists := attributes[attr]; exists {
-				oldSum := gameStat.GameAttributes[attr]
-				newSum := oldSum + value
-				if err := s.leaderboardRepository.UpdateLeaderboardItem(match.GameID, userID, attr, newSum, oldSum, nil); err != nil {
-					return nil, err
-				}
-			}
-		}
-
-		// Update GameStat
-		for attrName, value := range attributes {
-			if currentValue, exists := gameStat.GameAttributes[attrName]; exists {
-				gameStat.GameAttributes[attrName] = currentValue + value
-			} else {
-				gameStat.GameAttributes[attrName] = value
-			}
+		if err := s.applyPlayerAttributes(game, match, userID, nil, attributes, tx); err != nil {
+			return nil, err
 		}
+	}
 
-		if err := s.gameStatRepository.UpdateGameStat(gameStat, nil); err != nil {
+	for _, userID := range match.Players() {
+		if err := s.userRepository.AddGamePlayed(userID, match.GameID, tx); err != nil {
 			return nil, err
 		}
+	}
 
+	if err := s.ratingUpdater.applyMatch(game, match, tx); err != nil {
+		return nil, err
 	}
 
+	if err := s.requestFollowUp(match.GameID, match.DateID, attributePlayers(match), nil, tx); err != nil {
+		return nil, err
+	}
+
+	if err := s.transactionRepository.ExecuteTransaction(tx); err != nil {
+		return nil, err
+	}
 	return createdMatch, nil
 }
 
+// UpdateMatch replaces the match, which must still be at match.Version unless
+// it is 0, and adjusts all-time GameStats and Leaderboards from the old to the
+// new player attributes in a single transaction. Players joining the match get
+// the game added to their GamesPlayed in the transaction, and if its teams or
+// scores changed, the transaction requests a recompute of the game's ratings.
+// Like CreateMatch, it retries when it loses a race on a GameStat and leaves
+// the period and season scopes to a follow-up, which also removes the game
+// from the GamesPlayed of players leaving the match who have no other match
+// of it.
 func (s *MatchServiceImpl) UpdateMatch(match *models.Match) (*models.Match, error) {
-	oldMatch, err := s.matchRepository.GetMatch(match.GameID, match.MatchID, match.DateID)
+	version := match.Version
+	var updatedMatch *models.Match
+	err := retryOnPreconditionFailed(func() (err error) {
+		match.Version = version
+		updatedMatch, err = s.updateMatch(match)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.followUp(match.GameID, match.DateID)
+	return updatedMatch, nil
+}
 
-	updatedMatch, err := s.matchRepository.UpdateMatch(match, nil)
+func (s *MatchServiceImpl) updateMatch(match *models.Match) (*models.Match, error) {
+	oldMatch, err := s.matchRepository.GetMatch(match.GameID, match.MatchID, match.DateID)
 	if err != nil {
 		return nil, err
 	}
+	if err := matchVersion(fmt.Sprintf("match %s of game %s on %s", match.MatchID, match.GameID, match.DateID), match.Version, oldMatch.Version); err != nil {
+		return nil, err
+	}
+	match.Version = oldMatch.Version
 
 	game, err := s.gameRepository.GetGame(match.GameID)
 	if err != nil {
 		return nil, err
 	}
+	if err := game.CheckWritable(); err != nil {
+		return nil, err
+	}
+	if err := s.validateMatch(game, match, oldMatch); err != nil {
+		return nil, err
+	}
+
+	tx := &dynamodb.TransactWriteItemsInput{}
+
+	updatedMatch, err := s.matchRepository.UpdateMatch(match, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Players dropped from the match need their old attributes reverted too
+	userIDs := make(map[models.UserID]bool)
+	for userID := range oldMatch.PlayerAttributesMap {
+		userIDs[userID] = true
+	}
+	for userID := range match.PlayerAttributesMap {
+		userIDs[userID] = true
+	}
 
 	// Update GameStats and Leaderboards for each player
-	for userID, newAttributes := range match.PlayerAttributesMap {
+	for userID := range userIDs {
 		oldAttributes, _ := oldMatch.GetPlayerAttributes(userID)
-
-		gameStat, err := s.gameStatRepository.GetGameStat(userID, match.GameID)
-		if err != nil {
+		newAttributes, _ := match.GetPlayerAttributes(userID)
+		if err := s.applyPlayerAttributes(game, match, userID, oldAttributes, newAttributes, tx); err != nil {
 			return nil, err
 		}
-		// Update Leaderboards
-		for _, attr := range game.RankedAttributes {
-			if newValue, exists := newAttributes[attr]; exists {
-				oldValue := oldAttributes[attr]
-				oldSum := gameStat.GameAttributes[attr]
-				newSum := oldSum + (newValue - oldValue)
-				if err := s.leaderboardRepository.UpdateLeaderboardItem(match.GameID, userID, attr, newSum, oldSum, nil); err != nil {
-					return nil, err
-				}
-			}
-		}
+	}
 
-		// Update GameStat
-		for attrName, newValue := range newAttributes {
-			oldValue := oldAttributes[attrName]
-			oldSum := gameStat.GameAttributes[attrName]
-			newSum := oldSum + (newValue - oldValue)
-			gameStat.GameAttributes[attrName] = newSum
+	oldPlayers := oldMatch.Players()
+	newPlayers := match.Players()
+	for _, userID := range newPlayers {
+		if slices.Contains(oldPlayers, userID) {
+			continue
 		}
+		if err := s.userRepository.AddGamePlayed(userID, match.GameID, tx); err != nil {
+			return nil, err
+		}
+	}
 
-		if err := s.gameStatRepository.UpdateGameStat(gameStat, nil); err != nil {
+	if game.RatingSystem != models.RatingSystemNone && !match.SameResult(oldMatch) {
+		if err := s.ratingUpdater.requestRecompute(game.GameID, tx); err != nil {
 			return nil, err
 		}
+	}
 
+	var leavers []models.UserID
+	for _, userID := range oldPlayers {
+		if !slices.Contains(newPlayers, userID) {
+			leavers = append(leavers, userID)
+		}
+	}
+	if err := s.requestFollowUp(match.GameID, match.DateID, attributePlayers(oldMatch, match), leavers, tx); err != nil {
+		return nil, err
 	}
 
+	if err := s.transactionRepository.ExecuteTransaction(tx); err != nil {
+		return nil, err
+	}
 	return updatedMatch, nil
 }
 
+// DeleteMatch removes the match and takes its player attributes out of
+// all-time GameStats and Leaderboards in a single transaction, which also
+// requests a recompute of the game's ratings. Like CreateMatch, it retries
+// when it loses a race on a GameStat and leaves the period and season scopes
+// to a follow-up, which also removes the game from the GamesPlayed of players
+// with no other match of it.
 func (s *MatchServiceImpl) DeleteMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) error {
+	err := retryOnPreconditionFailed(func() error {
+		return s.deleteMatch(gameID, matchID, dateID)
+	})
+	if err != nil {
+		return err
+	}
+	s.followUp(gameID, dateID)
+	return nil
+}
+
+func (s *MatchServiceImpl) deleteMatch(gameID models.GameID, matchID models.MatchID, dateID models.DateID) error {
 	match, err := s.matchRepository.GetMatch(gameID, matchID, dateID)
 	if err != nil {
 		return err
@@ -152,35 +332,460 @@ func (s *MatchServiceImpl) DeleteMatch(gameID models.GameID, matchID models.Matc
 	if err != nil {
 		return err
 	}
+	if err := game.CheckWritable(); err != nil {
+		return err
+	}
+
+	tx := &dynamodb.TransactWriteItemsInput{}
 
 	// Update GameStats and Leaderboards for each player
 	for userID, attributes := range match.PlayerAttributesMap {
-		gameStat, err := s.gameStatRepository.GetGameStat(userID, gameID)
+		if err := s.applyPlayerAttributes(game, match, userID, attributes, nil, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.matchRepository.DeleteMatch(gameID, matchID, dateID, tx); err != nil {
+		return err
+	}
+
+	if game.RatingSystem != models.RatingSystemNone {
+		if err := s.ratingUpdater.requestRecompute(gameID, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.requestFollowUp(gameID, dateID, attributePlayers(match), match.Players(), tx); err != nil {
+		return err
+	}
+
+	if err := s.transactionRepository.ExecuteTransaction(tx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// requestFollowUp stages in tx the request of a follow-up of the game's
+// matches on dateID for the players and leavers, unless there are none.
+func (s *MatchServiceImpl) requestFollowUp(gameID models.GameID, dateID models.DateID, players, leavers []models.UserID, tx *dynamodb.TransactWriteItemsInput) error {
+	if len(players) == 0 && len(leavers) == 0 {
+		return nil
+	}
+	return s.matchRepository.RequestMatchFollowUp(gameID, dateID, players, leavers, tx)
+}
+
+// followUp carries out the pending follow-up of the game's matches on dateID
+// right after a match write has committed, so the scopes are usually up to
+// date when the write returns. The match is stored by then, so a failure is
+// not reported: the follow-up stays pending and MatchFollowUpService finishes
+// it.
+func (s *MatchServiceImpl) followUp(gameID models.GameID, dateID models.DateID) {
+	followUp, err := s.matchRepository.GetMatchFollowUp(gameID, dateID)
+	if err != nil {
+		return
+	}
+	_ = s.runFollowUp(followUp)
+}
+
+// runFollowUp brings the day, week, month and season GameStats and ranked
+// Leaderboards of the follow-up's players in line with their match history
+// and removes the game from the GamesPlayed of its leavers who have no other
+// match of it. It then drops the follow-up, unless it was requested again
+// meanwhile: that request's matches may have been missed, so the follow-up is
+// left for the next run. Every value is recomputed from the history rather
+// than incremented, so carrying out a follow-up twice changes nothing. The
+// follow-ups of a game being deleted are dropped, as the deletion removes
+// what they would write.
+func (s *MatchServiceImpl) runFollowUp(followUp *models.MatchFollowUp) error {
+	game, err := s.gameRepository.GetGame(followUp.GameID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+	if err == nil && game.Status != models.GameStatusDeleting {
+		if err := s.applyScopedAttributes(game, followUp.DateID, followUp.Players); err != nil {
+			return err
+		}
+		for _, userID := range followUp.Leavers {
+			if err := s.removeGamePlayed(userID, game.GameID); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = s.matchRepository.DeleteMatchFollowUp(followUp, nil)
+	if errors.Is(err, models.ErrPreconditionFailed) {
+		return nil
+	}
+	return err
+}
+
+// removeGamePlayed removes the game from the user's GamesPlayed if their
+// history holds no match of it any more. A match of the game stored
+// concurrently adds the game back in its own transaction, unless it was
+// stored between the history check and the removal; the history is checked
+// again afterwards to put the game back in that case.
+func (s *MatchServiceImpl) removeGamePlayed(userID models.UserID, gameID models.GameID) error {
+	// Anonymous players are not users
+	if models.IsAnonymousPlayer(userID) {
+		return nil
+	}
+	played, err := s.hasPlayed(userID, gameID)
+	if err != nil || played {
+		return err
+	}
+	err = s.userRepository.RemoveGamePlayed(userID, gameID, nil)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	played, err = s.hasPlayed(userID, gameID)
+	if err != nil || !played {
+		return err
+	}
+	return s.userRepository.AddGamePlayed(userID, gameID, nil)
+}
+
+// hasPlayed reports whether the user's history holds a match of the game.
+func (s *MatchServiceImpl) hasPlayed(userID models.UserID, gameID models.GameID) (bool, error) {
+	history, err := s.matchRepository.GetUserMatches(userID, models.UserMatchFilter{GameID: gameID}, 1, "")
+	if err != nil {
+		return false, err
+	}
+	return len(history.Matches) > 0, nil
+}
+
+// dateScopes returns the scopes a match played on dateID counts towards: the
+// all-time scope, the day, week and month containing dateID, and the season
+// of seasons containing dateID unless that season is closed.
+func dateScopes(dateID models.DateID, seasons []*models.Season) ([]models.LeaderboardScope, error) {
+	periodScopes, err := models.PeriodScopes(dateID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := append([]models.LeaderboardScope{models.AllTimeScope}, periodScopes...)
+
+	for _, season := range seasons {
+		if season.Contains(dateID) && !season.Closed {
+			scopes = append(scopes, season.Scope())
+		}
+	}
+	return scopes, nil
+}
+
+// attributePlayers returns the players with attributes in any of the
+// matches, in order, leaving out anonymous players, who have no GameStats.
+func attributePlayers(matches ...*models.Match) []models.UserID {
+	var userIDs []models.UserID
+	for _, match := range matches {
+		for userID := range match.PlayerAttributesMap {
+			if !models.IsAnonymousPlayer(userID) && !slices.Contains(userIDs, userID) {
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+	slices.Sort(userIDs)
+	return userIDs
+}
+
+// applyPlayerAttributes replaces the player's attributes in the match,
+// oldAttributes, by newAttributes in the player's all-time GameStat and
+// ranked Leaderboards, staging every write in tx. Either is nil when the
+// player had or has no attributes in the match. Summed attributes are
+// incremented by the difference atomically, so concurrent matches of the
+// player all count. Attributes aggregated otherwise cannot be updated from
+// the difference and are recomputed from the player's match history. The
+// leaderboard moves and recomputed values depend on what was read, though,
+// so when there are any the write is based on the GameStat version read, and
+// the transaction fails with ErrPreconditionFailed if the GameStat changed in
+// between. The other scopes are left to applyScopedAttributes.
+func (s *MatchServiceImpl) applyPlayerAttributes(game *models.Game, match *models.Match, userID models.UserID, oldAttributes, newAttributes models.AttributesStatsMap, tx *dynamodb.TransactWriteItemsInput) error {
+	// Anonymous players have no GameStats and no user
+	if models.IsAnonymousPlayer(userID) {
+		return nil
+	}
+	deltas := models.AttributesStatsMap{}
+	var recomputed []models.AttributeName
+	for _, attributes := range []models.AttributesStatsMap{oldAttributes, newAttributes} {
+		for attr := range attributes {
+			oldValue, hadValue := oldAttributes[attr]
+			newValue, hasValue := newAttributes[attr]
+			if game.Aggregation(attr).Invertible() {
+				deltas[attr] = newValue - oldValue
+			} else if (hadValue != hasValue || oldValue != newValue) && !slices.Contains(recomputed, attr) {
+				recomputed = append(recomputed, attr)
+			}
+		}
+	}
+
+	// A new GameStat starts with all attributes of the game at 0
+	increments := models.AttributesStatsMap{}
+	for _, attr := range game.Attributes {
+		if !slices.Contains(recomputed, attr) {
+			increments[attr] = 0
+		}
+	}
+	for attrName, delta := range deltas {
+		increments[attrName] += delta
+	}
+
+	var rankedAttributes []models.AttributeName
+	for _, attr := range game.RankedAttributes {
+		if _, exists := deltas[attr]; exists || slices.Contains(recomputed, attr) {
+			rankedAttributes = append(rankedAttributes, attr)
+		}
+	}
+
+	// The GameStat is read before the history, so that a match stored in
+	// between changes its version and fails the transaction. It is read
+	// whenever the game ranks attributes, as a player without a GameStat yet
+	// is added to every leaderboard of the game
+	var gameStat *models.GameStat
+	if len(game.RankedAttributes) > 0 || len(recomputed) > 0 {
+		var err error
+		gameStat, err = s.readGameStat(models.AllTimeScope, userID, game.GameID)
+		if err != nil {
+			return err
+		}
+	}
+
+	var values models.AttributesStatsMap
+	if len(recomputed) > 0 {
+		scopeValues, err := s.aggregateHistory(game, match, userID, newAttributes, recomputed, []models.LeaderboardScope{models.AllTimeScope}, models.UserMatchFilter{GameID: game.GameID})
 		if err != nil {
 			return err
 		}
-		// Update Leaderboards
+		values = scopeValues[models.AllTimeScope]
+	}
+
+	if gameStat != nil {
+		newValues := models.AttributesStatsMap{}
 		for _, attr := range game.RankedAttributes {
-			if value, exists := attributes[attr]; exists {
-				oldSum := gameStat.GameAttributes[attr]
-				newSum := oldSum - value
-				if err := s.leaderboardRepository.UpdateLeaderboardItem(gameID, userID, attr, newSum, oldSum, nil); err != nil {
-					return err
-				}
+			newValue, ok := values[attr]
+			if !ok {
+				newValue = gameStat.GameAttributes[attr] + deltas[attr]
 			}
+			newValues[attr] = newValue
+		}
+		if err := s.moveLeaderboardItems(game, models.AllTimeScope, gameStat, rankedAttributes, newValues, tx); err != nil {
+			return err
 		}
+	}
+
+	return s.gameStatRepository.IncrementGameStat(userID, game.GameID, increments, values, gameStat, tx)
+}
 
-		// Update GameStat
-		for attrName, value := range attributes {
-			gameStat.GameAttributes[attrName] -= value
+// readGameStat returns the player's GameStat in the scope, or a new one at
+// version 0 if they have none.
+func (s *MatchServiceImpl) readGameStat(scope models.LeaderboardScope, userID models.UserID, gameID models.GameID) (*models.GameStat, error) {
+	gameStat, err := s.gameStatRepository.WithScope(scope).GetGameStat(userID, gameID)
+	if errors.Is(err, models.ErrNotFound) {
+		return models.NewGameStat(userID, gameID, models.AttributesStatsMap{})
+	}
+	return gameStat, err
+}
+
+// moveLeaderboardItems stages in tx the moves of the player's entries for
+// attrs in the scope's ranked Leaderboards from their values in gameStat to
+// newValues. A player without a GameStat yet is added to every leaderboard
+// of the game instead, as moving an entry writes nothing when its value
+// stays 0.
+func (s *MatchServiceImpl) moveLeaderboardItems(game *models.Game, scope models.LeaderboardScope, gameStat *models.GameStat, attrs []models.AttributeName, newValues models.AttributesStatsMap, tx *dynamodb.TransactWriteItemsInput) error {
+	leaderboardRepository := s.leaderboardRepository.WithScope(scope)
+	if gameStat.Version == 0 {
+		for _, attr := range game.RankedAttributes {
+			if err := leaderboardRepository.AddLeaderboardItem(game.GameID, gameStat.UserID, attr, newValues[attr], tx); err != nil {
+				return err
+			}
 		}
+		return nil
+	}
+	for _, attr := range attrs {
+		if err := leaderboardRepository.UpdateLeaderboardItem(game.GameID, gameStat.UserID, attr, newValues[attr], gameStat.GameAttributes[attr], tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyScopedAttributes brings the players' GameStats and ranked Leaderboards
+// in the day, week, month and season scopes of a match of the game on dateID
+// in line with their match history. A match with many players would not fit
+// them in its own transaction, so they are written by its follow-up. The
+// writes of each player take one transaction, which MaxRankedAttributes keeps
+// within MaxTransactionItems, and are retried when a concurrent match changes
+// the GameStats they are based on.
+func (s *MatchServiceImpl) applyScopedAttributes(game *models.Game, dateID models.DateID, userIDs []models.UserID) error {
+	seasons, err := s.seasonRepository.GetSeasonsByGame(game.GameID)
+	if err != nil {
+		return err
+	}
+	scopes, err := dateScopes(dateID, seasons)
+	if err != nil {
+		return err
+	}
+	// The all-time scope is written with the match
+	scopes = slices.DeleteFunc(scopes, func(scope models.LeaderboardScope) bool {
+		return scope == models.AllTimeScope
+	})
+	filter, err := scopesFilter(game.GameID, dateID, seasons)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		err := retryOnPreconditionFailed(func() error {
+			return s.applyPlayerScopes(game, userID, scopes, filter)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		if err := s.gameStatRepository.UpdateGameStat(gameStat, nil); err != nil {
+// scopesFilter narrows a player's history of the game to the matches that can
+// count towards the period and season scopes of dateID: those played within
+// the week, month and open season containing it.
+func scopesFilter(gameID models.GameID, dateID models.DateID, seasons []*models.Season) (models.UserMatchFilter, error) {
+	filter := models.UserMatchFilter{GameID: gameID, From: dateID, To: dateID}
+	for _, period := range models.Periods {
+		first, last, err := models.PeriodDates(period, dateID)
+		if err != nil {
+			return models.UserMatchFilter{}, err
+		}
+		filter.From, filter.To = min(filter.From, first), max(filter.To, last)
+	}
+	for _, season := range seasons {
+		if season.Contains(dateID) && !season.Closed {
+			filter.From, filter.To = min(filter.From, season.StartDate), max(filter.To, season.EndDate)
+		}
+	}
+	return filter, nil
+}
+
+// applyPlayerScopes writes the player's GameStats and ranked Leaderboards in
+// the scopes as aggregated from their match history within filter, skipping
+// the scopes already up to date.
+func (s *MatchServiceImpl) applyPlayerScopes(game *models.Game, userID models.UserID, scopes []models.LeaderboardScope, filter models.UserMatchFilter) error {
+	// The GameStats are read before the history, so that a match stored in
+	// between changes their version and fails the transaction
+	gameStats := make([]*models.GameStat, len(scopes))
+	for i, scope := range scopes {
+		gameStat, err := s.readGameStat(scope, userID, game.GameID)
+		if err != nil {
 			return err
 		}
+		gameStats[i] = gameStat
+	}
 
+	scopeValues, err := s.aggregateHistory(game, nil, userID, nil, game.Attributes, scopes, filter)
+	if err != nil {
+		return err
 	}
 
-	return s.matchRepository.DeleteMatch(gameID, matchID, dateID, nil)
+	tx := &dynamodb.TransactWriteItemsInput{}
+	for i, scope := range scopes {
+		gameStat, values := gameStats[i], scopeValues[scope]
+		var changed []models.AttributeName
+		for _, attr := range game.Attributes {
+			if value, ok := gameStat.GameAttributes[attr]; !ok || value != values[attr] {
+				changed = append(changed, attr)
+			}
+		}
+		if gameStat.Version != 0 && len(changed) == 0 {
+			continue
+		}
+
+		var rankedAttributes []models.AttributeName
+		for _, attr := range game.RankedAttributes {
+			if slices.Contains(changed, attr) {
+				rankedAttributes = append(rankedAttributes, attr)
+			}
+		}
+		if err := s.moveLeaderboardItems(game, scope, gameStat, rankedAttributes, values, tx); err != nil {
+			return err
+		}
+		if err := s.gameStatRepository.WithScope(scope).IncrementGameStat(userID, game.GameID, nil, values, gameStat, tx); err != nil {
+			return err
+		}
+	}
+	if len(tx.TransactItems) == 0 {
+		return nil
+	}
+	return s.transactionRepository.ExecuteTransaction(tx)
 }
 
+// historyPageSize is how many entries of a player's match history are read
+// at a time when recomputing their attributes.
+const historyPageSize = 100
+
+// aggregateHistory recomputes attrs of the player in every scope from their
+// match history within filter, as it will be once the match is written with
+// attributes as the player's, or without the player when attributes is nil.
+// When match is nil, the history is aggregated as stored. filter must hold
+// every match counting towards the scopes.
+func (s *MatchServiceImpl) aggregateHistory(game *models.Game, match *models.Match, userID models.UserID, attributes models.AttributesStatsMap, attrs []models.AttributeName, scopes []models.LeaderboardScope, filter models.UserMatchFilter) (map[models.LeaderboardScope]models.AttributesStatsMap, error) {
+	var entries []*models.UserMatch
+	if match != nil && attributes != nil {
+		entries = append(entries, &models.UserMatch{DateID: match.DateID, MatchID: match.MatchID, Attributes: attributes})
+	}
+	cursor := ""
+	for {
+		history, err := s.matchRepository.GetUserMatches(userID, filter, historyPageSize, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range history.Matches {
+			if match == nil || entry.DateID != match.DateID || entry.MatchID != match.MatchID {
+				entries = append(entries, entry)
+			}
+		}
+		if history.NextCursor == "" {
+			break
+		}
+		cursor = history.NextCursor
+	}
+	// The history is newest first, and the match is not in its place
+	slices.SortFunc(entries, func(a, b *models.UserMatch) int {
+		if a.DateID != b.DateID {
+			return strings.Compare(string(a.DateID), string(b.DateID))
+		}
+		return strings.Compare(string(a.MatchID), string(b.MatchID))
+	})
+
+	seasons, err := s.seasonRepository.GetSeasonsByGame(game.GameID)
+	if err != nil {
+		return nil, err
+	}
+	collected := make(map[models.LeaderboardScope]map[models.AttributeName][]models.AttributeStat)
+	for _, scope := range scopes {
+		collected[scope] = make(map[models.AttributeName][]models.AttributeStat)
+	}
+	for _, entry := range entries {
+		entryScopes, err := dateScopes(entry.DateID, seasons)
+		if err != nil {
+			return nil, err
+		}
+		for _, scope := range entryScopes {
+			if collected[scope] == nil {
+				continue
+			}
+			for _, attr := range attrs {
+				if value, ok := entry.Attributes[attr]; ok {
+					collected[scope][attr] = append(collected[scope][attr], value)
+				}
+			}
+		}
+	}
+
+	scopeValues := make(map[models.LeaderboardScope]models.AttributesStatsMap, len(scopes))
+	for _, scope := range scopes {
+		scopeValues[scope] = models.AttributesStatsMap{}
+		for _, attr := range attrs {
+			scopeValues[scope][attr] = game.Aggregation(attr).Aggregate(collected[scope][attr])
+		}
+	}
+	return scopeValues, nil
+}
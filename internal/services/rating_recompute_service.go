@@ -0,0 +1,16 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+// RatingRecomputeService carries out the rating recomputes that match, user
+// and game changes request.
+type RatingRecomputeService interface {
+	// RecomputePendingRatings recomputes the ratings of every game with a
+	// pending recompute until deadline and returns the games it finished. A
+	// recompute requested again meanwhile is left pending for the next call.
+	RecomputePendingRatings(deadline time.Time) ([]models.GameID, error)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type RatingRecomputeServiceImpl struct {
+	gameRepository   repositories.GameRepository
+	ratingRepository repositories.RatingRepository
+	ratingUpdater    *ratingUpdater
+}
+
+func NewRatingRecomputeServiceImpl(
+	gameRepository repositories.GameRepository,
+	matchRepository repositories.MatchRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	ratingRepository repositories.RatingRepository,
+	transactionRepository repositories.TransactionRepository,
+) RatingRecomputeService {
+	return &RatingRecomputeServiceImpl{
+		gameRepository:   gameRepository,
+		ratingRepository: ratingRepository,
+		ratingUpdater:    newRatingUpdater(matchRepository, ratingRepository, leaderboardRepository, transactionRepository),
+	}
+}
+
+// RecomputePendingRatings replays each game's match history in turn, and
+// drops its recompute once the ratings are written if its version is still
+// the one read. A request made while the game was replayed may have been
+// missed, so it leaves the recompute for the next run. The ratings of a game
+// deleted since are all deleted.
+func (s *RatingRecomputeServiceImpl) RecomputePendingRatings(deadline time.Time) ([]models.GameID, error) {
+	recomputes, err := s.ratingRepository.GetRatingRecomputes()
+	if err != nil {
+		return nil, err
+	}
+
+	var recomputed []models.GameID
+	for _, recompute := range recomputes {
+		if time.Now().After(deadline) {
+			return recomputed, nil
+		}
+
+		game, err := s.gameRepository.GetGame(recompute.GameID)
+		if errors.Is(err, models.ErrNotFound) {
+			// Without a rating system, recomputing deletes every rating
+			game = &models.Game{GameID: recompute.GameID}
+		} else if err != nil {
+			return recomputed, err
+		}
+		if err := s.ratingUpdater.recompute(game); err != nil {
+			return recomputed, fmt.Errorf("failed to recompute the ratings of game %s: %w", game.GameID, err)
+		}
+
+		err = s.ratingRepository.DeleteRatingRecompute(recompute, nil)
+		if errors.Is(err, models.ErrPreconditionFailed) {
+			continue
+		}
+		if err != nil {
+			return recomputed, err
+		}
+		recomputed = append(recomputed, recompute.GameID)
+	}
+	return recomputed, nil
+}
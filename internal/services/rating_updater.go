@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/ratings"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+// ratingWritesPerPlayer is the most writes rewriting one player's rating
+// takes: the rating itself and moving its leaderboard entry.
+const ratingWritesPerPlayer = 3
+
+// ratingUpdater keeps a game's ratings and its rating leaderboard in step with
+// the game's match history.
+type ratingUpdater struct {
+	matchRepository       repositories.MatchRepository
+	ratingRepository      repositories.RatingRepository
+	leaderboardRepository repositories.LeaderboardRepository
+	transactionRepository repositories.TransactionRepository
+}
+
+func newRatingUpdater(
+	matchRepository repositories.MatchRepository,
+	ratingRepository repositories.RatingRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	transactionRepository repositories.TransactionRepository,
+) *ratingUpdater {
+	return &ratingUpdater{
+		matchRepository:       matchRepository,
+		ratingRepository:      ratingRepository,
+		leaderboardRepository: leaderboardRepository,
+		transactionRepository: transactionRepository,
+	}
+}
+
+// applyMatch stages in tx the rating changes of a new match. This is only
+// correct when the match comes after every match its players have played and
+// no recompute of the game's ratings is pending, so otherwise applyMatch
+// stages a recompute request instead. The rating changes are staged with a
+// check that no recompute is requested before tx runs, as the recompute could
+// overwrite them.
+func (u *ratingUpdater) applyMatch(game *models.Game, match *models.Match, tx *dynamodb.TransactWriteItemsInput) error {
+	if game.RatingSystem == models.RatingSystemNone {
+		return nil
+	}
+	calculator, err := ratings.NewCalculator(game.RatingSystem)
+	if err != nil {
+		return err
+	}
+
+	// The pending recompute may not have replayed the ratings read below yet
+	if _, err := u.ratingRepository.GetRatingRecompute(game.GameID); err == nil {
+		return u.requestRecompute(game.GameID, tx)
+	} else if !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+
+	current := make(map[models.UserID]*models.Rating)
+	oldValues := make(map[models.UserID]models.AttributeStat)
+	for _, userID := range match.Players() {
+		rating, err := u.ratingRepository.GetRating(game.GameID, userID)
+		if errors.Is(err, models.ErrNotFound) {
+			// Players without a rating start from the initial one
+			current[userID] = calculator.NewRating(game.GameID, userID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !rating.PlayedBefore(match) {
+			return u.requestRecompute(game.GameID, tx)
+		}
+		current[userID] = rating
+		oldValues[userID] = rating.Value
+	}
+
+	rateMatch(calculator, match, current)
+
+	if err := u.ratingRepository.CheckNoRatingRecompute(game.GameID, tx); err != nil {
+		return err
+	}
+	for _, rating := range current {
+		if err := u.ratingRepository.UpdateRating(rating, tx); err != nil {
+			return err
+		}
+		if err := u.updateLeaderboard(rating, oldValues, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestRecompute stages in tx, or writes right away when tx is nil, a
+// request to recompute the game's ratings, which RatingRecomputeService
+// carries out in the background. It is used by every change that applyMatch
+// cannot apply: matches stored out of order, updated or deleted, deleted
+// users and changed rating systems.
+func (u *ratingUpdater) requestRecompute(gameID models.GameID, tx *dynamodb.TransactWriteItemsInput) error {
+	return u.ratingRepository.RequestRatingRecompute(gameID, tx)
+}
+
+// recompute replays the game's whole match history and rewrites every rating
+// that differs from the result, deleting those of players left without
+// matches. Anonymous players, who stand for deleted users, are not stored. A
+// game without a rating system ends up with no ratings. It reads every match
+// of the game, so it only runs in background jobs.
+//
+// The writes are split into transactions of at most MaxTransactionItems, so a
+// failure can leave the ratings partly rewritten; recomputing again repairs
+// them.
+func (u *ratingUpdater) recompute(game *models.Game) error {
+	replayed := make(map[models.UserID]*models.Rating)
+	if game.RatingSystem != models.RatingSystemNone {
+		calculator, err := ratings.NewCalculator(game.RatingSystem)
+		if err != nil {
+			return err
+		}
+		matches, err := u.matchRepository.GetMatchesByGame(game.GameID)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			for _, userID := range match.Players() {
+				// An anonymous ID stands for a different deleted user in
+				// every match, each rated as a newcomer
+				if _, ok := replayed[userID]; !ok || models.IsAnonymousPlayer(userID) {
+					replayed[userID] = calculator.NewRating(game.GameID, userID)
+				}
+			}
+			rateMatch(calculator, match, replayed)
+		}
+		for userID := range replayed {
+			if models.IsAnonymousPlayer(userID) {
+				delete(replayed, userID)
+			}
+		}
+	}
+
+	stored, err := u.ratingRepository.GetRatingsByGame(game.GameID)
+	if err != nil {
+		return err
+	}
+	oldValues := make(map[models.UserID]models.AttributeStat)
+	storedRatings := make(map[models.UserID]*models.Rating)
+	userIDs := make([]models.UserID, 0, len(replayed)+len(stored))
+	for _, rating := range stored {
+		oldValues[rating.UserID] = rating.Value
+		storedRatings[rating.UserID] = rating
+		userIDs = append(userIDs, rating.UserID)
+	}
+	for userID := range replayed {
+		if _, ok := storedRatings[userID]; !ok {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	tx := &dynamodb.TransactWriteItemsInput{}
+	for _, userID := range userIDs {
+		rating, ok := replayed[userID]
+		switch {
+		case !ok:
+			if err := u.ratingRepository.DeleteRating(game.GameID, userID, tx); err != nil {
+				return err
+			}
+			if err := u.leaderboardRepository.DeleteLeaderboardItem(game.GameID, userID, models.RatingAttribute, oldValues[userID], tx); err != nil {
+				return err
+			}
+		case storedRatings[userID] == nil || *storedRatings[userID] != *rating:
+			if err := u.ratingRepository.UpdateRating(rating, tx); err != nil {
+				return err
+			}
+			if err := u.updateLeaderboard(rating, oldValues, tx); err != nil {
+				return err
+			}
+		}
+
+		if len(tx.TransactItems) > repositories.MaxTransactionItems-ratingWritesPerPlayer {
+			if err := u.transactionRepository.ExecuteTransaction(tx); err != nil {
+				return err
+			}
+			tx = &dynamodb.TransactWriteItemsInput{}
+		}
+	}
+	if len(tx.TransactItems) == 0 {
+		return nil
+	}
+	return u.transactionRepository.ExecuteTransaction(tx)
+}
+
+// updateLeaderboard moves the player's rating leaderboard entry from its old
+// value, or adds it if the player had no rating before.
+func (u *ratingUpdater) updateLeaderboard(rating *models.Rating, oldValues map[models.UserID]models.AttributeStat, tx *dynamodb.TransactWriteItemsInput) error {
+	oldValue, ok := oldValues[rating.UserID]
+	if !ok {
+		return u.leaderboardRepository.AddLeaderboardItem(rating.GameID, rating.UserID, models.RatingAttribute, rating.Value, tx)
+	}
+	return u.leaderboardRepository.UpdateLeaderboardItem(rating.GameID, rating.UserID, models.RatingAttribute, rating.Value, oldValue, tx)
+}
+
+// rateMatch updates the ratings of the match's players, which must all be in
+// ratings, and records the match as the latest each of them played. Empty
+// teams take no part in the match.
+func rateMatch(calculator ratings.Calculator, match *models.Match, current map[models.UserID]*models.Rating) {
+	ranks := match.TeamRanks()
+	var teams [][]*models.Rating
+	var teamRanks []int
+	for i, members := range match.TeamMembers {
+		if len(members) == 0 {
+			continue
+		}
+		team := make([]*models.Rating, len(members))
+		for k, member := range members {
+			team[k] = current[models.UserID(member)]
+		}
+		teams = append(teams, team)
+		teamRanks = append(teamRanks, ranks[i])
+	}
+
+	calculator.Rate(teams, teamRanks)
+
+	for _, userID := range match.Players() {
+		rating := current[userID]
+		rating.MatchesPlayed++
+		rating.LastDateID = match.DateID
+		rating.LastMatchID = match.MatchID
+	}
+}
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"github.com/mquan1409/game-api/internal/models"
+)
+
+type SeasonService interface {
+	GetSeason(gameID models.GameID, seasonID models.SeasonID) (*models.Season, error)
+	GetSeasonsByGame(gameID models.GameID) ([]*models.Season, error)
+	CreateSeason(season *models.Season) (*models.Season, error)
+	UpdateSeason(season *models.Season) (*models.Season, error)
+	CloseSeason(gameID models.GameID, seasonID models.SeasonID) (*models.Season, error)
+	DeleteSeason(gameID models.GameID, seasonID models.SeasonID) error
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories"
+)
+
+type SeasonServiceImpl struct {
+	seasonRepository      repositories.SeasonRepository
+	gameRepository        repositories.GameRepository
+	gameStatRepository    repositories.GameStatRepository
+	leaderboardRepository repositories.LeaderboardRepository
+	matchRepository       repositories.MatchRepository
+}
+
+func NewSeasonServiceImpl(
+	seasonRepository repositories.SeasonRepository,
+	gameRepository repositories.GameRepository,
+	gameStatRepository repositories.GameStatRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	matchRepository repositories.MatchRepository,
+) SeasonService {
+	return &SeasonServiceImpl{
+		seasonRepository:      seasonRepository,
+		gameRepository:        gameRepository,
+		gameStatRepository:    gameStatRepository,
+		leaderboardRepository: leaderboardRepository,
+		matchRepository:       matchRepository,
+	}
+}
+
+func (s *SeasonServiceImpl) GetSeason(gameID models.GameID, seasonID models.SeasonID) (*models.Season, error) {
+	return s.seasonRepository.GetSeason(gameID, seasonID)
+}
+
+func (s *SeasonServiceImpl) GetSeasonsByGame(gameID models.GameID) ([]*models.Season, error) {
+	return s.seasonRepository.GetSeasonsByGame(gameID)
+}
+
+// CreateSeason adds an open season. Seasons of a game may not overlap, so
+// every match date belongs to at most one season.
+func (s *SeasonServiceImpl) CreateSeason(season *models.Season) (*models.Season, error) {
+	if _, err := s.writableGame(season.GameID); err != nil {
+		return nil, err
+	}
+	if _, err := s.seasonRepository.GetSeason(season.GameID, season.SeasonID); err == nil {
+		return nil, models.NewConflictError("season %s already exists", season.SeasonID)
+	} else if !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+	if err := s.checkOverlap(season); err != nil {
+		return nil, err
+	}
+
+	season.Closed = false
+	return s.seasonRepository.CreateSeason(season, nil)
+}
+
+// UpdateSeason changes an open season's name and dates. The season's GameStats
+// and leaderboards count the matches within its dates, so the dates can only
+// change while the game has no match within either the old or the new ones.
+func (s *SeasonServiceImpl) UpdateSeason(season *models.Season) (*models.Season, error) {
+	oldSeason, err := s.seasonRepository.GetSeason(season.GameID, season.SeasonID)
+	if err != nil {
+		return nil, err
+	}
+	if oldSeason.Closed {
+		return nil, models.NewForbiddenError("season %s is closed", season.SeasonID)
+	}
+	if _, err := s.writableGame(season.GameID); err != nil {
+		return nil, err
+	}
+	if err := s.checkOverlap(season); err != nil {
+		return nil, err
+	}
+	if season.StartDate != oldSeason.StartDate || season.EndDate != oldSeason.EndDate {
+		for _, dates := range []models.DateRange{{From: oldSeason.StartDate, To: oldSeason.EndDate}, {From: season.StartDate, To: season.EndDate}} {
+			page, err := s.matchRepository.GetMatchesByGameAndDateRange(season.GameID, dates, 1, "")
+			if err != nil {
+				return nil, err
+			}
+			if len(page.Matches) > 0 {
+				return nil, models.NewConflictError("the dates of season %s cannot change: game %s has matches from %s to %s", season.SeasonID, season.GameID, dates.From, dates.To)
+			}
+		}
+	}
+
+	// Closing goes through CloseSeason
+	season.Closed = false
+	return s.seasonRepository.UpdateSeason(season, nil)
+}
+
+// CloseSeason freezes the season: matches stop counting towards it, so its
+// GameStats and leaderboards keep their final standings.
+func (s *SeasonServiceImpl) CloseSeason(gameID models.GameID, seasonID models.SeasonID) (*models.Season, error) {
+	season, err := s.seasonRepository.GetSeason(gameID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	if season.Closed {
+		return season, nil
+	}
+	if _, err := s.writableGame(gameID); err != nil {
+		return nil, err
+	}
+
+	season.Closed = true
+	return s.seasonRepository.UpdateSeason(season, nil)
+}
+
+// DeleteSeason removes the season with its leaderboards and the season
+// GameStats of every player.
+func (s *SeasonServiceImpl) DeleteSeason(gameID models.GameID, seasonID models.SeasonID) error {
+	season, err := s.seasonRepository.GetSeason(gameID, seasonID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.writableGame(gameID); err != nil {
+		return err
+	}
+
+	if err := s.gameStatRepository.WithScope(season.Scope()).DeleteGameStatsByGame(gameID, nil); err != nil {
+		return err
+	}
+	leaderboardRepository := s.leaderboardRepository.WithScope(season.Scope())
+	if err := leaderboardRepository.DeleteLeaderboardItemsByGame(gameID, nil); err != nil {
+		return err
+	}
+	return s.seasonRepository.DeleteSeason(gameID, seasonID, nil)
+}
+
+// writableGame reads the game of a season being changed, which must be
+// neither archived nor being deleted.
+func (s *SeasonServiceImpl) writableGame(gameID models.GameID) (*models.Game, error) {
+	game, err := s.gameRepository.GetGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+	if err := game.CheckWritable(); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func (s *SeasonServiceImpl) checkOverlap(season *models.Season) error {
+	seasons, err := s.seasonRepository.GetSeasonsByGame(season.GameID)
+	if err != nil {
+		return err
+	}
+	for _, other := range seasons {
+		if other.SeasonID != season.SeasonID && other.Overlaps(season) {
+			return models.NewConflictError("season %s overlaps season %s (%s to %s)", season.SeasonID, other.SeasonID, other.StartDate, other.EndDate)
+		}
+	}
+	return nil
+}
@@ -7,7 +7,9 @@ import (
 type UserService interface {
 	GetUser(id models.UserID) (*models.User, error)
 	GetUserBasicsByPrefix(prefix string) ([]*models.UserBasic, error)
-	GetGameStat(userID models.UserID, gameID models.GameID) (*models.GameStat, error)	
+	GetGameStat(userID models.UserID, gameID models.GameID, scope models.LeaderboardScope) (*models.GameStat, error)
+	GetGameStats(userID models.UserID, scope models.LeaderboardScope, attrs []models.AttributeName) ([]*models.GameStat, error)
+	GetUserMatches(userID models.UserID, filter models.UserMatchFilter, limit int, cursor string) (*models.UserMatchHistory, error)
 	CreateUser(user *models.User) (*models.User, error)
 	UpdateUser(user *models.User) (*models.User, error)
 	DeleteUser(id *models.UserID) error
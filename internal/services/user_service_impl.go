@@ -1,19 +1,48 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
 )
 
 type UserServiceImpl struct {
-	userRepository repositories.UserRepository
-	gamestatRepository repositories.GameStatRepository
+	userRepository        repositories.UserRepository
+	gamestatRepository    repositories.GameStatRepository
+	matchRepository       repositories.MatchRepository
+	gameRepository        repositories.GameRepository
+	leaderboardRepository repositories.LeaderboardRepository
+	seasonRepository      repositories.SeasonRepository
+	ratingRepository      repositories.RatingRepository
+	transactionRepository repositories.TransactionRepository
+	ratingUpdater         *ratingUpdater
 }
 
-func NewUserServiceImpl(userRepository repositories.UserRepository, gamestatRepository repositories.GameStatRepository) UserService {
+func NewUserServiceImpl(
+	userRepository repositories.UserRepository,
+	gamestatRepository repositories.GameStatRepository,
+	matchRepository repositories.MatchRepository,
+	gameRepository repositories.GameRepository,
+	leaderboardRepository repositories.LeaderboardRepository,
+	seasonRepository repositories.SeasonRepository,
+	ratingRepository repositories.RatingRepository,
+	transactionRepository repositories.TransactionRepository,
+) UserService {
 	return &UserServiceImpl{
-		userRepository: userRepository,
-		gamestatRepository: gamestatRepository,
+		userRepository:        userRepository,
+		gamestatRepository:    gamestatRepository,
+		matchRepository:       matchRepository,
+		gameRepository:        gameRepository,
+		leaderboardRepository: leaderboardRepository,
+		seasonRepository:      seasonRepository,
+		ratingRepository:      ratingRepository,
+		transactionRepository: transactionRepository,
+		ratingUpdater:         newRatingUpdater(matchRepository, ratingRepository, leaderboardRepository, transactionRepository),
 	}
 }
 func (s *UserServiceImpl) GetUser(id models.UserID) (*models.User, error) {
@@ -24,25 +53,275 @@ func (s *UserServiceImpl) GetUserBasicsByPrefix(prefix string) ([]*models.UserBa
 	return s.userRepository.GetUserBasicsByPrefix(prefix)
 }
 
-func (s *UserServiceImpl) GetGameStat(userID models.UserID, gameID models.GameID) (*models.GameStat, error) {
-	gameStat, err := s.gamestatRepository.GetGameStat(userID, gameID)
+func (s *UserServiceImpl) GetGameStat(userID models.UserID, gameID models.GameID, scope models.LeaderboardScope) (*models.GameStat, error) {
+	gameStat, err := s.gamestatRepository.WithScope(scope).GetGameStat(userID, gameID)
 	if err != nil {
 		return nil, err
 	}
 	return gameStat, nil
 }
 
+// GetGameStats returns the user's GameStats in every game within scope,
+// narrowed to attrs when it is not empty. All-time GameStats are read with
+// one Query. Those of other scopes are kept per game, so they are read one
+// game of the user's GamesPlayed at a time.
+func (s *UserServiceImpl) GetGameStats(userID models.UserID, scope models.LeaderboardScope, attrs []models.AttributeName) ([]*models.GameStat, error) {
+	if scope == models.AllTimeScope {
+		return s.gamestatRepository.GetGameStatsByUser(userID, attrs)
+	}
+
+	user, err := s.userRepository.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	gameStats := []*models.GameStat{}
+	for _, gameID := range user.GamesPlayed {
+		gameStat, err := s.gamestatRepository.WithScope(scope).GetGameStat(userID, gameID)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(attrs) > 0 {
+			narrowed := models.AttributesStatsMap{}
+			for _, attr := range attrs {
+				if value, ok := gameStat.GameAttributes[attr]; ok {
+					narrowed[attr] = value
+				}
+			}
+			gameStat.GameAttributes = narrowed
+		}
+		gameStats = append(gameStats, gameStat)
+	}
+	slices.SortFunc(gameStats, func(a, b *models.GameStat) int {
+		return strings.Compare(string(a.GameID), string(b.GameID))
+	})
+	return gameStats, nil
+}
+
+func (s *UserServiceImpl) GetUserMatches(userID models.UserID, filter models.UserMatchFilter, limit int, cursor string) (*models.UserMatchHistory, error) {
+	history, err := s.matchRepository.GetUserMatches(userID, filter, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// CreateUser stores a user who has played no game yet; GamesPlayed is kept
+// up to date by the match service.
 func (s *UserServiceImpl) CreateUser(user *models.User) (*models.User, error) {
+	user.GamesPlayed = []models.GameID{}
 	return s.userRepository.CreateUser(user, nil)
 }
 
+// UpdateUser replaces the user, which must still be at user.Version unless it
+// is 0, keeping their GamesPlayed. A match changing GamesPlayed also bumps the
+// version, so an update without a version reads the user again and retries
+// when one is written between its read and its write.
 func (s *UserServiceImpl) UpdateUser(user *models.User) (*models.User, error) {
-	return s.userRepository.UpdateUser(user, nil)
+	version := user.Version
+	update := func() error {
+		oldUser, err := s.userRepository.GetUser(user.UserID)
+		if err != nil {
+			return err
+		}
+		if err := matchVersion(fmt.Sprintf("user %s", user.UserID), version, oldUser.Version); err != nil {
+			return err
+		}
+		user.Version = oldUser.Version
+		user.GamesPlayed = oldUser.GamesPlayed
+		_, err = s.userRepository.UpdateUser(user, nil)
+		return err
+	}
+
+	var err error
+	if version == 0 {
+		err = retryOnPreconditionFailed(update)
+	} else {
+		err = update()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
+// DeleteUser deletes the user and everything derived from them in each game
+// of GamesPlayed: their GameStats and leaderboard entries in every scope and
+// their rating. The matches they played keep their result for the other
+// players, with the user anonymized. The user is deleted last, so a deletion
+// that fails part way can be retried.
 func (s *UserServiceImpl) DeleteUser(id *models.UserID) error {
+	if *id == "" {
+		return models.NewValidationError("id cannot be empty")
+	}
+	user, err := s.userRepository.GetUser(*id)
+	if err != nil {
+		return err
+	}
+
+	for _, gameID := range user.GamesPlayed {
+		if err := s.deleteGameData(user.UserID, gameID); err != nil {
+			return fmt.Errorf("failed to delete data of user %s in game %s: %w", user.UserID, gameID, err)
+		}
+	}
 	return s.userRepository.DeleteUser(id, nil)
 }
 
+// deleteGameData deletes the user's GameStats and leaderboard entries in
+// every scope they can have, then anonymizes the user in the game's matches,
+// and finally deletes their rating while requesting a recompute of the other
+// players' ratings without them. The scopes come from the user's match
+// history, so they are deleted before anonymizing removes it.
+func (s *UserServiceImpl) deleteGameData(userID models.UserID, gameID models.GameID) error {
+	userMatches, err := s.getUserMatches(userID, gameID)
+	if err != nil {
+		return err
+	}
+
+	// A GameStat exists in the period scopes of the dates played, and in the
+	// scope of any season of the game, closed or not
+	scopes := []models.LeaderboardScope{models.AllTimeScope}
+	for _, userMatch := range userMatches {
+		periodScopes, err := models.PeriodScopes(userMatch.DateID)
+		if err != nil {
+			return err
+		}
+		for _, scope := range periodScopes {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	seasons, err := s.seasonRepository.GetSeasonsByGame(gameID)
+	if err != nil {
+		return err
+	}
+	for _, season := range seasons {
+		scopes = append(scopes, season.Scope())
+	}
+
+	for _, scope := range scopes {
+		if err := s.deleteScopedGameStat(userID, gameID, scope); err != nil {
+			return err
+		}
+	}
+	if err := s.anonymizeMatches(userID, userMatches); err != nil {
+		return err
+	}
+
+	game, err := s.gameRepository.GetGame(gameID)
+	if errors.Is(err, models.ErrNotFound) {
+		// Ratings go with their game
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if game.RatingSystem == models.RatingSystemNone {
+		return nil
+	}
+	tx := &dynamodb.TransactWriteItemsInput{}
+	rating, err := s.ratingRepository.GetRating(gameID, userID)
+	if err == nil {
+		if err := s.ratingRepository.DeleteRating(gameID, userID, tx); err != nil {
+			return err
+		}
+		if err := s.leaderboardRepository.DeleteLeaderboardItem(gameID, userID, models.RatingAttribute, rating.Value, tx); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+	if err := s.ratingUpdater.requestRecompute(gameID, tx); err != nil {
+		return err
+	}
+	return s.transactionRepository.ExecuteTransaction(tx)
+}
+
+// deleteScopedGameStat deletes the user's GameStat in scope together with
+// their leaderboard entries, whose keys hold the GameStat's values. Every
+// attribute is deleted, since an attribute may have been ranked when its
+// entry was written. The GameStat is deleted last, so a deletion that fails
+// part way can be retried.
+func (s *UserServiceImpl) deleteScopedGameStat(userID models.UserID, gameID models.GameID, scope models.LeaderboardScope) error {
+	gameStat, err := s.gamestatRepository.WithScope(scope).GetGameStat(userID, gameID)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	leaderboardRepository := s.leaderboardRepository.WithScope(scope)
+	attrs := make([]models.AttributeName, 0, len(gameStat.GameAttributes))
+	for attr := range gameStat.GameAttributes {
+		attrs = append(attrs, attr)
+	}
+	slices.Sort(attrs)
+
+	tx := &dynamodb.TransactWriteItemsInput{}
+	for _, attr := range attrs {
+		if len(tx.TransactItems) >= repositories.MaxTransactionItems-1 {
+			if err := s.transactionRepository.ExecuteTransaction(tx); err != nil {
+				return err
+			}
+			tx = &dynamodb.TransactWriteItemsInput{}
+		}
+		if err := leaderboardRepository.DeleteLeaderboardItem(gameID, userID, attr, gameStat.GameAttributes[attr], tx); err != nil {
+			return err
+		}
+	}
+	if err := s.gamestatRepository.WithScope(scope).DeleteGameStat(userID, gameID, tx); err != nil {
+		return err
+	}
+	return s.transactionRepository.ExecuteTransaction(tx)
+}
 
+// userMatchPageSize is the number of history entries read at once when
+// deleting a user's data in a game.
+const userMatchPageSize = 100
 
+// getUserMatches returns the user's whole match history in the game.
+func (s *UserServiceImpl) getUserMatches(userID models.UserID, gameID models.GameID) ([]*models.UserMatch, error) {
+	var userMatches []*models.UserMatch
+	cursor := ""
+	for {
+		history, err := s.matchRepository.GetUserMatches(userID, models.UserMatchFilter{GameID: gameID}, userMatchPageSize, cursor)
+		if err != nil {
+			return nil, err
+		}
+		userMatches = append(userMatches, history.Matches...)
+		if history.NextCursor == "" {
+			return userMatches, nil
+		}
+		cursor = history.NextCursor
+	}
+}
+
+// anonymizeMatches anonymizes the user in every match of their history, which
+// also removes the history entries.
+func (s *UserServiceImpl) anonymizeMatches(userID models.UserID, userMatches []*models.UserMatch) error {
+	for _, userMatch := range userMatches {
+		// The match may be updated concurrently; read it again and retry
+		err := retryOnPreconditionFailed(func() error {
+			match, err := s.matchRepository.GetMatch(userMatch.GameID, userMatch.MatchID, userMatch.DateID)
+			if errors.Is(err, models.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !match.Anonymize(userID) {
+				return nil
+			}
+			_, err = s.matchRepository.UpdateMatch(match, nil)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package services
+
+import "github.com/mquan1409/game-api/internal/models"
+
+// matchVersion checks that an update given as version was based on stored,
+// the version of the stored resource. Version 0 means the caller didn't say
+// which version it read and matches any.
+func matchVersion(resource string, version, stored int) error {
+	if version != 0 && version != stored {
+		return models.NewPreconditionFailedError("%s is at version %d, not %d", resource, stored, version)
+	}
+	return nil
+}
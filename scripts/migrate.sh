@@ -0,0 +1 @@
+DYNAMODB_ENDPOINT=http://localhost:8000 DYNAMODB_TABLE=dev-table DYNAMODB_REGION=us-east-1 go run $BASE_WORK_DIR/cmd/migrate
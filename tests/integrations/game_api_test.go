@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/mquan1409/game-api/internal/config"
 	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
+	"github.com/mquan1409/game-api/internal/services"
 	"github.com/mquan1409/game-api/internal/utils"
 )
 
@@ -25,7 +27,16 @@ func TestGameAPI(t *testing.T) {
 	}
 
 	gameRepo := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
-	leaderboardRepo := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
+	gameDeletionService := services.NewGameDeletionServiceImpl(
+		gameRepo,
+		repositories.NewDynamoDBMatchRepository(db, cfg.TableName),
+		repositories.NewDynamoDBUserRepository(db, cfg.TableName),
+		repositories.NewDynamoDBGameStatRepository(db, cfg.TableName),
+		repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName),
+		repositories.NewDynamoDBSeasonRepository(db, cfg.TableName),
+		repositories.NewDynamoDBRatingRepository(db, cfg.TableName),
+		repositories.NewDynamoDBTransactionRepository(db),
+	)
 
 	// Scan the entire table before tests
 	beforeScan, err := utils.ScanEntireTable(db, cfg.TableName)
@@ -49,23 +60,48 @@ func TestGameAPI(t *testing.T) {
 		assert.Contains(t, game.RankedAttributes, models.AttributeName("elo"))
 	})
 
-	// Test GetLeaderboard
+	// Test GetGames
+	t.Run("GetGames", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("%s/games?limit=2", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page models.GamePage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(page.Games))
+		assert.NotEmpty(t, page.NextCursor)
+
+		resp, err = http.Get(fmt.Sprintf("%s/games?description=Pool&rankedAttribute=elo", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		page = models.GamePage{}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Games))
+		assert.Equal(t, models.GameID("pool"), page.Games[0].GameID)
+	})
+
+	// Test GetLeaderboard without a limit returns the first page of the default size
 	t.Run("GetLeaderboard", func(t *testing.T) {
 		resp, err := http.Get(fmt.Sprintf("%s/games/soccer/leaderboard/elo", baseURL))
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 
-		var leaderboard models.LeaderBoard
+		var leaderboard models.BoundedLeaderboard
 		err = json.NewDecoder(resp.Body).Decode(&leaderboard)
 		assert.NoError(t, err)
 		assert.Equal(t, models.GameID("soccer"), leaderboard.GameID)
 		assert.Equal(t, models.AttributeName("elo"), leaderboard.AttributeName)
-		assert.Equal(t, 5, len(leaderboard.UserIDs))
-		assert.Contains(t, leaderboard.UserIDs, models.UserID("user2"))
-		assert.Contains(t, leaderboard.UserIDs, models.UserID("user1"))
-		assert.Contains(t, leaderboard.UserIDs, models.UserID("user3"))
-		assert.Contains(t, leaderboard.UserIDs, models.UserID("dianadancer"))
-		assert.Contains(t, leaderboard.UserIDs, models.UserID("eveexplorer"))
+		assert.Equal(t, 100, leaderboard.Limit)
+		assert.Empty(t, leaderboard.NextCursor)
+		assert.Equal(t, 5, len(leaderboard.UserIDs()))
+		assert.Contains(t, leaderboard.UserIDs(), models.UserID("user2"))
+		assert.Contains(t, leaderboard.UserIDs(), models.UserID("user1"))
+		assert.Contains(t, leaderboard.UserIDs(), models.UserID("user3"))
+		assert.Contains(t, leaderboard.UserIDs(), models.UserID("dianadancer"))
+		assert.Contains(t, leaderboard.UserIDs(), models.UserID("eveexplorer"))
 	})
 
 	// Test GetBoundedLeaderboard
@@ -79,8 +115,35 @@ func TestGameAPI(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, models.GameID("soccer"), leaderboard.GameID)
 		assert.Equal(t, models.AttributeName("elo"), leaderboard.AttributeName)
-		assert.Equal(t, 3, len(leaderboard.UserIDs))
+		assert.Equal(t, 3, len(leaderboard.UserIDs()))
 		assert.Equal(t, 3, leaderboard.Limit)
+		assert.NotEmpty(t, leaderboard.NextCursor)
+
+		// Follow the cursor to the rest of the leaderboard
+		resp, err = http.Get(fmt.Sprintf("%s/games/soccer/leaderboard/elo?limit=3&cursor=%s", baseURL, leaderboard.NextCursor))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var nextPage models.BoundedLeaderboard
+		err = json.NewDecoder(resp.Body).Decode(&nextPage)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(nextPage.UserIDs()))
+		assert.Equal(t, 4, nextPage.Entries[0].Rank)
+		assert.Empty(t, nextPage.NextCursor)
+	})
+
+	// Test GetLeaderboardAroundUser
+	t.Run("GetLeaderboardAroundUser", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("%s/games/soccer/leaderboard/elo/users/user3?window=1", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var leaderboard models.LeaderboardAroundUser
+		err = json.NewDecoder(resp.Body).Decode(&leaderboard)
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserID("user3"), leaderboard.UserID)
+		assert.Equal(t, 3, leaderboard.Rank)
+		assert.Equal(t, 3, len(leaderboard.UserIDs()))
 	})
 
 	// Test CreateGame
@@ -142,16 +205,55 @@ func TestGameAPI(t *testing.T) {
 		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/games/deletegame", baseURL), nil)
 		resp, err := http.DefaultClient.Do(req)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		var deletingGame models.Game
+		err = json.NewDecoder(resp.Body).Decode(&deletingGame)
+		assert.NoError(t, err)
+		assert.Equal(t, models.GameStatusDeleting, deletingGame.Status)
+
+		// The game is read-only until the background job deletes it
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("%s/games/deletegame/archive", baseURL), nil)
+		resp, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// Run the job in place of the scheduled one
+		deleted, err := gameDeletionService.DeletePendingGames(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Contains(t, deleted, newGame.GameID)
 
 		// Verify game is deleted
 		resp, err = http.Get(fmt.Sprintf("%s/games/deletegame", baseURL))
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	// Test archiving a game
+	t.Run("ArchiveGame", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/games/soccer/archive", baseURL), nil)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var archivedGame models.Game
+		err = json.NewDecoder(resp.Body).Decode(&archivedGame)
+		assert.NoError(t, err)
+		assert.Equal(t, models.GameStatusArchived, archivedGame.Status)
 
-		// Clean up: Delete any remaining leaderboard items
-		err = leaderboardRepo.DeleteLeaderboardItemsByGame(newGame.GameID, nil)
+		// Archived games are read-only
+		jsonGame, _ := json.Marshal(archivedGame)
+		req, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("%s/games/soccer", baseURL), bytes.NewBuffer(jsonGame))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = http.DefaultClient.Do(req)
 		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// Unarchiving makes it writable again
+		req, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("%s/games/soccer/unarchive", baseURL), nil)
+		resp, err = http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
 
 	// Scan the entire table after tests
@@ -27,9 +27,13 @@ func TestMatchAPI(t *testing.T) {
 
 	matchRepo := repositories.NewDynamoDBMatchRepository(db, cfg.TableName)
 	gameRepo := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
+	userRepo := repositories.NewDynamoDBUserRepository(db, cfg.TableName)
 	gameStatRepo := repositories.NewDynamoDBGameStatRepository(db, cfg.TableName)
 	leaderboardRepo := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
-	matchService := services.NewMatchServiceImpl(matchRepo, gameRepo, gameStatRepo, leaderboardRepo)
+	seasonRepo := repositories.NewDynamoDBSeasonRepository(db, cfg.TableName)
+	ratingRepo := repositories.NewDynamoDBRatingRepository(db, cfg.TableName)
+	transactionRepo := repositories.NewDynamoDBTransactionRepository(db)
+	matchService := services.NewMatchServiceImpl(matchRepo, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
 
 	// Scan the entire table before tests
 	beforeScan, err := utils.ScanEntireTable(db, cfg.TableName)
@@ -67,6 +71,28 @@ func TestMatchAPI(t *testing.T) {
 		assert.Equal(t, models.MatchID("match1"), matches[0].MatchID)
 	})
 
+	// Test GetMatchesByGameAndDateRange
+	t.Run("GetMatchesByGameAndDateRange", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("%s/matches?gameId=soccer&from=2023-06-01&to=2023-06-30&limit=1", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page models.MatchPage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Matches))
+		assert.Equal(t, models.MatchID("match1"), page.Matches[0].MatchID)
+		assert.Equal(t, 1, page.Limit)
+
+		resp, err = http.Get(fmt.Sprintf("%s/matches?gameId=soccer&from=2023-06-30&to=2023-06-01", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = http.Get(fmt.Sprintf("%s/matches?gameId=soccer&from=2023-06-01&to=2023-06-30&cursor=invalid", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
 	// Test CreateMatch
 	t.Run("CreateMatch", func(t *testing.T) {
 		newMatch, err := models.NewMatch(
@@ -187,7 +213,7 @@ func TestMatchAPI(t *testing.T) {
 		// Verify match is deleted
 		resp, err = http.Get(fmt.Sprintf("%s/matches/soccer/deletematch/2023-06-14", baseURL))
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 	})
 
 	// Scan the entire table after tests
@@ -73,6 +73,47 @@ func TestUserAPI(t *testing.T) {
 		assert.Equal(t, models.AttributeStat(1), gameStat.GameAttributes["goals"])
 	})
 
+	// Test GetGameStats
+	t.Run("GetGameStats", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("%s/users/user1/stats?attributes=goals,elo", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var gameStats []models.GameStat
+		err = json.NewDecoder(resp.Body).Decode(&gameStats)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(gameStats))
+		assert.Equal(t, models.GameID("soccer"), gameStats[2].GameID)
+		assert.Equal(t, models.AttributesStatsMap{"elo": 2, "goals": 1}, gameStats[2].GameAttributes)
+
+		resp, err = http.Get(fmt.Sprintf("%s/users/user1/stats?attributes=goals,", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	// Test GetUserMatches
+	t.Run("GetUserMatches", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("%s/users/user1/matches?limit=2", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var history models.UserMatchHistory
+		err = json.NewDecoder(resp.Body).Decode(&history)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(history.Matches))
+		assert.Equal(t, models.MatchID("match3"), history.Matches[0].MatchID)
+		assert.NotEmpty(t, history.NextCursor)
+
+		resp, err = http.Get(fmt.Sprintf("%s/users/user1/matches?gameId=soccer&from=2023-06-01&to=2023-06-30", baseURL))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		err = json.NewDecoder(resp.Body).Decode(&history)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(history.Matches))
+		assert.Equal(t, models.MatchID("match1"), history.Matches[0].MatchID)
+	})
+
 	// Test CreateUser
 	t.Run("CreateUser", func(t *testing.T) {
 		newUser, err := models.NewUser("user6", "TestUser", "test@example.com", []models.GameID{})
@@ -150,7 +191,7 @@ func TestUserAPI(t *testing.T) {
 		// Verify user is deleted
 		resp, err = http.Get(fmt.Sprintf("%s/users/tempuser", baseURL))
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 	})
 
 	// Scan the entire table after tests
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mquan1409/game-api/internal/handlers"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories/inmemory"
+	"github.com/mquan1409/game-api/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+type errorBody struct {
+	Code     string   `json:"Code"`
+	Message  string   `json:"Message"`
+	Problems []string `json:"Problems"`
+}
+
+func decodeErrorBody(t *testing.T, response events.APIGatewayProxyResponse) errorBody {
+	var body errorBody
+	assert.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+	return body
+}
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"NotFound", models.NewNotFoundError("game %s not found", "chess"), http.StatusNotFound, "not_found"},
+		{"Conflict", models.NewConflictError("season %s already exists", "2024"), http.StatusConflict, "conflict"},
+		{"Validation", models.NewValidationError("limit must be a positive integer"), http.StatusBadRequest, "validation"},
+		{"Forbidden", models.NewForbiddenError("season %s is closed", "2024"), http.StatusForbidden, "forbidden"},
+		{"PreconditionFailed", models.NewPreconditionFailedError("game %s is at version %d, not %d", "chess", 3, 2), http.StatusPreconditionFailed, "precondition_failed"},
+		{"Wrapped", fmt.Errorf("failed to read game: %w", models.NewNotFoundError("game chess not found")), http.StatusNotFound, "not_found"},
+		{"Internal", errors.New("connection reset"), http.StatusInternalServerError, "internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := handlers.ErrorResponse(tt.err)
+			assert.Equal(t, tt.status, response.StatusCode)
+			assert.Equal(t, "application/json", response.Headers["Content-Type"])
+
+			body := decodeErrorBody(t, response)
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.err.Error(), body.Message)
+		})
+	}
+}
+
+func TestHandlerErrorStatuses(t *testing.T) {
+	// Setup
+	store := inmemory.NewSeededStore()
+
+	gameRepo := inmemory.NewInMemoryGameRepository(store)
+	leaderboardRepo := inmemory.NewInMemoryLeaderboardRepository(store)
+	userRepo := inmemory.NewInMemoryUserRepository(store)
+	gameStatRepo := inmemory.NewInMemoryGameStatRepository(store)
+	matchRepo := inmemory.NewInMemoryMatchRepository(store)
+	seasonRepo := inmemory.NewInMemorySeasonRepository(store)
+	ratingRepo := inmemory.NewInMemoryRatingRepository(store)
+	transactionRepo := inmemory.NewInMemoryTransactionRepository(store)
+	gameHandler := handlers.NewGameHandlerImpl(services.NewGameServiceImpl(gameRepo, leaderboardRepo, userRepo, gameStatRepo, matchRepo, ratingRepo, transactionRepo))
+	matchHandler := handlers.NewMatchHandlerImpl(services.NewMatchServiceImpl(matchRepo, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo))
+	userHandler := handlers.NewUserHandlerImpl(services.NewUserServiceImpl(userRepo, gameStatRepo, matchRepo, gameRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo))
+	seasonHandler := handlers.NewSeasonHandlerImpl(services.NewSeasonServiceImpl(seasonRepo, gameRepo, gameStatRepo, leaderboardRepo, matchRepo))
+
+	t.Run("NotFound", func(t *testing.T) {
+		response, err := gameHandler.GetGame(events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"gameId": "chess"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		assert.Equal(t, "not_found", decodeErrorBody(t, response).Code)
+
+		response, err = userHandler.GetUser(events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"userId": "nobody"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+		response, err = matchHandler.GetMatch(events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"gameId": "soccer", "matchId": "nomatch", "dateId": "2023-06-01"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		// models.NewMatch rejects a match without teams
+		response, err := matchHandler.CreateMatch(events.APIGatewayProxyRequest{
+			Body: `{"MatchID": "badmatch", "DateID": "2023-06-01", "GameID": "soccer"}`,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		assert.Equal(t, "validation", decodeErrorBody(t, response).Code)
+
+		// Every problem of a match that doesn't fit its game is listed
+		response, err = matchHandler.CreateMatch(events.APIGatewayProxyRequest{
+			Body: `{"MatchID": "badmatch", "DateID": "2023-06-01", "GameID": "soccer", "TeamNames": ["Team A"], "TeamScores": [1, 0], "TeamMembers": [["user1"], ["nobody"]], "PlayerAttributesMap": {"user1": {"touchdowns": 1}}}`,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		assert.Equal(t, []string{
+			"1 team names for 2 team scores",
+			"attribute touchdowns of player user1 is not an attribute of game soccer",
+			"user nobody does not exist",
+		}, decodeErrorBody(t, response).Problems)
+
+		response, err = userHandler.CreateUser(events.APIGatewayProxyRequest{Body: "not json"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+		response, err = gameHandler.GetBoundedLeaderboard(events.APIGatewayProxyRequest{
+			PathParameters:        map[string]string{"gameId": "soccer", "attribute": "elo"},
+			QueryStringParameters: map[string]string{"cursor": "not-a-cursor"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+		response, err = gameHandler.GetBoundedLeaderboard(events.APIGatewayProxyRequest{
+			PathParameters:        map[string]string{"gameId": "soccer", "attribute": "elo"},
+			QueryStringParameters: map[string]string{"period": "decade"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	t.Run("ConflictAndForbidden", func(t *testing.T) {
+		request := events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"gameId": "soccer"},
+			Body:           `{"SeasonID": "2030", "Name": "2030", "StartDate": "2030-01-01", "EndDate": "2030-12-31"}`,
+		}
+		response, err := seasonHandler.CreateSeason(request)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+
+		response, err = seasonHandler.CreateSeason(request)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, response.StatusCode)
+		assert.Equal(t, "conflict", decodeErrorBody(t, response).Code)
+
+		// Creating an existing user conflicts, and updating a missing one
+		// doesn't create it
+		response, err = userHandler.CreateUser(events.APIGatewayProxyRequest{
+			Body: `{"UserID": "user1", "Username": "Impostor", "Email": "impostor@example.com"}`,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, response.StatusCode)
+
+		response, err = userHandler.UpdateUser(events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"userId": "nobody"},
+			Body:           `{"Username": "Nobody", "Email": "nobody@example.com"}`,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+		seasonPath := map[string]string{"gameId": "soccer", "seasonId": "2030"}
+		response, err = seasonHandler.CloseSeason(events.APIGatewayProxyRequest{PathParameters: seasonPath})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+
+		response, err = seasonHandler.UpdateSeason(events.APIGatewayProxyRequest{
+			PathParameters: seasonPath,
+			Body:           `{"Name": "Renamed", "StartDate": "2030-01-01", "EndDate": "2030-12-31"}`,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		assert.Equal(t, "forbidden", decodeErrorBody(t, response).Code)
+
+		response, err = seasonHandler.DeleteSeason(events.APIGatewayProxyRequest{PathParameters: seasonPath})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	})
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mquan1409/game-api/internal/handlers"
+	"github.com/mquan1409/game-api/internal/repositories/inmemory"
+	"github.com/mquan1409/game-api/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagAndIfMatch(t *testing.T) {
+	// Setup
+	store := inmemory.NewSeededStore()
+
+	gameRepo := inmemory.NewInMemoryGameRepository(store)
+	leaderboardRepo := inmemory.NewInMemoryLeaderboardRepository(store)
+	userRepo := inmemory.NewInMemoryUserRepository(store)
+	gameStatRepo := inmemory.NewInMemoryGameStatRepository(store)
+	matchRepo := inmemory.NewInMemoryMatchRepository(store)
+	seasonRepo := inmemory.NewInMemorySeasonRepository(store)
+	ratingRepo := inmemory.NewInMemoryRatingRepository(store)
+	transactionRepo := inmemory.NewInMemoryTransactionRepository(store)
+	gameHandler := handlers.NewGameHandlerImpl(services.NewGameServiceImpl(gameRepo, leaderboardRepo, userRepo, gameStatRepo, matchRepo, ratingRepo, transactionRepo))
+	userHandler := handlers.NewUserHandlerImpl(services.NewUserServiceImpl(userRepo, gameStatRepo, matchRepo, gameRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo))
+
+	gamePath := map[string]string{"gameId": "pool"}
+	body := `{"Description": "Eight-ball", "Attributes": ["elo", "banks", "pockets", "breaks", "safeties"], "RankedAttributes": ["elo"]}`
+
+	t.Run("GetReturnsETag", func(t *testing.T) {
+		response, err := gameHandler.GetGame(events.APIGatewayProxyRequest{PathParameters: gamePath})
+		assert.NoError(t, err)
+		assert.Equal(t, `"1"`, response.Headers["ETag"])
+		assert.Equal(t, "ETag", response.Headers["Access-Control-Expose-Headers"])
+
+		response, err = userHandler.GetGameStat(events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"userId": "user1", "gameId": "pool"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `"1"`, response.Headers["ETag"])
+	})
+
+	t.Run("PutHonoursIfMatch", func(t *testing.T) {
+		response, err := gameHandler.UpdateGame(events.APIGatewayProxyRequest{
+			PathParameters: gamePath,
+			Headers:        map[string]string{"If-Match": `"1"`},
+			Body:           body,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, `"2"`, response.Headers["ETag"])
+
+		// The same precondition no longer holds, whatever the header's case
+		response, err = gameHandler.UpdateGame(events.APIGatewayProxyRequest{
+			PathParameters: gamePath,
+			Headers:        map[string]string{"if-match": `"1"`},
+			Body:           body,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+		assert.Equal(t, "precondition_failed", decodeErrorBody(t, response).Code)
+
+		// Without If-Match, or with *, the update applies to any version
+		response, err = gameHandler.UpdateGame(events.APIGatewayProxyRequest{
+			PathParameters: gamePath,
+			Headers:        map[string]string{"If-Match": "*"},
+			Body:           body,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, `"3"`, response.Headers["ETag"])
+	})
+
+	t.Run("InvalidIfMatch", func(t *testing.T) {
+		response, err := userHandler.UpdateUser(events.APIGatewayProxyRequest{
+			PathParameters: map[string]string{"userId": "user1"},
+			Headers:        map[string]string{"If-Match": "yesterday"},
+			Body:           `{"Username": "AliceWonder", "Email": "alice.wonder@example.com"}`,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// component generates non-empty IDs made mostly of the characters keys escape
+// and of text that looks like an escape, so collisions are likely if escaping
+// is wrong.
+type component string
+
+const componentAlphabet = "ab.#%2E35_-"
+
+func (component) Generate(rand *rand.Rand, size int) reflect.Value {
+	if size < 1 {
+		size = 1
+	}
+	b := make([]byte, 1+rand.Intn(size))
+	for i := range b {
+		b[i] = componentAlphabet[rand.Intn(len(componentAlphabet))]
+	}
+	return reflect.ValueOf(component(b))
+}
+
+func TestEscape(t *testing.T) {
+	// Test IDs without delimiters keep the keys they had before escaping
+	t.Run("PlainIDsUnchanged", func(t *testing.T) {
+		assert.Equal(t, "user1", keys.Escape("user1"))
+		assert.Equal(t, "USER_INFO-prefix:u", keys.UserPartition("user1"))
+		assert.Equal(t, "MATCH_INFO.soccer", keys.MatchPartition("soccer"))
+		assert.Equal(t, "2024-06-03.match1", keys.MatchRange("2024-06-03", "match1"))
+		assert.Equal(t, "2024-06-03.soccer.match1", keys.UserMatchRange("2024-06-03", "soccer", "match1"))
+		assert.Equal(t, "GameStat.user1", keys.GameStatPartition("user1"))
+		assert.Equal(t, "ScopedGameStat.soccer#day.2024-06-03", keys.ScopedGameStatPartition("soccer", models.LeaderboardScope("day.2024-06-03")))
+		assert.Equal(t, "soccer#day.2024-06-03", keys.ScopedGameKey("soccer", models.LeaderboardScope("day.2024-06-03")))
+		assert.Equal(t, "Leaderboard.soccer", keys.LeaderboardPartition("soccer", models.AllTimeScope))
+	})
+
+	// Test delimiters are escaped
+	t.Run("EscapesDelimiters", func(t *testing.T) {
+		assert.Equal(t, "a%2Eb%23c%25d", keys.Escape("a.b#c%d"))
+		assert.Equal(t, "2024-06-03.m%2E1", keys.MatchRange("2024-06-03", "m.1"))
+		assert.Equal(t, "GameStat.a%2Eb", keys.GameStatPartition("a.b"))
+		assert.Equal(t, "ScopedGameStat.a%23b#season.s1", keys.ScopedGameStatPartition("a#b", models.SeasonScope("s1")))
+		assert.Equal(t, "a%23b#day.2024-06-03", keys.ScopedGameKey("a#b", models.LeaderboardScope("day.2024-06-03")))
+	})
+
+	// Test invalid escapes are rejected
+	t.Run("RejectsInvalidEscapes", func(t *testing.T) {
+		for _, escaped := range []string{"%", "a%2", "%41", "%2e", "%zz"} {
+			_, err := keys.Unescape(escaped)
+			assert.Error(t, err, "escape %q", escaped)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		property := func(s string) bool {
+			unescaped, err := keys.Unescape(keys.Escape(s))
+			return err == nil && unescaped == s
+		}
+		assert.NoError(t, quick.Check(property, nil))
+		assert.NoError(t, quick.Check(func(c component) bool { return property(string(c)) }, nil))
+	})
+
+	t.Run("EscapedHasNoDelimiters", func(t *testing.T) {
+		property := func(c component) bool {
+			escaped := keys.Escape(string(c))
+			return !strings.Contains(escaped, keys.Separator) && !strings.Contains(escaped, keys.ScopeSeparator)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	// Test prefix searches over escaped IDs find the same IDs as over raw ones
+	t.Run("PreservesPrefixes", func(t *testing.T) {
+		property := func(s, prefix component) bool {
+			return strings.HasPrefix(keys.Escape(string(s)), keys.Escape(string(prefix))) == strings.HasPrefix(string(s), string(prefix))
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("JoinSplitRoundTrip", func(t *testing.T) {
+		property := func(components []component) bool {
+			parts := make([]string, len(components))
+			for i, c := range components {
+				parts[i] = string(c)
+			}
+			split, err := keys.Split(keys.Join(parts...))
+			if len(parts) == 0 {
+				return err == nil && reflect.DeepEqual(split, []string{""})
+			}
+			return err == nil && reflect.DeepEqual(split, parts)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+}
+
+func TestKeysRoundTrip(t *testing.T) {
+	t.Run("User", func(t *testing.T) {
+		property := func(c component) bool {
+			userID, err := keys.ParseUserRange(keys.UserRange(models.UserID(c)))
+			return err == nil && userID == models.UserID(c) && strings.HasPrefix(keys.UserPartition(models.UserID(c)), "USER_INFO-prefix:")
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	// Test users sharing a prefix share a partition
+	t.Run("UserPrefix", func(t *testing.T) {
+		property := func(prefix, rest component) bool {
+			userID := models.UserID(prefix + rest)
+			return keys.UserPartition(userID) == keys.UserPartition(models.UserID(prefix)) &&
+				strings.HasPrefix(keys.UserRange(userID), keys.UserRange(models.UserID(prefix)))
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("Game", func(t *testing.T) {
+		property := func(c component) bool {
+			gameID, err := keys.ParseGameRange(keys.GameRange(models.GameID(c)))
+			return err == nil && gameID == models.GameID(c)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		property := func(game, date, match component) bool {
+			gameID, dateID, matchID, err := keys.ParseMatchKey(keys.MatchPartition(models.GameID(game)), keys.MatchRange(models.DateID(date), models.MatchID(match)))
+			return err == nil && gameID == models.GameID(game) && dateID == models.DateID(date) && matchID == models.MatchID(match)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+
+		_, _, _, err := keys.ParseMatchKey("MATCH_INFO.soccer", "2024-06-03.a.b")
+		assert.Error(t, err)
+		_, _, _, err = keys.ParseMatchKey("GAME_INFO", "2024-06-03.match1")
+		assert.Error(t, err)
+	})
+
+	// Test a date's bounds hold its matches and no other date's
+	t.Run("DateBounds", func(t *testing.T) {
+		property := func(date, other, match component) bool {
+			rangeKey := keys.MatchRange(models.DateID(date), models.MatchID(match))
+			inBounds := rangeKey >= keys.DateRangeStart(models.DateID(date)) && rangeKey <= keys.DateRangeEnd(models.DateID(date))
+			prefixed := strings.HasPrefix(rangeKey, keys.DatePrefix(models.DateID(other)))
+			return inBounds && prefixed == (date == other)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("UserMatch", func(t *testing.T) {
+		property := func(date, game, match component) bool {
+			dateID, gameID, matchID, err := keys.ParseUserMatchRange(keys.UserMatchRange(models.DateID(date), models.GameID(game), models.MatchID(match)))
+			return err == nil && dateID == models.DateID(date) && gameID == models.GameID(game) && matchID == models.MatchID(match)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("MatchFollowUp", func(t *testing.T) {
+		property := func(game, date component) bool {
+			gameID, dateID, err := keys.ParseMatchFollowUpRange(keys.MatchFollowUpRange(models.GameID(game), models.DateID(date)))
+			return err == nil && gameID == models.GameID(game) && dateID == models.DateID(date)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("GameStat", func(t *testing.T) {
+		property := func(user, game component) bool {
+			userID, err := keys.ParseGameStatPartition(keys.GameStatPartition(models.UserID(user)))
+			if err != nil || userID != models.UserID(user) {
+				return false
+			}
+			for _, scope := range []models.LeaderboardScope{models.AllTimeScope, models.LeaderboardScope("day.2024-06-03"), models.SeasonScope(models.SeasonID(game))} {
+				gameID, parsedScope, err := keys.ParseScopedGameKey(keys.ScopedGameKey(models.GameID(game), scope))
+				if err != nil || gameID != models.GameID(game) || parsedScope != scope {
+					return false
+				}
+				userID, gameID, parsedScope, err = keys.ParseGameStatKey(keys.GameStatKey(models.UserID(user), models.GameID(game), scope))
+				if err != nil || userID != models.UserID(user) || gameID != models.GameID(game) || parsedScope != scope {
+					return false
+				}
+			}
+			return true
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	t.Run("SeasonAndRating", func(t *testing.T) {
+		property := func(c component) bool {
+			seasonID, seasonErr := keys.ParseSeasonRange(keys.SeasonRange(models.SeasonID(c)))
+			userID, ratingErr := keys.ParseRatingRange(keys.RatingRange(models.UserID(c)))
+			return seasonErr == nil && seasonID == models.SeasonID(c) && ratingErr == nil && userID == models.UserID(c)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/mquan1409/game-api/internal/keys"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboardKeys(t *testing.T) {
+	values := []models.AttributeStat{math.MinInt64, -100000, -1, 0, 1, 2, 99999, 100000, 1234567, math.MaxInt64}
+
+	// Test encoded values sort in numeric order
+	t.Run("EncodingPreservesOrder", func(t *testing.T) {
+		encoded := make([]string, len(values))
+		for i, value := range values {
+			encoded[i] = keys.EncodeLeaderboardValue(value)
+		}
+		assert.True(t, sort.StringsAreSorted(encoded))
+
+		property := func(a, b int64) bool {
+			encodedA := keys.EncodeLeaderboardValue(models.AttributeStat(a))
+			encodedB := keys.EncodeLeaderboardValue(models.AttributeStat(b))
+			return (a < b) == (encodedA < encodedB)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	// Test values round-trip through the Range key
+	t.Run("RangeKeyRoundTrip", func(t *testing.T) {
+		for _, value := range values {
+			rangeKey := keys.LeaderboardRange("elo", value, "user1")
+			attr, decoded, userID, err := keys.ParseLeaderboardRange(rangeKey)
+			assert.NoError(t, err)
+			assert.Equal(t, models.AttributeName("elo"), attr)
+			assert.Equal(t, value, decoded)
+			assert.Equal(t, models.UserID("user1"), userID)
+		}
+
+		property := func(attr, userID component, value int64) bool {
+			parsedAttr, parsedValue, parsedUserID, err := keys.ParseLeaderboardRange(keys.LeaderboardRange(models.AttributeName(attr), models.AttributeStat(value), models.UserID(userID)))
+			return err == nil && parsedAttr == models.AttributeName(attr) && parsedValue == models.AttributeStat(value) && parsedUserID == models.UserID(userID)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+
+	// Test legacy keys are not mistaken for the new format
+	t.Run("RejectsLegacyKey", func(t *testing.T) {
+		_, _, _, err := keys.ParseLeaderboardRange("elo.00002.user1")
+		assert.Error(t, err)
+
+		attr, value, err := keys.ParseLegacyLeaderboardRange("elo.00002.user1", "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeName("elo"), attr)
+		assert.Equal(t, models.AttributeStat(2), value)
+	})
+
+	// Test an attribute's bounds hold its items and no other attribute's
+	t.Run("AttributeBounds", func(t *testing.T) {
+		rangeKey := keys.LeaderboardRange("a.b", 5, "user1")
+		assert.False(t, strings.HasPrefix(rangeKey, keys.LeaderboardAttributePrefix("a")))
+
+		property := func(attr, other component, value int64, userID component) bool {
+			rangeKey := keys.LeaderboardRange(models.AttributeName(attr), models.AttributeStat(value), models.UserID(userID))
+			inBounds := rangeKey >= keys.LeaderboardAttributePrefix(models.AttributeName(attr)) && rangeKey < keys.LeaderboardAttributeEnd(models.AttributeName(attr))
+			prefixed := strings.HasPrefix(rangeKey, keys.LeaderboardAttributePrefix(models.AttributeName(other)))
+			return inBounds && prefixed == (attr == other)
+		}
+		assert.NoError(t, quick.Check(property, nil))
+	})
+}
@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregationMode(t *testing.T) {
+	t.Run("Aggregate", func(t *testing.T) {
+		values := []models.AttributeStat{3, -7, 10, 4}
+		cases := []struct {
+			mode     models.AggregationMode
+			expected models.AttributeStat
+		}{
+			{models.AggregationSum, 10},
+			{models.AggregationMax, 10},
+			{models.AggregationMin, -7},
+			{models.AggregationLast, 4},
+			{models.AggregationAvg, 2},
+		}
+		for _, c := range cases {
+			assert.Equal(t, c.expected, c.mode.Aggregate(values), "mode %s", c.mode)
+			assert.Equal(t, models.AttributeStat(0), c.mode.Aggregate(nil), "mode %s", c.mode)
+		}
+		// Averages round toward zero
+		assert.Equal(t, models.AttributeStat(-1), models.AggregationAvg.Aggregate([]models.AttributeStat{-1, -2}))
+	})
+
+	t.Run("GameAggregation", func(t *testing.T) {
+		game, err := models.NewGame("racing", "Racing", []models.AttributeName{"laps", "best_lap"}, nil)
+		assert.NoError(t, err)
+		game.Aggregations = map[models.AttributeName]models.AggregationMode{"best_lap": models.AggregationMin}
+		assert.NoError(t, game.ValidateAggregations())
+		assert.Equal(t, models.AggregationSum, game.Aggregation("laps"))
+		assert.Equal(t, models.AggregationMin, game.Aggregation("best_lap"))
+		assert.False(t, game.Aggregation("best_lap").Invertible())
+
+		game.Aggregations["laps"] = "median"
+		assert.ErrorIs(t, game.ValidateAggregations(), models.ErrValidation)
+		game.Aggregations = map[models.AttributeName]models.AggregationMode{"pit_stops": models.AggregationMax}
+		assert.ErrorIs(t, game.ValidateAggregations(), models.ErrValidation)
+	})
+}
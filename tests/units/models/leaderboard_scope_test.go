@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboardScope(t *testing.T) {
+	t.Run("NewPeriodScope", func(t *testing.T) {
+		cases := []struct {
+			period   models.Period
+			date     models.DateID
+			expected models.LeaderboardScope
+		}{
+			{models.PeriodDay, "2024-06-03", "day.2024-06-03"},
+			{models.PeriodWeek, "2024-06-03", "week.2024-W23"},
+			{models.PeriodWeek, "2024-06-09", "week.2024-W23"},
+			{models.PeriodWeek, "2024-06-10", "week.2024-W24"},
+			// ISO weeks can belong to the neighbouring year
+			{models.PeriodWeek, "2024-12-30", "week.2025-W01"},
+			{models.PeriodWeek, "2021-01-03", "week.2020-W53"},
+			{models.PeriodMonth, "2024-06-30", "month.2024-06"},
+		}
+		for _, c := range cases {
+			scope, err := models.NewPeriodScope(c.period, c.date)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, scope)
+		}
+	})
+
+	t.Run("InvalidInput", func(t *testing.T) {
+		_, err := models.NewPeriodScope(models.PeriodDay, "2024-02-30")
+		assert.Error(t, err)
+		_, err = models.NewPeriodScope(models.PeriodDay, "20240603")
+		assert.Error(t, err)
+		_, err = models.NewPeriodScope("year", "2024-06-03")
+		assert.Error(t, err)
+		_, err = models.ParsePeriod("fortnight")
+		assert.Error(t, err)
+	})
+
+	t.Run("PeriodScopes", func(t *testing.T) {
+		scopes, err := models.PeriodScopes("2024-06-03")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.LeaderboardScope{"day.2024-06-03", "week.2024-W23", "month.2024-06"}, scopes)
+	})
+
+	t.Run("PeriodDates", func(t *testing.T) {
+		cases := []struct {
+			period      models.Period
+			date        models.DateID
+			first, last models.DateID
+		}{
+			{models.PeriodDay, "2024-06-03", "2024-06-03", "2024-06-03"},
+			{models.PeriodWeek, "2024-06-05", "2024-06-03", "2024-06-09"},
+			{models.PeriodWeek, "2024-06-09", "2024-06-03", "2024-06-09"},
+			// Weeks can span months and years
+			{models.PeriodWeek, "2025-01-01", "2024-12-30", "2025-01-05"},
+			{models.PeriodMonth, "2024-02-15", "2024-02-01", "2024-02-29"},
+		}
+		for _, c := range cases {
+			first, last, err := models.PeriodDates(c.period, c.date)
+			assert.NoError(t, err)
+			assert.Equal(t, c.first, first)
+			assert.Equal(t, c.last, last)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMatchDateID(t *testing.T) {
+	newMatch := func(dateID models.DateID) (*models.Match, error) {
+		return models.NewMatch("match", dateID, "soccer", []string{"Team A"}, []int{1}, [][]string{{"user1"}}, nil)
+	}
+
+	for _, dateID := range []models.DateID{"2024-06-03", "2024-02-29", "2023-12-31"} {
+		match, err := newMatch(dateID)
+		assert.NoError(t, err)
+		assert.Equal(t, dateID, match.DateID)
+	}
+
+	// Only real calendar dates written exactly as YYYY-MM-DD are accepted
+	for _, dateID := range []models.DateID{"", "June 3rd", "2023-02-29", "2024-06-31", "2024-13-01", "2024-6-3", "20240603", "2024-06-03.x", " 2024-06-03", "2024-06-03T00:00:00Z"} {
+		_, err := newMatch(dateID)
+		assert.ErrorIs(t, err, models.ErrValidation, "date %q", dateID)
+	}
+
+	// A match decoded from a request skips NewMatch, so Problems checks it too
+	match := &models.Match{
+		MatchID: "match", DateID: "June 3rd", GameID: "soccer",
+		TeamNames: []string{"Team A"}, TeamScores: []int{1}, TeamMembers: [][]string{{"user1"}},
+	}
+	assert.Equal(t, []string{`invalid date "June 3rd": must be a YYYY-MM-DD calendar date`}, match.Problems(&models.Game{GameID: "soccer"}))
+}
+
+func TestMatchAnonymize(t *testing.T) {
+	match := &models.Match{
+		MatchID: "match", DateID: "2024-06-03", GameID: "soccer",
+		TeamNames:   []string{"Team A", "Team B"},
+		TeamScores:  []int{1, 0},
+		TeamMembers: [][]string{{"user1", "user2"}, {"user3", "user4"}},
+		PlayerAttributesMap: map[models.UserID]models.AttributesStatsMap{
+			"user1": {"goals": 1},
+			"user3": {"goals": 0},
+		},
+	}
+
+	assert.True(t, match.Anonymize("user3"))
+	assert.True(t, match.Anonymize("user2"))
+	assert.False(t, match.Anonymize("user5"))
+	assert.Equal(t, [][]string{{"user1", "~deleted-2"}, {"~deleted-1", "user4"}}, match.TeamMembers)
+	assert.Equal(t, map[models.UserID]models.AttributesStatsMap{"user1": {"goals": 1}}, match.PlayerAttributesMap)
+	assert.True(t, models.IsAnonymousPlayer("~deleted-1"))
+	assert.False(t, models.IsAnonymousPlayer("user1"))
+
+	// Anonymous players have no match history
+	var players []models.UserID
+	for _, userMatch := range match.UserMatches() {
+		players = append(players, userMatch.UserID)
+	}
+	assert.Equal(t, []models.UserID{"user1", "user4"}, players)
+
+	// No user can take an anonymous player's ID
+	_, err := models.NewUser("~deleted-1", "Impostor", "impostor@example.com", nil)
+	assert.ErrorIs(t, err, models.ErrValidation)
+}
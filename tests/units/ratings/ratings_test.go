@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/ratings"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElo(t *testing.T) {
+	calculator, err := ratings.NewCalculator(models.RatingSystemElo)
+	assert.NoError(t, err)
+
+	t.Run("EvenMatch", func(t *testing.T) {
+		winner := calculator.NewRating("chess", "user1")
+		loser := calculator.NewRating("chess", "user2")
+		calculator.Rate([][]*models.Rating{{winner}, {loser}}, []int{0, 1})
+		assert.InDelta(t, 1516, winner.Mu, 0.001)
+		assert.InDelta(t, 1484, loser.Mu, 0.001)
+		assert.Equal(t, models.AttributeStat(1516), winner.Value)
+	})
+
+	t.Run("Draw", func(t *testing.T) {
+		stronger := &models.Rating{Mu: 1600}
+		weaker := &models.Rating{Mu: 1400}
+		calculator.Rate([][]*models.Rating{{stronger}, {weaker}}, []int{0, 0})
+		assert.InDelta(t, 1600-8.312, stronger.Mu, 0.001)
+		assert.InDelta(t, 1400+8.312, weaker.Mu, 0.001)
+	})
+
+	t.Run("Teams", func(t *testing.T) {
+		team1 := []*models.Rating{{Mu: 1400}, {Mu: 1600}}
+		team2 := []*models.Rating{{Mu: 1500}, {Mu: 1500}}
+		calculator.Rate([][]*models.Rating{team1, team2}, []int{1, 0})
+		// Both members of a team move by the same amount
+		assert.InDelta(t, 1384, team1[0].Mu, 0.001)
+		assert.InDelta(t, 1584, team1[1].Mu, 0.001)
+		assert.InDelta(t, 1516, team2[0].Mu, 0.001)
+	})
+}
+
+func TestGlicko2(t *testing.T) {
+	calculator, err := ratings.NewCalculator(models.RatingSystemGlicko2)
+	assert.NoError(t, err)
+
+	// The worked example of Glickman's Glicko-2 paper: the player beats the
+	// 1400 player and loses to the 1550 and 1700 players.
+	player := &models.Rating{Mu: 1500, Sigma: 200, Volatility: 0.06}
+	opponent1 := &models.Rating{Mu: 1400, Sigma: 30, Volatility: 0.06}
+	opponent2 := &models.Rating{Mu: 1550, Sigma: 100, Volatility: 0.06}
+	opponent3 := &models.Rating{Mu: 1700, Sigma: 300, Volatility: 0.06}
+	calculator.Rate([][]*models.Rating{{player}, {opponent1}, {opponent2}, {opponent3}}, []int{2, 3, 0, 0})
+
+	assert.InDelta(t, 1464.06, player.Mu, 0.01)
+	assert.InDelta(t, 151.52, player.Sigma, 0.01)
+	assert.InDelta(t, 0.05999, player.Volatility, 0.00001)
+	assert.Equal(t, models.AttributeStat(1464), player.Value)
+
+	newPlayer := calculator.NewRating("chess", "user1")
+	assert.Equal(t, 350.0, newPlayer.Sigma)
+}
+
+func TestTrueSkill(t *testing.T) {
+	calculator, err := ratings.NewCalculator(models.RatingSystemTrueSkill)
+	assert.NoError(t, err)
+
+	t.Run("Win", func(t *testing.T) {
+		// Matches the reference 1 vs 1 result of 29.396 ± 7.171 and
+		// 20.604 ± 7.171, scaled by 60
+		winner := calculator.NewRating("chess", "user1")
+		loser := calculator.NewRating("chess", "user2")
+		assert.Equal(t, models.AttributeStat(0), winner.Value)
+
+		calculator.Rate([][]*models.Rating{{winner}, {loser}}, []int{0, 1})
+		assert.InDelta(t, 29.396*60, winner.Mu, 0.1)
+		assert.InDelta(t, 7.171*60, winner.Sigma, 0.1)
+		assert.InDelta(t, 20.604*60, loser.Mu, 0.1)
+		assert.InDelta(t, 7.171*60, loser.Sigma, 0.1)
+	})
+
+	t.Run("Draw", func(t *testing.T) {
+		player1 := calculator.NewRating("chess", "user1")
+		player2 := calculator.NewRating("chess", "user2")
+		calculator.Rate([][]*models.Rating{{player1}, {player2}}, []int{0, 0})
+		assert.InDelta(t, 1500, player1.Mu, 0.001)
+		assert.InDelta(t, 6.458*60, player1.Sigma, 0.1)
+	})
+
+	t.Run("FreeForAll", func(t *testing.T) {
+		first := calculator.NewRating("chess", "user1")
+		second := calculator.NewRating("chess", "user2")
+		third := calculator.NewRating("chess", "user3")
+		calculator.Rate([][]*models.Rating{{third}, {first}, {second}}, []int{2, 0, 1})
+		assert.Greater(t, first.Mu, second.Mu)
+		assert.Greater(t, second.Mu, third.Mu)
+		assert.InDelta(t, 1500, second.Mu, 0.001)
+	})
+}
+
+func TestNewCalculator(t *testing.T) {
+	_, err := ratings.NewCalculator(models.RatingSystemNone)
+	assert.Error(t, err)
+}
@@ -41,16 +41,32 @@ func TestGameStatRepository(t *testing.T) {
 		assert.Equal(t, models.AttributeStat(20), gameStat.GameAttributes["passes_completed"])
 	})
 
+	// Test GetGameStatsByUser
+	t.Run("GetGameStatsByUser", func(t *testing.T) {
+		gameStats, err := repo.GetGameStatsByUser(models.UserID("user1"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(gameStats))
+		assert.Equal(t, models.GameID("pickleball"), gameStats[0].GameID)
+		assert.Equal(t, models.GameID("soccer"), gameStats[2].GameID)
+		assert.Equal(t, models.AttributeStat(20), gameStats[2].GameAttributes["passes_completed"])
+
+		gameStats, err = repo.GetGameStatsByUser(models.UserID("user1"), []models.AttributeName{"elo", "goals"})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(gameStats))
+		assert.Equal(t, models.AttributesStatsMap{"elo": 2, "goals": 1}, gameStats[2].GameAttributes)
+	})
+
 	// Test CreateGameStat
 	t.Run("CreateGameStat", func(t *testing.T) {
-		failedAttributes := models.AttributesStatsMap{
+		// Negative totals are allowed so they can be ranked
+		negativeAttributes := models.AttributesStatsMap{
 			"goals":             1,
 			"assists":           -1,
 			"shots_on_target":   0,
 			"passes_completed":  -1,
 		}
-		_, err := models.NewGameStat(models.UserID("newuser"), models.GameID("soccer"), failedAttributes)
-		assert.Error(t, err)
+		_, err := models.NewGameStat(models.UserID("newuser"), models.GameID("soccer"), negativeAttributes)
+		assert.NoError(t, err)
 
 		attributes := models.AttributesStatsMap{
 			"goals":             1,
@@ -124,6 +140,29 @@ func TestGameStatRepository(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	// Test IncrementGameStat adds deltas and sets values in one update
+	t.Run("IncrementGameStat", func(t *testing.T) {
+		err := repo.IncrementGameStat(models.UserID("incrementuser"), models.GameID("racing"), models.AttributesStatsMap{"laps": 10}, models.AttributesStatsMap{"best_lap": 95}, nil, nil)
+		assert.NoError(t, err)
+		gameStat, err := repo.GetGameStat(models.UserID("incrementuser"), models.GameID("racing"))
+		assert.NoError(t, err)
+
+		err = repo.IncrementGameStat(models.UserID("incrementuser"), models.GameID("racing"), models.AttributesStatsMap{"laps": 12}, models.AttributesStatsMap{"best_lap": 92}, gameStat, nil)
+		assert.NoError(t, err)
+		fetchedGameStat, err := repo.GetGameStat(models.UserID("incrementuser"), models.GameID("racing"))
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributesStatsMap{"laps": 22, "best_lap": 92}, fetchedGameStat.GameAttributes)
+		assert.Equal(t, 2, fetchedGameStat.Version)
+
+		// An increment based on a stale read fails
+		err = repo.IncrementGameStat(models.UserID("incrementuser"), models.GameID("racing"), nil, models.AttributesStatsMap{"best_lap": 90}, gameStat, nil)
+		assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+
+		// Clean up
+		err = repo.DeleteGameStat(models.UserID("incrementuser"), models.GameID("racing"), nil)
+		assert.NoError(t, err)
+	})
+
 	// Test CreateGameStat and UpdateGameStat in the same transaction
 	t.Run("CreateAndUpdateGameStatInTransaction", func(t *testing.T) {
 		tx := &dynamodb.TransactWriteItemsInput{}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories/inmemory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryRepositories(t *testing.T) {
+	// Setup
+	store := inmemory.NewSeededStore()
+
+	userRepo := inmemory.NewInMemoryUserRepository(store)
+	gameStatRepo := inmemory.NewInMemoryGameStatRepository(store)
+	leaderboardRepo := inmemory.NewInMemoryLeaderboardRepository(store)
+	transactionRepo := inmemory.NewInMemoryTransactionRepository(store)
+
+	// Test GetUserBasicsByPrefix
+	t.Run("GetUserBasicsByPrefix", func(t *testing.T) {
+		users, err := userRepo.GetUserBasicsByPrefix("user")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(users))
+		assert.Equal(t, models.UserID("user1"), users[0].UserID)
+		assert.Equal(t, models.UserID("user2"), users[1].UserID)
+		assert.Equal(t, models.UserID("user3"), users[2].UserID)
+
+		emptyUsers, err := userRepo.GetUserBasicsByPrefix("NonexistentPrefix")
+		assert.NoError(t, err)
+		assert.Empty(t, emptyUsers)
+
+		_, err = userRepo.GetUserBasicsByPrefix("")
+		assert.Error(t, err)
+	})
+
+	// Test GetLeaderboard ordering matches the DynamoDB Range key order
+	t.Run("GetLeaderboard", func(t *testing.T) {
+		leaderboard, err := leaderboardRepo.GetLeaderboard("pool", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "eveexplorer", "user3", "user1", "dianadancer"}, leaderboard.UserIDs())
+
+		boundedLeaderboard, err := leaderboardRepo.GetBoundedLeaderboard("pool", "elo", models.SortDescending, 2, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "eveexplorer"}, boundedLeaderboard.UserIDs())
+
+		// Ascending reads yield the Range keys in the opposite order
+		leaderboard, err = leaderboardRepo.GetLeaderboard("pool", "elo", models.SortAscending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"dianadancer", "user1", "user3", "eveexplorer", "user2"}, leaderboard.UserIDs())
+
+		boundedLeaderboard, err = leaderboardRepo.GetBoundedLeaderboard("pool", "elo", models.SortAscending, 2, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"dianadancer", "user1"}, boundedLeaderboard.UserIDs())
+		boundedLeaderboard, err = leaderboardRepo.GetBoundedLeaderboard("pool", "elo", models.SortAscending, 2, boundedLeaderboard.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user3", "eveexplorer"}, boundedLeaderboard.UserIDs())
+		assert.Equal(t, 3, boundedLeaderboard.Entries[0].Rank)
+
+		aroundUser, err := leaderboardRepo.GetLeaderboardAroundUser("pool", "elo", models.SortAscending, "user3", 2, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, aroundUser.Rank)
+		assert.Equal(t, []models.UserID{"user1", "user3", "eveexplorer"}, aroundUser.UserIDs())
+	})
+
+	// Test returned models are copies
+	t.Run("GetGameStatReturnsCopy", func(t *testing.T) {
+		gameStat, err := gameStatRepo.GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		gameStat.GameAttributes["goals"] = 100
+
+		fetchedGameStat, err := gameStatRepo.GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(1), fetchedGameStat.GameAttributes["goals"])
+	})
+
+	// Test writes staged in a transaction are applied only on execution
+	t.Run("ExecuteTransaction", func(t *testing.T) {
+		tx := &dynamodb.TransactWriteItemsInput{}
+
+		newUser, err := models.NewUser("user7", "NewUser", "new@example.com", []models.GameID{"soccer"})
+		assert.NoError(t, err)
+		_, err = userRepo.CreateUser(newUser, tx)
+		assert.NoError(t, err)
+
+		err = leaderboardRepo.UpdateLeaderboardItem("soccer", "user3", "elo", 5, 1, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(tx.TransactItems))
+
+		_, err = userRepo.GetUser("user7")
+		assert.Error(t, err)
+
+		err = transactionRepo.ExecuteTransaction(tx)
+		assert.NoError(t, err)
+
+		fetchedUser, err := userRepo.GetUser("user7")
+		assert.NoError(t, err)
+		assert.Equal(t, newUser, fetchedUser)
+
+		leaderboard, err := leaderboardRepo.GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserID("user3"), leaderboard.UserIDs()[0])
+		assert.Equal(t, 5, len(leaderboard.UserIDs()))
+	})
+
+	// Test a transaction writing the same item twice is rejected like
+	// DynamoDB does, and applies none of its writes
+	t.Run("ExecuteTransactionRejectsDuplicateItems", func(t *testing.T) {
+		tx := &dynamodb.TransactWriteItemsInput{}
+
+		err := leaderboardRepo.AddLeaderboardItem("soccer", "user8", "elo", 4, tx)
+		assert.NoError(t, err)
+		err = gameStatRepo.IncrementGameStat("user8", "soccer", models.AttributesStatsMap{"elo": 4}, nil, nil, tx)
+		assert.NoError(t, err)
+		err = gameStatRepo.DeleteGameStat("user8", "soccer", tx)
+		assert.NoError(t, err)
+
+		err = transactionRepo.ExecuteTransaction(tx)
+		assert.ErrorIs(t, err, models.ErrValidation)
+
+		_, err = gameStatRepo.GetGameStat("user8", "soccer")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		leaderboard, err := leaderboardRepo.GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.NotContains(t, leaderboard.UserIDs(), models.UserID("user8"))
+
+		// The same item in another scope is a different item
+		tx = &dynamodb.TransactWriteItemsInput{}
+		err = gameStatRepo.IncrementGameStat("user8", "soccer", models.AttributesStatsMap{"elo": 4}, nil, nil, tx)
+		assert.NoError(t, err)
+		err = gameStatRepo.WithScope("day.2024-06-03").IncrementGameStat("user8", "soccer", models.AttributesStatsMap{"elo": 4}, nil, nil, tx)
+		assert.NoError(t, err)
+		err = transactionRepo.ExecuteTransaction(tx)
+		assert.NoError(t, err)
+		for _, scope := range []models.LeaderboardScope{models.AllTimeScope, "day.2024-06-03"} {
+			err = gameStatRepo.WithScope(scope).DeleteGameStat("user8", "soccer", nil)
+			assert.NoError(t, err)
+		}
+	})
+}
@@ -30,26 +30,59 @@ func TestLeaderboardRepository(t *testing.T) {
 
 	// Test GetLeaderboard
 	t.Run("GetLeaderboard", func(t *testing.T) {
-		leaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"))
+		leaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
 		assert.NotNil(t, leaderboard)
-		assert.Equal(t, 5, len(leaderboard.UserIDs))
-		assert.Equal(t, models.UserID("user2"), leaderboard.UserIDs[0])
-		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs[1])
-		assert.Equal(t, models.UserID("user3"), leaderboard.UserIDs[2])
-		assert.Equal(t, models.UserID("dianadancer"), leaderboard.UserIDs[3])
-		assert.Equal(t, models.UserID("eveexplorer"), leaderboard.UserIDs[4])
+		assert.Equal(t, 5, len(leaderboard.UserIDs()))
+		assert.Equal(t, models.UserID("user2"), leaderboard.UserIDs()[0])
+		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs()[1])
+		assert.Equal(t, models.UserID("user3"), leaderboard.UserIDs()[2])
+		assert.Equal(t, models.UserID("dianadancer"), leaderboard.UserIDs()[3])
+		assert.Equal(t, models.UserID("eveexplorer"), leaderboard.UserIDs()[4])
 	})
 
 	// Test GetBoundedLeaderboard
 	t.Run("GetBoundedLeaderboard", func(t *testing.T) {
-		boundedLeaderboard, err := repo.GetBoundedLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), 3)
+		boundedLeaderboard, err := repo.GetBoundedLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending, 3, "")
 		assert.NoError(t, err)
 		assert.NotNil(t, boundedLeaderboard)
-		assert.Equal(t, 3, len(boundedLeaderboard.UserIDs))
-		assert.Equal(t, models.UserID("user2"), boundedLeaderboard.UserIDs[0])
-		assert.Equal(t, models.UserID("user1"), boundedLeaderboard.UserIDs[1])
-		assert.Equal(t, models.UserID("user3"), boundedLeaderboard.UserIDs[2])
+		assert.Equal(t, 3, len(boundedLeaderboard.UserIDs()))
+		assert.Equal(t, models.UserID("user2"), boundedLeaderboard.UserIDs()[0])
+		assert.Equal(t, models.UserID("user1"), boundedLeaderboard.UserIDs()[1])
+		assert.Equal(t, models.UserID("user3"), boundedLeaderboard.UserIDs()[2])
+	})
+
+	// Test GetLeaderboardAroundUser
+	t.Run("GetLeaderboardAroundUser", func(t *testing.T) {
+		leaderboard, err := repo.GetLeaderboardAroundUser(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending, models.UserID("user3"), models.AttributeStat(1), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, leaderboard.Rank)
+		assert.Equal(t, []models.UserID{"user1", "user3", "dianadancer"}, leaderboard.UserIDs())
+		assert.Equal(t, 2, leaderboard.Entries[0].Rank)
+
+		_, err = repo.GetLeaderboardAroundUser(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending, models.UserID("user3"), models.AttributeStat(99), 1)
+		assert.Error(t, err)
+	})
+
+	// Test reading the same items in ascending order
+	t.Run("AscendingLeaderboard", func(t *testing.T) {
+		leaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortAscending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"eveexplorer", "dianadancer", "user3", "user1", "user2"}, leaderboard.UserIDs())
+		assert.Equal(t, 1, leaderboard.Entries[0].Rank)
+
+		firstPage, err := repo.GetBoundedLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortAscending, 3, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"eveexplorer", "dianadancer", "user3"}, firstPage.UserIDs())
+		secondPage, err := repo.GetBoundedLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortAscending, 3, firstPage.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1", "user2"}, secondPage.UserIDs())
+		assert.Equal(t, 4, secondPage.Entries[0].Rank)
+
+		aroundUser, err := repo.GetLeaderboardAroundUser(models.GameID("soccer"), models.AttributeName("elo"), models.SortAscending, models.UserID("user3"), models.AttributeStat(1), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, aroundUser.Rank)
+		assert.Equal(t, []models.UserID{"dianadancer", "user3", "user1"}, aroundUser.UserIDs())
 	})
 
 	// Test AddLeaderboardItem
@@ -58,10 +91,10 @@ func TestLeaderboardRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the leaderboard was updated
-		leaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"))
+		leaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 6, len(leaderboard.UserIDs))
-		assert.Equal(t, models.UserID("newuser"), leaderboard.UserIDs[0])
+		assert.Equal(t, 6, len(leaderboard.UserIDs()))
+		assert.Equal(t, models.UserID("newuser"), leaderboard.UserIDs()[0])
 
 		// Clean up
 		err = repo.DeleteLeaderboardItem(models.GameID("soccer"), models.UserID("newuser"), models.AttributeName("elo"), models.AttributeStat(5), nil)
@@ -70,7 +103,7 @@ func TestLeaderboardRepository(t *testing.T) {
 
 	// Test UpdateLeaderboardItem
 	t.Run("UpdateLeaderboardItem", func(t *testing.T) {
-		oldLeaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"))
+		oldLeaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
 		t.Logf("Old leaderboard: %v", oldLeaderboard)
 
@@ -78,12 +111,12 @@ func TestLeaderboardRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the leaderboard was updated
-		newLeaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"))
+		newLeaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
 		t.Logf("New leaderboard: %v", newLeaderboard)
 
-		assert.Equal(t, models.UserID("user1"), newLeaderboard.UserIDs[0], "Expected user1 to be at the top after update")
-		assert.NotEqual(t, oldLeaderboard.UserIDs[0], newLeaderboard.UserIDs[0], "Expected top user to change after update")
+		assert.Equal(t, models.UserID("user1"), newLeaderboard.UserIDs()[0], "Expected user1 to be at the top after update")
+		assert.NotEqual(t, oldLeaderboard.UserIDs()[0], newLeaderboard.UserIDs()[0], "Expected top user to change after update")
 
 		// Revert changes
 		err = repo.UpdateLeaderboardItem(models.GameID("soccer"), models.UserID("user1"), models.AttributeName("elo"), models.AttributeStat(2), models.AttributeStat(10), nil)
@@ -101,9 +134,9 @@ func TestLeaderboardRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the leaderboard for this game is empty
-		leaderboard, err := repo.GetLeaderboard(models.GameID("deletegame"), models.AttributeName("elo"))
+		leaderboard, err := repo.GetLeaderboard(models.GameID("deletegame"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 0, len(leaderboard.UserIDs))
+		assert.Equal(t, 0, len(leaderboard.UserIDs()))
 	})
 
 	// Test AddLeaderboardItem and UpdateLeaderboardItem in the same transaction
@@ -122,11 +155,11 @@ func TestLeaderboardRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the leaderboard was updated
-		leaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"))
+		leaderboard, err := repo.GetLeaderboard(models.GameID("soccer"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 6, len(leaderboard.UserIDs))
-		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs[0])
-		assert.Equal(t, models.UserID("transactionuser"), leaderboard.UserIDs[1])
+		assert.Equal(t, 6, len(leaderboard.UserIDs()))
+		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs()[0])
+		assert.Equal(t, models.UserID("transactionuser"), leaderboard.UserIDs()[1])
 
 		// Clean up
 		cleanupTx := &dynamodb.TransactWriteItemsInput{}
@@ -154,13 +187,13 @@ func TestLeaderboardRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the items were deleted
-		leaderboard, err := repo.GetLeaderboard(models.GameID("testgame"), models.AttributeName("elo"))
+		leaderboard, err := repo.GetLeaderboard(models.GameID("testgame"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 0, len(leaderboard.UserIDs))
+		assert.Equal(t, 0, len(leaderboard.UserIDs()))
 
-		leaderboard, err = repo.GetLeaderboard(models.GameID("testgame"), models.AttributeName("score"))
+		leaderboard, err = repo.GetLeaderboard(models.GameID("testgame"), models.AttributeName("score"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 0, len(leaderboard.UserIDs))
+		assert.Equal(t, 0, len(leaderboard.UserIDs()))
 	})
 
 	// Test DeleteLeaderboardItemsByGame
@@ -176,9 +209,9 @@ func TestLeaderboardRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the items were deleted
-		leaderboard, err := repo.GetLeaderboard(models.GameID("deletegame"), models.AttributeName("elo"))
+		leaderboard, err := repo.GetLeaderboard(models.GameID("deletegame"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 0, len(leaderboard.UserIDs))
+		assert.Equal(t, 0, len(leaderboard.UserIDs()))
 	})
 
 	// Test DeleteLeaderboardItemsByGameAndAttribute
@@ -198,14 +231,14 @@ func TestLeaderboardRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Verify the "elo" items were deleted
-		leaderboard, err := repo.GetLeaderboard(models.GameID("attributegame"), models.AttributeName("elo"))
+		leaderboard, err := repo.GetLeaderboard(models.GameID("attributegame"), models.AttributeName("elo"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 0, len(leaderboard.UserIDs))
+		assert.Equal(t, 0, len(leaderboard.UserIDs()))
 
 		// Verify the "score" items still exist
-		leaderboard, err = repo.GetLeaderboard(models.GameID("attributegame"), models.AttributeName("score"))
+		leaderboard, err = repo.GetLeaderboard(models.GameID("attributegame"), models.AttributeName("score"), models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 2, len(leaderboard.UserIDs))
+		assert.Equal(t, 2, len(leaderboard.UserIDs()))
 
 		// Clean up
 		err = repo.DeleteLeaderboardItemsByGame(models.GameID("attributegame"), nil)
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/mquan1409/game-api/internal/config"
 	"github.com/mquan1409/game-api/internal/utils"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
@@ -216,6 +217,36 @@ func TestMatchRepository(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	// Test malformed stored matches are reported instead of panicking
+	t.Run("MalformedItems", func(t *testing.T) {
+		items := []map[string]*dynamodb.AttributeValue{
+			// Date shorter than YYYY-MM-DD
+			{"Id": {S: aws.String("MATCH_INFO.soccer")}, "Range": {S: aws.String("2023-6-1.badmatch")}, "TeamNames": {L: []*dynamodb.AttributeValue{{S: aws.String("Team A")}}}, "TeamScores": {L: []*dynamodb.AttributeValue{{N: aws.String("1")}}}, "TeamMembers": {L: []*dynamodb.AttributeValue{{L: []*dynamodb.AttributeValue{{S: aws.String("user1")}}}}}},
+			// Missing teams
+			{"Id": {S: aws.String("MATCH_INFO.soccer")}, "Range": {S: aws.String("2023-06-01.emptymatch")}},
+		}
+		for _, item := range items {
+			_, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String(cfg.TableName), Item: item})
+			assert.NoError(t, err)
+		}
+
+		_, err := repo.GetMatch("soccer", "badmatch", "2023-6-1")
+		assert.Error(t, err)
+		assert.NotPanics(t, func() {
+			_, err = repo.GetMatchesByGameAndDate("soccer", "2023-06-01")
+		})
+		assert.Error(t, err)
+
+		// Clean up: delete the malformed items
+		for _, item := range items {
+			_, err := db.DeleteItem(&dynamodb.DeleteItemInput{
+				TableName: aws.String(cfg.TableName),
+				Key:       map[string]*dynamodb.AttributeValue{"Id": item["Id"], "Range": item["Range"]},
+			})
+			assert.NoError(t, err)
+		}
+	})
+
 	// Scan the entire table after tests
 	afterScan, err := utils.ScanEntireTable(db, cfg.TableName)
 	if err != nil {
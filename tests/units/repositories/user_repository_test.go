@@ -205,7 +205,7 @@ func TestUserRepository(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, fetchedUser.Username, "UpdatedUser")	
 		assert.Equal(t, fetchedUser.Email, "updated@example.com")
-		assert.Equal(t, fetchedUser.GamesPlayed, []models.GameID{"tennis", "badminton"})
+		assert.Equal(t, fetchedUser.GamesPlayed, []models.GameID{"badminton", "tennis"})
 
 		// Clean up: Delete the remaining user
 		user8ID := models.UserID("user8")
@@ -213,6 +213,36 @@ func TestUserRepository(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	// Test GamesPlayed is updated in place as a set
+	t.Run("AddAndRemoveGamePlayed", func(t *testing.T) {
+		newUser, err := models.NewUser("user10", "SetUser", "set@example.com", nil)
+		assert.NoError(t, err)
+		_, err = repo.CreateUser(newUser, nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.AddGamePlayed("user10", "soccer", nil))
+		assert.NoError(t, repo.AddGamePlayed("user10", "pool", nil))
+		assert.NoError(t, repo.AddGamePlayed("user10", "soccer", nil))
+		user, err := repo.GetUser("user10")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.GameID{"pool", "soccer"}, user.GamesPlayed)
+		assert.Equal(t, 4, user.Version)
+
+		assert.NoError(t, repo.RemoveGamePlayed("user10", "soccer", nil))
+		user, err = repo.GetUser("user10")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.GameID{"pool"}, user.GamesPlayed)
+
+		// Missing users are not created
+		err = repo.AddGamePlayed("nobody", "soccer", nil)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		_, err = repo.GetUser("nobody")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		userID := models.UserID("user10")
+		assert.NoError(t, repo.DeleteUser(&userID, nil))
+	})
+
 	// Scan the entire table after tests
 	afterScan, err := utils.ScanEntireTable(db, cfg.TableName)
 	if err != nil {
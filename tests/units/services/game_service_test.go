@@ -1,35 +1,36 @@
 package tests
 
 import (
+	"fmt"
+	"slices"
 	"testing"
+	"time"
 
-	"github.com/mquan1409/game-api/internal/config"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/models"
 	"github.com/mquan1409/game-api/internal/repositories"
+	"github.com/mquan1409/game-api/internal/repositories/inmemory"
 	"github.com/mquan1409/game-api/internal/services"
-	"github.com/mquan1409/game-api/internal/utils"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGameService(t *testing.T) {
-	// Load test configuration
-	cfg := config.LoadConfig("development")
-
 	// Setup
-	db, err := utils.SetupTestDB(&cfg)
-	if err != nil {
-		t.Fatalf("Failed to setup test DB: %v", err)
-	}
-
-	gameRepo := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
-	leaderboardRepo := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
-	gameService := services.NewGameServiceImpl(gameRepo, leaderboardRepo)
-
-	// Scan the entire table before tests
-	beforeScan, err := utils.ScanEntireTable(db, cfg.TableName)
-	if err != nil {
-		t.Fatalf("Failed to scan table before tests: %v", err)
-	}
+	store := inmemory.NewSeededStore()
+
+	gameRepo := inmemory.NewInMemoryGameRepository(store)
+	leaderboardRepo := inmemory.NewInMemoryLeaderboardRepository(store)
+	userRepo := inmemory.NewInMemoryUserRepository(store)
+	gameStatRepo := inmemory.NewInMemoryGameStatRepository(store)
+	matchRepo := inmemory.NewInMemoryMatchRepository(store)
+	ratingRepo := inmemory.NewInMemoryRatingRepository(store)
+	transactionRepo := inmemory.NewInMemoryTransactionRepository(store)
+	seasonRepo := inmemory.NewInMemorySeasonRepository(store)
+	gameService := services.NewGameServiceImpl(gameRepo, leaderboardRepo, userRepo, gameStatRepo, matchRepo, ratingRepo, transactionRepo)
+	matchService := services.NewMatchServiceImpl(matchRepo, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+	seasonService := services.NewSeasonServiceImpl(seasonRepo, gameRepo, gameStatRepo, leaderboardRepo, matchRepo)
+	gameDeletionService := services.NewGameDeletionServiceImpl(gameRepo, matchRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+	ratingRecomputeService := services.NewRatingRecomputeServiceImpl(gameRepo, matchRepo, leaderboardRepo, ratingRepo, transactionRepo)
 
 	// Test GetGame
 	t.Run("GetGame", func(t *testing.T) {
@@ -42,28 +43,221 @@ func TestGameService(t *testing.T) {
 		assert.ElementsMatch(t, []models.AttributeName{"elo"}, game.RankedAttributes)
 	})
 
-	// Test GetGameLeaderboard
-	t.Run("GetGameLeaderboard", func(t *testing.T) {
-		leaderboard, err := gameService.GetGameLeaderboard("soccer", "elo")
+	// Test GetGames
+	t.Run("GetGames", func(t *testing.T) {
+		// Page through the catalogue two games at a time
+		page, err := gameService.GetGames(models.GameFilter{}, 2, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(page.Games))
+		assert.Equal(t, models.GameID("pickleball"), page.Games[0].GameID)
+		assert.Equal(t, models.GameID("pool"), page.Games[1].GameID)
+		assert.NotEmpty(t, page.NextCursor)
+
+		page, err = gameService.GetGames(models.GameFilter{}, 2, page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Games))
+		assert.Equal(t, models.GameID("soccer"), page.Games[0].GameID)
+		assert.Empty(t, page.NextCursor)
+
+		// Filter on description text
+		page, err = gameService.GetGames(models.GameFilter{Description: "oo"}, 10, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Games))
+		assert.Equal(t, models.GameID("pool"), page.Games[0].GameID)
+
+		// The description filter ignores case
+		page, err = gameService.GetGames(models.GameFilter{Description: "pIcKLe"}, 10, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Games))
+		assert.Equal(t, models.GameID("pickleball"), page.Games[0].GameID)
+
+		// Filter on ranked attribute
+		page, err = gameService.GetGames(models.GameFilter{RankedAttribute: "elo"}, 10, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(page.Games))
+		page, err = gameService.GetGames(models.GameFilter{RankedAttribute: "goals"}, 10, "")
+		assert.NoError(t, err)
+		assert.Empty(t, page.Games)
+
+		// Both filters together
+		page, err = gameService.GetGames(models.GameFilter{Description: "ball", RankedAttribute: "elo"}, 10, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Games))
+		assert.Equal(t, models.GameID("pickleball"), page.Games[0].GameID)
+
+		_, err = gameService.GetGames(models.GameFilter{}, 2, "!")
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+	})
+
+	// Test a page large enough for the whole leaderboard
+	t.Run("GetWholeGameLeaderboard", func(t *testing.T) {
+		leaderboard, err := gameService.GetBoundedGameLeaderboard("soccer", "elo", models.AllTimeScope, 100, "")
 		assert.NoError(t, err)
 		assert.NotEmpty(t, leaderboard)
-		assert.Equal(t, 5, len(leaderboard.UserIDs))
-		assert.Equal(t, models.UserID("user2"), leaderboard.UserIDs[0])
-		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs[1])
-		assert.Equal(t, models.UserID("user3"), leaderboard.UserIDs[2])
-		assert.Equal(t, models.UserID("dianadancer"), leaderboard.UserIDs[3])
-		assert.Equal(t, models.UserID("eveexplorer"), leaderboard.UserIDs[4])
+		assert.Equal(t, 5, len(leaderboard.UserIDs()))
+		assert.Equal(t, models.UserID("user2"), leaderboard.UserIDs()[0])
+		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs()[1])
+		assert.Equal(t, models.UserID("user3"), leaderboard.UserIDs()[2])
+		assert.Equal(t, models.UserID("dianadancer"), leaderboard.UserIDs()[3])
+		assert.Equal(t, models.UserID("eveexplorer"), leaderboard.UserIDs()[4])
+
+		// Entries carry the value, rank and username for display
+		assert.Equal(t, models.LeaderboardEntry{Rank: 1, UserID: "user2", Username: "BobBuilder", Value: 2}, leaderboard.Entries[0])
+		assert.Equal(t, models.LeaderboardEntry{Rank: 3, UserID: "user3", Username: "CharlieChaplin", Value: 1}, leaderboard.Entries[2])
+		assert.Equal(t, models.LeaderboardEntry{Rank: 5, UserID: "eveexplorer", Username: "EveExplorer", Value: 0}, leaderboard.Entries[4])
 	})
 
 	// Test GetBoundedGameLeaderboard
 	t.Run("GetBoundedGameLeaderboard", func(t *testing.T) {
-		boundedLeaderboard, err := gameService.GetBoundedGameLeaderboard("soccer", "elo", 3)
+		boundedLeaderboard, err := gameService.GetBoundedGameLeaderboard("soccer", "elo", models.AllTimeScope, 3, "")
 		assert.NoError(t, err)
 		assert.NotEmpty(t, boundedLeaderboard)
-		assert.Equal(t, 3, len(boundedLeaderboard.UserIDs))
-		assert.Equal(t, models.UserID("user2"), boundedLeaderboard.UserIDs[0])
-		assert.Equal(t, models.UserID("user1"), boundedLeaderboard.UserIDs[1])
-		assert.Equal(t, models.UserID("user3"), boundedLeaderboard.UserIDs[2])
+		assert.Equal(t, 3, len(boundedLeaderboard.UserIDs()))
+		assert.Equal(t, models.UserID("user2"), boundedLeaderboard.UserIDs()[0])
+		assert.Equal(t, models.UserID("user1"), boundedLeaderboard.UserIDs()[1])
+		assert.Equal(t, models.UserID("user3"), boundedLeaderboard.UserIDs()[2])
+		assert.Equal(t, "AliceWonder", boundedLeaderboard.Entries[1].Username)
+		assert.Equal(t, 2, boundedLeaderboard.Entries[1].Rank)
+	})
+
+	// Test paging through a leaderboard with cursors
+	t.Run("PageGameLeaderboard", func(t *testing.T) {
+		var userIDs []models.UserID
+		var ranks []int
+		cursor := ""
+		for pages := 0; ; pages++ {
+			page, err := gameService.GetBoundedGameLeaderboard("soccer", "elo", models.AllTimeScope, 2, cursor)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Entries), 2)
+			for _, entry := range page.Entries {
+				userIDs = append(userIDs, entry.UserID)
+				ranks = append(ranks, entry.Rank)
+			}
+			if page.NextCursor == "" {
+				assert.Equal(t, 2, pages)
+				break
+			}
+			cursor = page.NextCursor
+		}
+		assert.Equal(t, []models.UserID{"user2", "user1", "user3", "dianadancer", "eveexplorer"}, userIDs)
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, ranks)
+
+		// A cursor keeps its position when entries are added above it
+		firstPage, err := gameService.GetBoundedGameLeaderboard("soccer", "elo", models.AllTimeScope, 2, "")
+		assert.NoError(t, err)
+		err = leaderboardRepo.AddLeaderboardItem("soccer", "newuser", "elo", 50, nil)
+		assert.NoError(t, err)
+		secondPage, err := gameService.GetBoundedGameLeaderboard("soccer", "elo", models.AllTimeScope, 2, firstPage.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user3", "dianadancer"}, secondPage.UserIDs())
+		err = leaderboardRepo.DeleteLeaderboardItem("soccer", "newuser", "elo", 50, nil)
+		assert.NoError(t, err)
+
+		// Cursors from another leaderboard or garbage are rejected
+		_, err = leaderboardRepo.GetBoundedLeaderboard("soccer", "goals", models.SortDescending, 2, firstPage.NextCursor)
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+		_, err = gameService.GetBoundedGameLeaderboard("pool", "elo", models.AllTimeScope, 2, firstPage.NextCursor)
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+		_, err = gameService.GetBoundedGameLeaderboard("soccer", "elo", models.LeaderboardScope("day.2024-06-03"), 2, firstPage.NextCursor)
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+		_, err = leaderboardRepo.GetBoundedLeaderboard("soccer", "elo", models.SortAscending, 2, firstPage.NextCursor)
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+		_, err = gameService.GetBoundedGameLeaderboard("soccer", "elo", models.AllTimeScope, 2, "not-a-cursor")
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+	})
+
+	// Test GetLeaderboardAroundUser
+	t.Run("GetLeaderboardAroundUser", func(t *testing.T) {
+		leaderboard, err := gameService.GetLeaderboardAroundUser("soccer", "elo", models.AllTimeScope, "user3", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, leaderboard.Rank)
+		assert.Equal(t, []models.UserID{"user1", "user3", "dianadancer"}, leaderboard.UserIDs())
+		assert.Equal(t, []int{2, 3, 4}, []int{leaderboard.Entries[0].Rank, leaderboard.Entries[1].Rank, leaderboard.Entries[2].Rank})
+		assert.Equal(t, "CharlieChaplin", leaderboard.Entries[1].Username)
+
+		// The window is cut off at either end of the leaderboard
+		leaderboard, err = gameService.GetLeaderboardAroundUser("soccer", "elo", models.AllTimeScope, "user2", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, leaderboard.Rank)
+		assert.Equal(t, []models.UserID{"user2", "user1", "user3"}, leaderboard.UserIDs())
+
+		leaderboard, err = gameService.GetLeaderboardAroundUser("soccer", "elo", models.AllTimeScope, "eveexplorer", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, leaderboard.Rank)
+		assert.Equal(t, []models.UserID{"eveexplorer"}, leaderboard.UserIDs())
+
+		// Unranked attributes and users without a GameStat are rejected
+		_, err = gameService.GetLeaderboardAroundUser("soccer", "goals", models.AllTimeScope, "user3", 1)
+		assert.Error(t, err)
+		_, err = gameService.GetLeaderboardAroundUser("soccer", "elo", models.AllTimeScope, "nobody", 1)
+		assert.Error(t, err)
+	})
+
+	// Test leaderboards of attributes ranked in ascending order
+	t.Run("AscendingLeaderboard", func(t *testing.T) {
+		golf, err := models.NewGame("golf", "Golf", []models.AttributeName{"strokes", "birdies"}, []models.AttributeName{"strokes", "birdies"})
+		assert.NoError(t, err)
+
+		// Directions must be valid and belong to ranked attributes
+		golf.RankDirections = map[models.AttributeName]models.SortDirection{"strokes": "sideways"}
+		_, err = gameService.CreateGame(golf)
+		assert.ErrorIs(t, err, models.ErrValidation)
+		golf.RankDirections = map[models.AttributeName]models.SortDirection{"putts": models.SortAscending}
+		_, err = gameService.CreateGame(golf)
+		assert.ErrorIs(t, err, models.ErrValidation)
+
+		golf.RankDirections = map[models.AttributeName]models.SortDirection{"strokes": models.SortAscending}
+		_, err = gameService.CreateGame(golf)
+		assert.NoError(t, err)
+
+		match, err := models.NewMatch("round1", "2024-08-01", "golf", []string{"Team A", "Team B", "Team C"}, []int{0, 0, 0}, [][]string{{"user1"}, {"user2"}, {"user3"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"strokes": 72, "birdies": 3},
+			"user2": {"strokes": 68, "birdies": 5},
+			"user3": {"strokes": 75, "birdies": 1},
+		})
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(match)
+		assert.NoError(t, err)
+
+		// The fewest strokes ranks first, while birdies keep the default order
+		leaderboard, err := gameService.GetBoundedGameLeaderboard("golf", "strokes", models.AllTimeScope, 100, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "user1", "user3"}, leaderboard.UserIDs())
+		assert.Equal(t, models.LeaderboardEntry{Rank: 1, UserID: "user2", Username: "BobBuilder", Value: 68}, leaderboard.Entries[0])
+		leaderboard, err = gameService.GetBoundedGameLeaderboard("golf", "birdies", models.AllTimeScope, 100, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "user1", "user3"}, leaderboard.UserIDs())
+
+		firstPage, err := gameService.GetBoundedGameLeaderboard("golf", "strokes", models.AllTimeScope, 2, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "user1"}, firstPage.UserIDs())
+		secondPage, err := gameService.GetBoundedGameLeaderboard("golf", "strokes", models.AllTimeScope, 2, firstPage.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user3"}, secondPage.UserIDs())
+		assert.Equal(t, 3, secondPage.Entries[0].Rank)
+		assert.Empty(t, secondPage.NextCursor)
+
+		aroundUser, err := gameService.GetLeaderboardAroundUser("golf", "strokes", models.AllTimeScope, "user1", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, aroundUser.Rank)
+		assert.Equal(t, []models.UserID{"user2", "user1", "user3"}, aroundUser.UserIDs())
+		aroundUser, err = gameService.GetLeaderboardAroundUser("golf", "strokes", models.AllTimeScope, "user2", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, aroundUser.Rank)
+		assert.Equal(t, []models.UserID{"user2", "user1"}, aroundUser.UserIDs())
+
+		// Leaderboards of unknown games are not found
+		_, err = gameService.GetBoundedGameLeaderboard("nogame", "strokes", models.AllTimeScope, 100, "")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		// Clean up: Delete the game and everything its match wrote
+		_, err = gameService.DeleteGame("golf")
+		assert.NoError(t, err)
+		_, err = gameDeletionService.DeletePendingGames(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		leaderboard, err = gameService.GetBoundedGameLeaderboard("soccer", "elo", models.AllTimeScope, 100, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 5, len(leaderboard.Entries))
 	})
 
 	// Test CreateGame
@@ -82,8 +276,33 @@ func TestGameService(t *testing.T) {
 		assert.Equal(t, createdGame, retrievedGame)
 
 		// Clean up: Delete the created game
-		err = gameService.DeleteGame(createdGame.GameID)
+		_, err = gameService.DeleteGame(createdGame.GameID)
+		assert.NoError(t, err)
+		_, err = gameDeletionService.DeletePendingGames(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+	})
+
+	// Test CreateGame does not overwrite an existing game
+	t.Run("CreateExistingGame", func(t *testing.T) {
+		duplicate, err := models.NewGame("soccer", "Not soccer", []models.AttributeName{"score"}, []models.AttributeName{})
 		assert.NoError(t, err)
+		_, err = gameService.CreateGame(duplicate)
+		assert.ErrorIs(t, err, models.ErrConflict)
+
+		game, err := gameService.GetGame("soccer")
+		assert.NoError(t, err)
+		assert.NotEqual(t, "Not soccer", game.Description)
+	})
+
+	// Test UpdateGame does not create a missing game
+	t.Run("UpdateMissingGame", func(t *testing.T) {
+		missing, err := models.NewGame("nogame", "Missing game", []models.AttributeName{"score"}, []models.AttributeName{})
+		assert.NoError(t, err)
+		_, err = gameService.UpdateGame(missing)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		_, err = gameService.GetGame("nogame")
+		assert.ErrorIs(t, err, models.ErrNotFound)
 	})
 
 	// Test UpdateGame
@@ -127,10 +346,26 @@ func TestGameService(t *testing.T) {
 
 		// Test case 3: Remove a ranked attribute
 		t.Run("RemoveRankedAttribute", func(t *testing.T) {
+			match, err := models.NewMatch("timed", "2024-05-01", createdGame.GameID, []string{"Solo"}, []int{1}, [][]string{{"user1"}}, map[models.UserID]models.AttributesStatsMap{
+				"user1": {"score": 3, "time": 30},
+			})
+			assert.NoError(t, err)
+			_, err = matchService.CreateMatch(match)
+			assert.NoError(t, err)
+
 			newRankedAttributes := []models.AttributeName{"score"}
 			updatedGame, err := models.NewGame(createdGame.GameID, createdGame.Description, createdGame.Attributes, newRankedAttributes)
 			assert.NoError(t, err)
 
+			// An update losing the race on the game's version removes nothing
+			racingService := services.NewGameServiceImpl(&racingGameRepository{GameRepository: gameRepo}, leaderboardRepo, userRepo, gameStatRepo, matchRepo, ratingRepo, transactionRepo)
+			_, err = racingService.UpdateGame(updatedGame)
+			assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+			leaderboard, err := leaderboardRepo.GetLeaderboard(createdGame.GameID, "time", models.SortDescending)
+			assert.NoError(t, err)
+			assert.Equal(t, []models.UserID{"user1"}, leaderboard.UserIDs())
+
+			updatedGame.Version = 0
 			result, err := gameService.UpdateGame(updatedGame)
 			assert.NoError(t, err)
 			assert.Equal(t, updatedGame, result)
@@ -142,59 +377,279 @@ func TestGameService(t *testing.T) {
 			assert.NotContains(t, retrievedGame.RankedAttributes, models.AttributeName("level"))
 
 			// Verify that the leaderboard items for the removed attributes were deleted
-			leaderboard, err := gameService.GetGameLeaderboard(createdGame.GameID, "time")
+			leaderboard, err = leaderboardRepo.GetLeaderboard(createdGame.GameID, "time", models.SortDescending)
 			assert.NoError(t, err)
-			assert.Empty(t, leaderboard.UserIDs)
+			assert.Empty(t, leaderboard.UserIDs())
+
+			// and that the leaderboards of every scope can no longer be read
+			for _, scope := range []models.LeaderboardScope{models.AllTimeScope, "day.2024-05-01"} {
+				for _, attr := range []models.AttributeName{"time", "level"} {
+					_, err = gameService.GetBoundedGameLeaderboard(createdGame.GameID, attr, scope, 100, "")
+					assert.ErrorIs(t, err, models.ErrNotFound)
+				}
+			}
+			leaderboard, err = leaderboardRepo.WithScope("day.2024-05-01").GetLeaderboard(createdGame.GameID, "score", models.SortDescending)
+			assert.NoError(t, err)
+			assert.Equal(t, []models.UserID{"user1"}, leaderboard.UserIDs())
 
-			leaderboard, err = gameService.GetGameLeaderboard(createdGame.GameID, "level")
+			// Entries left behind are cleared when the attribute is ranked again
+			assert.NoError(t, leaderboardRepo.AddLeaderboardItem(createdGame.GameID, "user2", "time", 5, nil))
+			reranked, err := models.NewGame(createdGame.GameID, createdGame.Description, createdGame.Attributes, []models.AttributeName{"score", "time"})
+			assert.NoError(t, err)
+			_, err = gameService.UpdateGame(reranked)
+			assert.NoError(t, err)
+			leaderboard, err = leaderboardRepo.GetLeaderboard(createdGame.GameID, "time", models.SortDescending)
+			assert.NoError(t, err)
+			assert.Empty(t, leaderboard.UserIDs())
+			updatedGame.Version = 0
+			_, err = gameService.UpdateGame(updatedGame)
 			assert.NoError(t, err)
-			assert.Empty(t, leaderboard.UserIDs)
+		})
+
+		// Test case 4: Aggregation modes can be given to new attributes only
+		t.Run("Aggregations", func(t *testing.T) {
+			updatedGame, err := gameService.GetGame(createdGame.GameID)
+			assert.NoError(t, err)
+
+			updatedGame.Aggregations = map[models.AttributeName]models.AggregationMode{"time": models.AggregationMin}
+			_, err = gameService.UpdateGame(updatedGame)
+			assert.ErrorIs(t, err, models.ErrValidation)
+
+			updatedGame.Aggregations = map[models.AttributeName]models.AggregationMode{"lap": "median"}
+			updatedGame.Attributes = append(updatedGame.Attributes, "lap")
+			_, err = gameService.UpdateGame(updatedGame)
+			assert.ErrorIs(t, err, models.ErrValidation)
+
+			updatedGame.Aggregations = map[models.AttributeName]models.AggregationMode{"best_lap": models.AggregationMin}
+			_, err = gameService.UpdateGame(updatedGame)
+			assert.ErrorIs(t, err, models.ErrValidation)
+
+			updatedGame.Attributes = append(updatedGame.Attributes, "best_lap")
+			result, err := gameService.UpdateGame(updatedGame)
+			assert.NoError(t, err)
+			assert.Equal(t, models.AggregationMin, result.Aggregation("best_lap"))
+			assert.Equal(t, models.AggregationSum, result.Aggregation("time"))
+
+			// A removed attribute keeps its aggregation mode when it is
+			// added back, as its GameStats still hold its values
+			result.Attributes = slices.DeleteFunc(result.Attributes, func(attr models.AttributeName) bool {
+				return attr == "best_lap"
+			})
+			result.Aggregations = nil
+			result, err = gameService.UpdateGame(result)
+			assert.NoError(t, err)
+			assert.Equal(t, map[models.AttributeName]models.AggregationMode{"best_lap": models.AggregationMin}, result.RemovedAggregations)
+
+			result.Attributes = append(result.Attributes, "best_lap")
+			_, err = gameService.UpdateGame(result)
+			assert.ErrorIs(t, err, models.ErrValidation)
+
+			result.Aggregations = map[models.AttributeName]models.AggregationMode{"best_lap": models.AggregationMin}
+			result.RemovedAggregations = nil
+			result, err = gameService.UpdateGame(result)
+			assert.NoError(t, err)
+			assert.Equal(t, models.AggregationMin, result.Aggregation("best_lap"))
+			assert.Empty(t, result.RemovedAggregations)
 		})
 
 		// Clean up: Delete the game used for update tests
-		err = gameService.DeleteGame(createdGame.GameID)
+		_, err = gameService.DeleteGame(createdGame.GameID)
+		assert.NoError(t, err)
+		_, err = gameDeletionService.DeletePendingGames(time.Now().Add(time.Minute))
 		assert.NoError(t, err)
 	})
 
-	// Test DeleteGame
+	// Test DeleteGame cascades to everything the game's matches wrote
 	t.Run("DeleteGame", func(t *testing.T) {
 		newGame, err := models.NewGame("tempgame", "Temporary game", []models.AttributeName{"score"}, []models.AttributeName{"score"})
 		assert.NoError(t, err)
-		createdGame, err := gameService.CreateGame(newGame)
+		_, err = gameService.CreateGame(newGame)
+		assert.NoError(t, err)
+		season, err := models.NewSeason("tempgame", "june", "June", "2024-06-01", "2024-06-30")
+		assert.NoError(t, err)
+		_, err = seasonService.CreateSeason(season)
+		assert.NoError(t, err)
+
+		dates := []models.DateID{"2024-06-03", "2024-06-04", "2024-07-01"}
+		for i, dateID := range dates {
+			match, err := models.NewMatch(models.MatchID(fmt.Sprintf("match%d", i+1)), dateID, "tempgame", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}},
+				map[models.UserID]models.AttributesStatsMap{"user1": {"score": 3}, "user2": {"score": 1}})
+			assert.NoError(t, err)
+			_, err = matchService.CreateMatch(match)
+			assert.NoError(t, err)
+		}
+		user, err := userRepo.GetUser("user1")
 		assert.NoError(t, err)
+		assert.Contains(t, user.GamesPlayed, models.GameID("tempgame"))
 
-		// Add some items to the leaderboard for tempgame
-		err = leaderboardRepo.AddLeaderboardItem(models.GameID("tempgame"), models.UserID("user1"), models.AttributeName("score"), 100, nil)
+		deletingGame, err := gameService.DeleteGame("tempgame")
 		assert.NoError(t, err)
-		err = leaderboardRepo.AddLeaderboardItem(models.GameID("tempgame"), models.UserID("user2"), models.AttributeName("score"), 200, nil)
+		assert.Equal(t, models.GameStatusDeleting, deletingGame.Status)
+		assert.Equal(t, &models.GameDeletion{Step: models.GameDeletionMatches}, deletingGame.Deletion)
+
+		// Deleting again changes nothing
+		again, err := gameService.DeleteGame("tempgame")
+		assert.NoError(t, err)
+		assert.Equal(t, deletingGame, again)
+
+		// The game is read-only while it is being deleted
+		match, err := models.NewMatch("match4", "2024-06-05", "tempgame", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, nil)
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(match)
+		assert.ErrorIs(t, err, models.ErrForbidden)
+		_, err = seasonService.CloseSeason("tempgame", "june")
+		assert.ErrorIs(t, err, models.ErrForbidden)
+		_, err = gameService.ArchiveGame("tempgame")
+		assert.ErrorIs(t, err, models.ErrForbidden)
+
+		// A run past its deadline deletes one page and saves its progress
+		deleted, err := gameDeletionService.DeletePendingGames(time.Now().Add(-time.Second))
+		assert.NoError(t, err)
+		assert.Empty(t, deleted)
+		game, err := gameService.GetGame("tempgame")
 		assert.NoError(t, err)
+		assert.Equal(t, &models.GameDeletion{Step: models.GameDeletionMatches, MatchesDeleted: len(dates)}, game.Deletion)
 
-		// Verify leaderboard items exist
-		leaderboard, err := gameService.GetGameLeaderboard(models.GameID("tempgame"), models.AttributeName("score"))
+		// The next run resumes from there
+		deleted, err = gameDeletionService.DeletePendingGames(time.Now().Add(time.Minute))
 		assert.NoError(t, err)
-		assert.Equal(t, 2, len(leaderboard.UserIDs))
+		assert.Equal(t, []models.GameID{"tempgame"}, deleted)
 
-		err = gameService.DeleteGame(createdGame.GameID)
+		_, err = gameService.GetGame("tempgame")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		matches, err := matchRepo.GetMatchesByGame("tempgame")
 		assert.NoError(t, err)
+		assert.Empty(t, matches)
+		seasons, err := seasonRepo.GetSeasonsByGame("tempgame")
+		assert.NoError(t, err)
+		assert.Empty(t, seasons)
+
+		dayScope, err := models.NewPeriodScope(models.PeriodDay, "2024-06-03")
+		assert.NoError(t, err)
+		for _, scope := range []models.LeaderboardScope{models.AllTimeScope, dayScope, season.Scope()} {
+			for _, userID := range []models.UserID{"user1", "user2"} {
+				_, err = gameStatRepo.WithScope(scope).GetGameStat(userID, "tempgame")
+				assert.ErrorIs(t, err, models.ErrNotFound, "%s in scope %q", userID, scope)
+			}
+			leaderboard, err := leaderboardRepo.WithScope(scope).GetLeaderboard("tempgame", "score", models.SortDescending)
+			assert.NoError(t, err)
+			assert.Empty(t, leaderboard.UserIDs(), "scope %q", scope)
+		}
 
-		// Verify game is deleted
-		_, err = gameService.GetGame(createdGame.GameID)
+		user, err = userRepo.GetUser("user1")
+		assert.NoError(t, err)
+		assert.NotContains(t, user.GamesPlayed, models.GameID("tempgame"))
+		assert.Contains(t, user.GamesPlayed, models.GameID("soccer"))
+		history, err := matchRepo.GetUserMatches("user1", models.UserMatchFilter{GameID: "tempgame"}, 10, "")
+		assert.NoError(t, err)
+		assert.Empty(t, history.Matches)
+
+		// Other games are untouched
+		gameStat, err := gameStatRepo.GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, gameStat.GameAttributes)
+	})
+
+	// Test an archived game is read-only until it is unarchived
+	t.Run("ArchiveGame", func(t *testing.T) {
+		archivedGame, err := gameService.ArchiveGame("pool")
+		assert.NoError(t, err)
+		assert.Equal(t, models.GameStatusArchived, archivedGame.Status)
+
+		_, err = gameService.UpdateGame(archivedGame)
+		assert.ErrorIs(t, err, models.ErrForbidden)
+		match, err := models.NewMatch("archived1", "2024-06-03", "pool", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, nil)
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(match)
+		assert.ErrorIs(t, err, models.ErrForbidden)
+		season, err := models.NewSeason("pool", "archived", "Archived", "2024-06-01", "2024-06-30")
+		assert.NoError(t, err)
+		_, err = seasonService.CreateSeason(season)
+		assert.ErrorIs(t, err, models.ErrForbidden)
+
+		// Archived games are still listed and readable
+		page, err := gameService.GetGames(models.GameFilter{Status: models.GameStatusArchived}, 10, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.Game{archivedGame}, page.Games)
+		_, err = gameService.GetBoundedGameLeaderboard("pool", archivedGame.RankedAttributes[0], models.AllTimeScope, 100, "")
+		assert.NoError(t, err)
+
+		unarchivedGame, err := gameService.UnarchiveGame("pool")
+		assert.NoError(t, err)
+		assert.Equal(t, models.GameStatusActive, unarchivedGame.Status)
+		_, err = gameService.UpdateGame(unarchivedGame)
+		assert.NoError(t, err)
+	})
+
+	// Test switching a game's rating system
+	t.Run("RatingSystem", func(t *testing.T) {
+		game, err := gameService.GetGame("soccer")
+		assert.NoError(t, err)
+
+		invalid := *game
+		invalid.RatingSystem = "chess-titles"
+		_, err = gameService.UpdateGame(&invalid)
+		assert.Error(t, err)
+
+		reserved := *game
+		reserved.RatingSystem = models.RatingSystemElo
+		reserved.Attributes = append([]models.AttributeName{models.RatingAttribute}, game.Attributes...)
+		_, err = gameService.UpdateGame(&reserved)
 		assert.Error(t, err)
 
-		// Verify leaderboard items are also deleted
-		leaderboard, err = gameService.GetGameLeaderboard(models.GameID("tempgame"), models.AttributeName("score"))
+		// Enabling a rating system rates the game's existing matches once
+		// the recompute it requests has run
+		rated := *game
+		rated.RatingSystem = models.RatingSystemGlicko2
+		updated, err := gameService.UpdateGame(&rated)
+		assert.NoError(t, err)
+		assert.Equal(t, game.Version+1, updated.Version)
+		_, err = ratingRepo.GetRating("soccer", "user1")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		recomputed, err := ratingRecomputeService.RecomputePendingRatings(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, []models.GameID{"soccer"}, recomputed)
+
+		matches, err := matchRepo.GetMatchesByGame("soccer")
+		assert.NoError(t, err)
+		players := map[models.UserID]bool{}
+		for _, match := range matches {
+			for _, userID := range match.Players() {
+				players[userID] = true
+			}
+		}
+		leaderboard, err := gameService.GetBoundedGameLeaderboard("soccer", models.RatingAttribute, models.AllTimeScope, 100, "")
+		assert.NoError(t, err)
+		assert.Equal(t, len(players), len(leaderboard.Entries))
+
+		aroundUser, err := gameService.GetLeaderboardAroundUser("soccer", models.RatingAttribute, models.AllTimeScope, "user1", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserID("user1"), aroundUser.UserID)
+
+		// Disabling it removes the ratings again. The read game is stale now
+		_, err = gameService.UpdateGame(game)
+		assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+		game.Version = updated.Version
+		_, err = gameService.UpdateGame(game)
+		assert.NoError(t, err)
+		_, err = ratingRecomputeService.RecomputePendingRatings(time.Now().Add(time.Minute))
 		assert.NoError(t, err)
-		assert.Equal(t, 0, len(leaderboard.UserIDs))
+		_, err = gameService.GetBoundedGameLeaderboard("soccer", models.RatingAttribute, models.AllTimeScope, 100, "")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		ratingLeaderboard, err := leaderboardRepo.GetLeaderboard("soccer", models.RatingAttribute, models.SortDescending)
+		assert.NoError(t, err)
+		assert.Empty(t, ratingLeaderboard.Entries)
+		_, err = ratingRepo.GetRating("soccer", "user1")
+		assert.Error(t, err)
 	})
+}
 
-	// Scan the entire table after tests
-	afterScan, err := utils.ScanEntireTable(db, cfg.TableName)
-	if err != nil {
-		t.Fatalf("Failed to scan table after tests: %v", err)
-	}
+// racingGameRepository fails every UpdateGame as if a concurrent write had
+// changed the game after it was read.
+type racingGameRepository struct {
+	repositories.GameRepository
+}
 
-	// Compare before and after scans
-	if !t.Failed() {
-		assert.Equal(t, beforeScan, afterScan, "The database state has changed after running tests")
-	}
+func (r *racingGameRepository) UpdateGame(game *models.Game, tx *dynamodb.TransactWriteItemsInput) (*models.Game, error) {
+	return nil, models.NewPreconditionFailedError("game %s changed since version %d", game.GameID, game.Version)
 }
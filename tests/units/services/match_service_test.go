@@ -1,36 +1,40 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/mquan1409/game-api/internal/config"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/ratings"
 	"github.com/mquan1409/game-api/internal/repositories"
+	"github.com/mquan1409/game-api/internal/repositories/inmemory"
 	"github.com/mquan1409/game-api/internal/services"
-	"github.com/mquan1409/game-api/internal/utils"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMatchService(t *testing.T) {
-	// Load test configuration
-	cfg := config.LoadConfig("development")
-
 	// Setup
-	db, err := utils.SetupTestDB(&cfg)
-	if err != nil {
-		t.Fatalf("Failed to setup test DB: %v", err)
-	}
-
-	matchRepo := repositories.NewDynamoDBMatchRepository(db, cfg.TableName)
-	gameRepo := repositories.NewDynamoDBGameRepository(db, cfg.TableName)
-	gameStatRepo := repositories.NewDynamoDBGameStatRepository(db, cfg.TableName)
-	leaderboardRepo := repositories.NewDynamoDBLeaderboardRepository(db, cfg.TableName)
-	matchService := services.NewMatchServiceImpl(matchRepo, gameRepo, gameStatRepo, leaderboardRepo)
+	store := inmemory.NewSeededStore()
 
-	// Scan the entire table before tests
-	beforeScan, err := utils.ScanEntireTable(db, cfg.TableName)
-	if err != nil {
-		t.Fatalf("Failed to scan table before tests: %v", err)
+	matchRepo := inmemory.NewInMemoryMatchRepository(store)
+	gameRepo := inmemory.NewInMemoryGameRepository(store)
+	userRepo := inmemory.NewInMemoryUserRepository(store)
+	gameStatRepo := inmemory.NewInMemoryGameStatRepository(store)
+	leaderboardRepo := inmemory.NewInMemoryLeaderboardRepository(store)
+	seasonRepo := inmemory.NewInMemorySeasonRepository(store)
+	transactionRepo := inmemory.NewInMemoryTransactionRepository(store)
+	ratingRepo := inmemory.NewInMemoryRatingRepository(store)
+	matchService := services.NewMatchServiceImpl(matchRepo, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+	ratingRecomputeService := services.NewRatingRecomputeServiceImpl(gameRepo, matchRepo, leaderboardRepo, ratingRepo, transactionRepo)
+	recomputeRatings := func(t *testing.T) {
+		_, err := ratingRecomputeService.RecomputePendingRatings(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		_, err = ratingRepo.GetRatingRecompute("chess")
+		assert.ErrorIs(t, err, models.ErrNotFound)
 	}
 
 	// Test GetMatch
@@ -64,7 +68,7 @@ func TestMatchService(t *testing.T) {
 		match1, _ := models.NewMatch("testmatch2", "2023-06-11", "soccer", []string{"Team C", "Team D"}, []int{3, 3}, [][]string{{"user1", "user3"}, {"user2", "dianadancer"}}, nil)
 		match2, _ := models.NewMatch("testmatch3", "2023-06-11", "soccer", []string{"Team E", "Team F"}, []int{1, 0}, [][]string{{"user1", "dianadancer"}, {"user2", "user3"}}, nil)
 
-		_, err = matchService.CreateMatch(match1)
+		_, err := matchService.CreateMatch(match1)
 		assert.NoError(t, err)
 		_, err = matchService.CreateMatch(match2)
 		assert.NoError(t, err)
@@ -82,6 +86,59 @@ func TestMatchService(t *testing.T) {
 		err = matchService.DeleteMatch("soccer", "testmatch3", "2023-06-11")
 		assert.NoError(t, err)
 	})
+	// Test GetMatchesByGameAndDateRange
+	t.Run("GetMatchesByGameAndDateRange", func(t *testing.T) {
+		dates := []models.DateID{"2023-07-01", "2023-07-02", "2023-07-02", "2023-07-03", "2023-07-05"}
+		for i, date := range dates {
+			match, err := models.NewMatch(models.MatchID(fmt.Sprintf("rangematch%d", i)), date, "soccer", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, nil)
+			assert.NoError(t, err)
+			_, err = matchService.CreateMatch(match)
+			assert.NoError(t, err)
+		}
+
+		dateRange, err := models.NewDateRange("2023-07-02", "2023-07-05")
+		assert.NoError(t, err)
+
+		// Page through the range two matches at a time
+		var matchIDs []models.MatchID
+		cursor := ""
+		for pages := 0; pages < 5; pages++ {
+			page, err := matchService.GetMatchesByGameAndDateRange("soccer", dateRange, 2, cursor)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Matches), 2)
+			for _, match := range page.Matches {
+				matchIDs = append(matchIDs, match.MatchID)
+			}
+			cursor = page.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+		assert.Equal(t, []models.MatchID{"rangematch1", "rangematch2", "rangematch3", "rangematch4"}, matchIDs)
+
+		// A single day range is the same as querying that date
+		dayRange, err := models.NewDateRange("2023-07-01", "2023-07-01")
+		assert.NoError(t, err)
+		page, err := matchService.GetMatchesByGameAndDateRange("soccer", dayRange, 10, "")
+		assert.NoError(t, err)
+		assert.Len(t, page.Matches, 1)
+		assert.Equal(t, models.MatchID("rangematch0"), page.Matches[0].MatchID)
+		assert.Empty(t, page.NextCursor)
+
+		_, err = matchService.GetMatchesByGameAndDateRange("soccer", dateRange, 2, "not-a-cursor")
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+
+		_, err = models.NewDateRange("2023-07-05", "2023-07-02")
+		assert.Error(t, err)
+		_, err = models.NewDateRange("2023-07-02", "July 5th")
+		assert.Error(t, err)
+
+		// Clean up: Delete the created matches
+		for i, date := range dates {
+			err = matchService.DeleteMatch("soccer", models.MatchID(fmt.Sprintf("rangematch%d", i)), date)
+			assert.NoError(t, err)
+		}
+	})
 	// Test CreateMatch
 	t.Run("CreateMatch", func(t *testing.T) {
 		// Create a new match
@@ -147,15 +204,15 @@ func TestMatchService(t *testing.T) {
 		}
 
 		// Verify Leaderboard was updated only for 'elo'
-		leaderboard, err := leaderboardRepo.GetLeaderboard("soccer", "elo")
+		leaderboard, err := leaderboardRepo.GetLeaderboard("soccer", "elo", models.SortDescending)
 		assert.NoError(t, err)
-		assert.NotEmpty(t, leaderboard.UserIDs)
+		assert.NotEmpty(t, leaderboard.UserIDs())
 
-		assert.Equal(t, 5, len(leaderboard.UserIDs)) // 5 users in the leaderboard as per add-leaderboard.json
+		assert.Equal(t, 5, len(leaderboard.UserIDs())) // 5 users in the leaderboard as per add-leaderboard.json
 
 		// Verify the order of users in the leaderboard
 		expectedOrder := []models.UserID{"user1", "user2", "user3", "dianadancer", "eveexplorer"}
-		assert.Equal(t, expectedOrder, leaderboard.UserIDs)
+		assert.Equal(t, expectedOrder, leaderboard.UserIDs())
 
 		// Clean up: Delete the created match and reset GameStats and Leaderboard
 		err = matchRepo.DeleteMatch(models.GameID("soccer"), models.MatchID("testmatch1"), models.DateID("2023-06-10"), nil)
@@ -184,10 +241,10 @@ func TestMatchService(t *testing.T) {
 		}
 
 		// Verify the leaderboard is back to its original state
-		leaderboard, err = leaderboardRepo.GetLeaderboard("soccer", "elo")
+		leaderboard, err = leaderboardRepo.GetLeaderboard("soccer", "elo", models.SortDescending)
 		assert.NoError(t, err)
-		assert.Equal(t, 5, len(leaderboard.UserIDs))
-		assert.Equal(t, []models.UserID{"user2", "user1", "user3", "dianadancer", "eveexplorer"}, leaderboard.UserIDs)
+		assert.Equal(t, 5, len(leaderboard.UserIDs()))
+		assert.Equal(t, []models.UserID{"user2", "user1", "user3", "dianadancer", "eveexplorer"}, leaderboard.UserIDs())
 
 		// Reset GameStats to original state
 		originalGameStats := map[models.UserID]models.AttributesStatsMap{
@@ -301,31 +358,751 @@ func TestMatchService(t *testing.T) {
 		}
 
 		// Verify Leaderboard was updated correctly
-		leaderboard, err := leaderboardRepo.GetLeaderboard("soccer", "elo")
+		leaderboard, err := leaderboardRepo.GetLeaderboard("soccer", "elo", models.SortDescending)
 		assert.NoError(t, err)
 		assert.Equal(t, models.GameID("soccer"), leaderboard.GameID)
 		assert.Equal(t, models.AttributeName("elo"), leaderboard.AttributeName)
-		assert.Equal(t, 5, len(leaderboard.UserIDs))
-		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs[0])
-		assert.Equal(t, models.UserID("user2"), leaderboard.UserIDs[1])
-		assert.Contains(t, []models.UserID{"user3", "dianadancer"}, leaderboard.UserIDs[2])
-		assert.Contains(t, []models.UserID{"user3", "dianadancer"}, leaderboard.UserIDs[3])
+		assert.Equal(t, 5, len(leaderboard.UserIDs()))
+		assert.Equal(t, models.UserID("user1"), leaderboard.UserIDs()[0])
+		assert.Equal(t, models.UserID("user2"), leaderboard.UserIDs()[1])
+		assert.Contains(t, []models.UserID{"user3", "dianadancer"}, leaderboard.UserIDs()[2])
+		assert.Contains(t, []models.UserID{"user3", "dianadancer"}, leaderboard.UserIDs()[3])
 
 		// Clean up: Delete the created match
 		err = matchService.DeleteMatch("soccer", "testmatch4", "2023-06-12")
 		assert.NoError(t, err)
 	})
-	// Scan the entire table after tests
-	afterScan, err := utils.ScanEntireTable(db, cfg.TableName)
-	if err != nil {
-		t.Fatalf("Failed to scan table after tests: %v", err)
-	}
+	// Test matches feed the day, week and month leaderboards of their DateID
+	t.Run("PeriodLeaderboards", func(t *testing.T) {
+		// 2024-06-03 is the Monday of ISO week 23; 2024-06-09 is its Sunday
+		monday, _ := models.NewMatch("weekmatch1", "2024-06-03", "soccer", []string{"Team L", "Team M"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"elo": 3, "goals": 1},
+			"user2": {"elo": 1, "goals": 0},
+		})
+		sunday, _ := models.NewMatch("weekmatch2", "2024-06-09", "soccer", []string{"Team L", "Team M"}, []int{0, 2}, [][]string{{"user1"}, {"user2"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"elo": 0, "goals": 0},
+			"user2": {"elo": 5, "goals": 2},
+		})
+		_, err := matchService.CreateMatch(monday)
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(sunday)
+		assert.NoError(t, err)
 
-	// Compare before and after scans
-	if !t.Failed() {
-		assert.Equal(t, beforeScan, afterScan, "MatchService Test: The database state has changed after running tests")
-	}
+		week, err := models.NewPeriodScope(models.PeriodWeek, "2024-06-05")
+		assert.NoError(t, err)
+		leaderboard, err := leaderboardRepo.WithScope(week).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "user1"}, leaderboard.UserIDs())
+		assert.Equal(t, models.AttributeStat(6), leaderboard.Entries[0].Value)
+
+		day, err := models.NewPeriodScope(models.PeriodDay, "2024-06-03")
+		assert.NoError(t, err)
+		leaderboard, err = leaderboardRepo.WithScope(day).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1", "user2"}, leaderboard.UserIDs())
+
+		gameStat, err := gameStatRepo.WithScope(day).GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, models.GameID("soccer"), gameStat.GameID)
+		assert.Equal(t, models.AttributeStat(1), gameStat.GameAttributes["goals"])
+
+		// Updating and deleting a match adjusts its periods too
+		sunday.PlayerAttributesMap["user2"] = models.AttributesStatsMap{"elo": 1, "goals": 0}
+		_, err = matchService.UpdateMatch(sunday)
+		assert.NoError(t, err)
+		leaderboard, err = leaderboardRepo.WithScope(week).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1", "user2"}, leaderboard.UserIDs())
+
+		err = matchService.DeleteMatch("soccer", "weekmatch1", "2024-06-03")
+		assert.NoError(t, err)
+		err = matchService.DeleteMatch("soccer", "weekmatch2", "2024-06-09")
+		assert.NoError(t, err)
+		gameStat, err = gameStatRepo.WithScope(week).GetGameStat("user2", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(0), gameStat.GameAttributes["elo"])
+
+		// The all-time leaderboard is back to the seed values
+		leaderboard, err = leaderboardRepo.GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "user1", "user3", "dianadancer", "eveexplorer"}, leaderboard.UserIDs())
+
+		// Matches need a valid DateID to be placed in a period
+		_, err = models.NewMatch("badmatch", "June 3rd", "soccer", []string{"Team L"}, []int{1}, [][]string{{"user1"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"elo": 1},
+		})
+		assert.ErrorIs(t, err, models.ErrValidation)
+		_, err = matchService.CreateMatch(&models.Match{
+			MatchID: "badmatch", DateID: "June 3rd", GameID: "soccer",
+			TeamNames: []string{"Team L"}, TeamScores: []int{1}, TeamMembers: [][]string{{"user1"}},
+		})
+		assert.ErrorIs(t, err, models.ErrValidation)
+	})
+
+	// Test a match write succeeds once stored even if its follow-up fails, and
+	// the follow-up stays pending until MatchFollowUpService carries it out
+	t.Run("FollowUps", func(t *testing.T) {
+		failingHistory := &failingHistoryMatchRepository{MatchRepository: matchRepo, failures: 1}
+		failingService := services.NewMatchServiceImpl(failingHistory, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+		followUpService := services.NewMatchFollowUpServiceImpl(matchRepo, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+
+		match, _ := models.NewMatch("followup1", "2024-07-01", "soccer", []string{"Team L", "Team M"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"elo": 3, "goals": 1},
+		})
+		_, err := failingService.CreateMatch(match)
+		assert.NoError(t, err)
+		_, err = matchRepo.GetMatch("soccer", "followup1", "2024-07-01")
+		assert.NoError(t, err)
+
+		day, err := models.NewPeriodScope(models.PeriodDay, "2024-07-01")
+		assert.NoError(t, err)
+		_, err = gameStatRepo.WithScope(day).GetGameStat("user1", "soccer")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		followUp, err := matchRepo.GetMatchFollowUp("soccer", "2024-07-01")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1"}, followUp.Players)
+
+		finished, err := followUpService.RunPendingFollowUps(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Len(t, finished, 1)
+		gameStat, err := gameStatRepo.WithScope(day).GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(3), gameStat.GameAttributes["elo"])
+		leaderboard, err := leaderboardRepo.WithScope(day).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1"}, leaderboard.UserIDs())
+		_, err = matchRepo.GetMatchFollowUp("soccer", "2024-07-01")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		// A follow-up requested again while it ran is left for the next run
+		followUp = &models.MatchFollowUp{GameID: "soccer", DateID: "2024-07-01", Players: []models.UserID{"user1"}}
+		assert.NoError(t, matchRepo.RequestMatchFollowUp(followUp.GameID, followUp.DateID, followUp.Players, nil, nil))
+		assert.NoError(t, matchRepo.RequestMatchFollowUp(followUp.GameID, followUp.DateID, nil, []models.UserID{"user2"}, nil))
+		followUp.Version = 1
+		assert.ErrorIs(t, matchRepo.DeleteMatchFollowUp(followUp, nil), models.ErrPreconditionFailed)
+		finished, err = followUpService.RunPendingFollowUps(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2"}, finished[0].Leavers)
+
+		assert.NoError(t, matchService.DeleteMatch("soccer", "followup1", "2024-07-01"))
+		_, err = matchRepo.GetMatchFollowUp("soccer", "2024-07-01")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+
+	// Test CreateMatch does not overwrite an existing match or apply its stats
+	t.Run("CreateExistingMatch", func(t *testing.T) {
+		before, err := gameStatRepo.GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		goals := before.GameAttributes["goals"]
+
+		duplicate, err := models.NewMatch("match1", "2023-06-01", "soccer", []string{"Team A"}, []int{1}, [][]string{{"user1"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"goals": 5},
+		})
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(duplicate)
+		assert.ErrorIs(t, err, models.ErrConflict)
+
+		// A conflicting write fails its whole transaction
+		tx := &dynamodb.TransactWriteItemsInput{}
+		_, err = matchRepo.CreateMatch(duplicate, tx)
+		assert.NoError(t, err)
+		stat := *before
+		stat.GameAttributes = models.AttributesStatsMap{"goals": goals + 5}
+		assert.NoError(t, gameStatRepo.UpdateGameStat(&stat, tx))
+		assert.ErrorIs(t, transactionRepo.ExecuteTransaction(tx), models.ErrConflict)
+
+		after, err := gameStatRepo.GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, goals, after.GameAttributes["goals"])
+		match, err := matchService.GetMatch("soccer", "match1", "2023-06-01")
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 1}, match.TeamScores)
+	})
+
+	// Test writes based on a stale version of a match or GameStat fail
+	t.Run("StaleVersions", func(t *testing.T) {
+		match, err := matchService.GetMatch("soccer", "match1", "2023-06-01")
+		assert.NoError(t, err)
+		stale := *match
+		stale.Version = match.Version + 1
+		_, err = matchService.UpdateMatch(&stale)
+		assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+
+		// Two writers read the same GameStat; the second one to write loses
+		first, err := gameStatRepo.GetGameStat("user2", "soccer")
+		assert.NoError(t, err)
+		second, err := gameStatRepo.GetGameStat("user2", "soccer")
+		assert.NoError(t, err)
+		goals := first.GameAttributes["goals"]
+
+		first.GameAttributes["goals"] = goals + 1
+		assert.NoError(t, gameStatRepo.UpdateGameStat(first, nil))
+		second.GameAttributes["goals"] = goals + 2
+		assert.ErrorIs(t, gameStatRepo.UpdateGameStat(second, nil), models.ErrPreconditionFailed)
+
+		gameStat, err := gameStatRepo.GetGameStat("user2", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, goals+1, gameStat.GameAttributes["goals"])
+		assert.Equal(t, first.Version, gameStat.Version)
+
+		// Restore the seeded value
+		gameStat.GameAttributes["goals"] = goals
+		assert.NoError(t, gameStatRepo.UpdateGameStat(gameStat, nil))
+	})
+
+	// Test concurrent matches of the same players neither lose increments nor
+	// leave stale leaderboard entries
+	t.Run("ConcurrentMatches", func(t *testing.T) {
+		before, err := gameStatRepo.GetGameStat("eveexplorer", "soccer")
+		assert.NoError(t, err)
+
+		const matches = 10
+		errs := make([]error, matches)
+		var wg sync.WaitGroup
+		for i := 0; i < matches; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				match, err := models.NewMatch(models.MatchID(fmt.Sprintf("concurrent%d", i)), "2023-06-20", "soccer", []string{"Team P", "Team Q"}, []int{1, 0}, [][]string{{"eveexplorer"}, {"dianadancer"}}, map[models.UserID]models.AttributesStatsMap{
+					"eveexplorer": {"elo": 1, "goals": 1},
+					"dianadancer": {"passes_completed": 2},
+				})
+				if err == nil {
+					_, err = matchService.CreateMatch(match)
+				}
+				errs[i] = err
+			}(i)
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+			} else {
+				// Only losing every retry may fail a match
+				assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+			}
+		}
+		assert.Greater(t, created, 0)
+
+		after, err := gameStatRepo.GetGameStat("eveexplorer", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, before.GameAttributes["goals"]+models.AttributeStat(created), after.GameAttributes["goals"])
+		assert.Equal(t, before.GameAttributes["elo"]+models.AttributeStat(created), after.GameAttributes["elo"])
+
+		// The player is on the leaderboard once, with the stored total
+		leaderboard, err := leaderboardRepo.GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		var values []models.AttributeStat
+		for _, entry := range leaderboard.Entries {
+			if entry.UserID == "eveexplorer" {
+				values = append(values, entry.Value)
+			}
+		}
+		assert.Equal(t, []models.AttributeStat{after.GameAttributes["elo"]}, values)
+
+		// Clean up: Delete the created matches
+		for i, err := range errs {
+			if err == nil {
+				assert.NoError(t, matchService.DeleteMatch("soccer", models.MatchID(fmt.Sprintf("concurrent%d", i)), "2023-06-20"))
+			}
+		}
+		restored, err := gameStatRepo.GetGameStat("eveexplorer", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, before.GameAttributes, restored.GameAttributes)
+	})
+
+	// Test matches that don't fit their game are rejected with every problem
+	t.Run("CreateMatchValidatesAgainstGame", func(t *testing.T) {
+		invalid := &models.Match{
+			MatchID:     "invalidmatch",
+			DateID:      "2023-06-15",
+			GameID:      "soccer",
+			TeamNames:   []string{"Team A", "Team B", "Team C"},
+			TeamScores:  []int{1, 0},
+			TeamMembers: [][]string{{"user1", "nobody"}, {"user1"}},
+			PlayerAttributesMap: map[models.UserID]models.AttributesStatsMap{
+				"user1": {"goals": 1, "pockets": 2},
+				"user2": {"goals": 1},
+			},
+		}
+		_, err := matchService.CreateMatch(invalid)
+		assert.ErrorIs(t, err, models.ErrValidation)
+		assert.Equal(t, []string{
+			"3 team names for 2 team scores",
+			"player user1 is in teams 1 and 2",
+			"attribute pockets of player user1 is not an attribute of game soccer",
+			"player user2 has attributes but is in no team",
+			"user nobody does not exist",
+		}, models.ErrorProblems(err))
+
+		// Nothing was written
+		_, err = matchService.GetMatch("soccer", "invalidmatch", "2023-06-15")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		// Updates are validated too
+		match, err := matchService.GetMatch("soccer", "match1", "2023-06-01")
+		assert.NoError(t, err)
+		match.PlayerAttributesMap["user1"]["pockets"] = 1
+		_, err = matchService.UpdateMatch(match)
+		assert.ErrorIs(t, err, models.ErrValidation)
+		assert.Equal(t, []string{"attribute pockets of player user1 is not an attribute of game soccer"}, models.ErrorProblems(err))
+	})
+
+	// Test CreateMatch with more writes than one transaction allows
+	t.Run("CreateMatchRejectsOversizedTransaction", func(t *testing.T) {
+		playerAttributes := map[models.UserID]models.AttributesStatsMap{}
+		var team []string
+		for i := 0; i < repositories.MaxTransactionItems; i++ {
+			userID := models.UserID(fmt.Sprintf("bulkuser%d", i))
+			user, err := models.NewUser(userID, string(userID), string(userID)+"@example.com", nil)
+			assert.NoError(t, err)
+			_, err = userRepo.CreateUser(user, nil)
+			assert.NoError(t, err)
+			playerAttributes[userID] = models.AttributesStatsMap{"goals": 1}
+			team = append(team, string(userID))
+		}
+		newMatch, err := models.NewMatch("bulkmatch", "2023-06-14", "soccer", []string{"Team K"}, []int{1}, [][]string{team}, playerAttributes)
+		assert.NoError(t, err)
+
+		_, err = matchService.CreateMatch(newMatch)
+		assert.Error(t, err)
+
+		// Verify nothing was written
+		_, err = matchService.GetMatch("soccer", "bulkmatch", "2023-06-14")
+		assert.Error(t, err)
+		_, err = gameStatRepo.GetGameStat("bulkuser0", "soccer")
+		assert.Error(t, err)
+	})
+
+	// Test matches whose scoped writes alone exceed one transaction
+	t.Run("CreateLargeMatch", func(t *testing.T) {
+		rugby, err := models.NewGame("rugby", "Rugby", []models.AttributeName{"tries", "tackles", "carries"}, []models.AttributeName{"tries", "tackles"})
+		assert.NoError(t, err)
+		rugby.RatingSystem = models.RatingSystemElo
+		_, err = gameRepo.CreateGame(rugby, nil)
+		assert.NoError(t, err)
+		season, err := models.NewSeason("rugby", "spring", "Spring", "2024-03-01", "2024-05-31")
+		assert.NoError(t, err)
+		_, err = seasonRepo.CreateSeason(season, nil)
+		assert.NoError(t, err)
+
+		playerAttributes := map[models.UserID]models.AttributesStatsMap{}
+		teams := [][]string{{}, {}}
+		for i := 0; i < 8; i++ {
+			userID := models.UserID(fmt.Sprintf("rugbyuser%d", i))
+			user, err := models.NewUser(userID, string(userID), string(userID)+"@example.com", nil)
+			assert.NoError(t, err)
+			_, err = userRepo.CreateUser(user, nil)
+			assert.NoError(t, err)
+			playerAttributes[userID] = models.AttributesStatsMap{"tries": models.AttributeStat(i % 3), "tackles": models.AttributeStat(i), "carries": 1}
+			teams[i%2] = append(teams[i%2], string(userID))
+		}
+		match, err := models.NewMatch("final", "2024-04-20", "rugby", []string{"Reds", "Blues"}, []int{21, 14}, teams, playerAttributes)
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(match)
+		assert.NoError(t, err)
+
+		// Move every player's entries in every scope
+		for userID, attributes := range playerAttributes {
+			attributes["tackles"] += 10
+			playerAttributes[userID] = attributes
+		}
+		match, err = matchService.GetMatch("rugby", "final", "2024-04-20")
+		assert.NoError(t, err)
+		match.PlayerAttributesMap = playerAttributes
+		_, err = matchService.UpdateMatch(match)
+		assert.NoError(t, err)
+
+		for _, scope := range []models.LeaderboardScope{models.AllTimeScope, "day.2024-04-20", "week.2024-W16", "month.2024-04", season.Scope()} {
+			gameStat, err := gameStatRepo.WithScope(scope).GetGameStat("rugbyuser7", "rugby")
+			assert.NoError(t, err)
+			assert.Equal(t, models.AttributesStatsMap{"tries": 1, "tackles": 17, "carries": 1}, gameStat.GameAttributes)
+
+			leaderboard, err := leaderboardRepo.WithScope(scope).GetLeaderboard("rugby", "tackles", models.SortDescending)
+			assert.NoError(t, err)
+			assert.Equal(t, 8, len(leaderboard.Entries))
+			assert.Equal(t, models.UserID("rugbyuser7"), leaderboard.Entries[0].UserID)
+			assert.Equal(t, models.AttributeStat(17), leaderboard.Entries[0].Value)
+
+			leaderboard, err = leaderboardRepo.WithScope(scope).GetLeaderboard("rugby", "tries", models.SortDescending)
+			assert.NoError(t, err)
+			assert.Equal(t, 8, len(leaderboard.Entries))
+		}
+
+		leaderboard, err := leaderboardRepo.GetLeaderboard("rugby", models.RatingAttribute, models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, len(leaderboard.Entries))
+	})
+
+	// Test matches are kept in their players' match history
+	t.Run("UserMatchHistory", func(t *testing.T) {
+		newMatch, err := models.NewMatch("historymatch", "2023-07-01", "pool", []string{"Team X", "Team Y"}, []int{1, 1}, [][]string{{"user1"}, {"user2"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"pockets": 3},
+			"user2": {"pockets": 2},
+		})
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(newMatch)
+		assert.NoError(t, err)
+
+		history, err := matchRepo.GetUserMatches("user2", models.UserMatchFilter{}, 1, "")
+		assert.NoError(t, err)
+		assert.Equal(t, models.MatchID("historymatch"), history.Matches[0].MatchID)
+		assert.Equal(t, models.MatchResultDraw, history.Matches[0].Result)
+
+		// Players dropped from the match lose it from their history
+		updatedMatch, err := models.NewMatch("historymatch", "2023-07-01", "pool", []string{"Team X", "Team Y"}, []int{1, 0}, [][]string{{"user1"}, {"user3"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"pockets": 3},
+		})
+		assert.NoError(t, err)
+		_, err = matchService.UpdateMatch(updatedMatch)
+		assert.NoError(t, err)
+
+		history, err = matchRepo.GetUserMatches("user2", models.UserMatchFilter{}, 1, "")
+		assert.NoError(t, err)
+		assert.NotEqual(t, models.MatchID("historymatch"), history.Matches[0].MatchID)
+		history, err = matchRepo.GetUserMatches("user3", models.UserMatchFilter{}, 1, "")
+		assert.NoError(t, err)
+		assert.Equal(t, models.MatchID("historymatch"), history.Matches[0].MatchID)
+		assert.Equal(t, models.MatchResultLoss, history.Matches[0].Result)
+
+		assert.NoError(t, matchService.DeleteMatch("pool", "historymatch", "2023-07-01"))
+		for _, userID := range []models.UserID{"user1", "user3"} {
+			history, err = matchRepo.GetUserMatches(userID, models.UserMatchFilter{GameID: "pool"}, 10, "")
+			assert.NoError(t, err)
+			for _, userMatch := range history.Matches {
+				assert.NotEqual(t, models.MatchID("historymatch"), userMatch.MatchID)
+			}
+		}
+	})
+
+	// Test ratings are computed from match results
+	t.Run("Ratings", func(t *testing.T) {
+		chess, err := models.NewGame("chess", "Chess", []models.AttributeName{"moves"}, []models.AttributeName{})
+		assert.NoError(t, err)
+		chess.RatingSystem = models.RatingSystemElo
+		_, err = gameRepo.CreateGame(chess, nil)
+		assert.NoError(t, err)
+
+		newMatch := func(matchID models.MatchID, dateID models.DateID, white, black string, whiteScore, blackScore int) *models.Match {
+			match, err := models.NewMatch(matchID, dateID, "chess", []string{"White", "Black"}, []int{whiteScore, blackScore}, [][]string{{white}, {black}}, nil)
+			assert.NoError(t, err)
+			return match
+		}
+
+		_, err = matchService.CreateMatch(newMatch("game1", "2024-01-02", "user1", "user2", 1, 0))
+		assert.NoError(t, err)
+
+		rating, err := ratingRepo.GetRating("chess", "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(1516), rating.Value)
+		assert.Equal(t, 1, rating.MatchesPlayed)
+
+		leaderboard, err := leaderboardRepo.GetLeaderboard("chess", models.RatingAttribute, models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1", "user2"}, leaderboard.UserIDs())
+		assert.Equal(t, models.AttributeStat(1484), leaderboard.Entries[1].Value)
+
+		// A match played before one its players already have requests a
+		// replay in history order, which runs in the background
+		_, err = matchService.CreateMatch(newMatch("game2", "2024-01-03", "user2", "user3", 1, 0))
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(newMatch("game0", "2024-01-01", "user3", "user1", 1, 0))
+		assert.NoError(t, err)
+		pending, err := ratingRepo.GetRatingRecompute("chess")
+		assert.NoError(t, err)
+		rating, err = ratingRepo.GetRating("chess", "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, rating.MatchesPlayed)
+
+		// Matches stored while it is pending join it instead of rating
+		// players who are about to be replayed
+		_, err = matchService.CreateMatch(newMatch("game3", "2024-01-04", "user1", "user2", 1, 1))
+		assert.NoError(t, err)
+		requested, err := ratingRepo.GetRatingRecompute("chess")
+		assert.NoError(t, err)
+		assert.Equal(t, pending.Version+1, requested.Version)
+
+		// A recompute requested again while it runs stays pending
+		assert.ErrorIs(t, ratingRepo.DeleteRatingRecompute(pending, nil), models.ErrPreconditionFailed)
+		assert.NoError(t, matchService.DeleteMatch("chess", "game3", "2024-01-04"))
+		recomputeRatings(t)
+
+		replayed := map[models.UserID]*models.Rating{}
+		for _, id := range []models.UserID{"user1", "user2", "user3"} {
+			replayed[id] = &models.Rating{Mu: 1500}
+		}
+		elo, err := ratings.NewCalculator(models.RatingSystemElo)
+		assert.NoError(t, err)
+		elo.Rate([][]*models.Rating{{replayed["user3"]}, {replayed["user1"]}}, []int{0, 1})
+		elo.Rate([][]*models.Rating{{replayed["user1"]}, {replayed["user2"]}}, []int{0, 1})
+		elo.Rate([][]*models.Rating{{replayed["user2"]}, {replayed["user3"]}}, []int{0, 1})
+		for id, expected := range replayed {
+			rating, err := ratingRepo.GetRating("chess", id)
+			assert.NoError(t, err)
+			assert.InDelta(t, expected.Mu, rating.Mu, 0.000001)
+		}
+		rating, err = ratingRepo.GetRating("chess", "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, models.DateID("2024-01-02"), rating.LastDateID)
+		assert.Equal(t, 2, rating.MatchesPlayed)
+
+		// Reversing a result recomputes the ratings
+		_, err = matchService.UpdateMatch(newMatch("game1", "2024-01-02", "user1", "user2", 0, 1))
+		assert.NoError(t, err)
+		recomputeRatings(t)
+		user1, err := ratingRepo.GetRating("chess", "user1")
+		assert.NoError(t, err)
+		user2, err := ratingRepo.GetRating("chess", "user2")
+		assert.NoError(t, err)
+		assert.Less(t, user1.Mu, 1500.0)
+		assert.Greater(t, user2.Mu, 1500.0)
+
+		// Deleting every match leaves no ratings behind
+		assert.NoError(t, matchService.DeleteMatch("chess", "game0", "2024-01-01"))
+		assert.NoError(t, matchService.DeleteMatch("chess", "game1", "2024-01-02"))
+		assert.NoError(t, matchService.DeleteMatch("chess", "game2", "2024-01-03"))
+		recomputeRatings(t)
+		_, err = ratingRepo.GetRating("chess", "user1")
+		assert.Error(t, err)
+		leaderboard, err = leaderboardRepo.GetLeaderboard("chess", models.RatingAttribute, models.SortDescending)
+		assert.NoError(t, err)
+		assert.Empty(t, leaderboard.Entries)
+	})
+
+	// Test matches of deleted users keep their anonymous players
+	t.Run("AnonymousPlayers", func(t *testing.T) {
+		newMatch := func(matchID models.MatchID, teamMembers [][]string) *models.Match {
+			return &models.Match{MatchID: matchID, DateID: "2024-07-01", GameID: "soccer", TeamNames: []string{"Team A", "Team B"}, TeamScores: []int{1, 0}, TeamMembers: teamMembers}
+		}
+
+		// New matches cannot have anonymous players
+		_, err := matchService.CreateMatch(newMatch("anonymous", [][]string{{"user1"}, {"~deleted-1"}}))
+		assert.ErrorIs(t, err, models.ErrValidation)
+
+		match, err := matchService.CreateMatch(newMatch("anonymized", [][]string{{"user1"}, {"user2"}}))
+		assert.NoError(t, err)
+		assert.True(t, match.Anonymize("user2"))
+		match, err = matchRepo.UpdateMatch(match, nil)
+		assert.NoError(t, err)
+
+		// But an update keeps those of the stored match, without attributes
+		update := newMatch("anonymized", [][]string{{"user1"}, {"~deleted-1"}})
+		update.TeamScores = []int{0, 1}
+		update.Version = match.Version
+		_, err = matchService.UpdateMatch(update)
+		assert.NoError(t, err)
+		_, err = ratingRepo.GetRating("soccer", "~deleted-1")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		update = newMatch("anonymized", [][]string{{"user1"}, {"~deleted-1"}})
+		update.PlayerAttributesMap = map[models.UserID]models.AttributesStatsMap{"~deleted-1": {"goals": 1}}
+		_, err = matchService.UpdateMatch(update)
+		assert.ErrorIs(t, err, models.ErrValidation)
+		assert.Contains(t, models.ErrorProblems(err), "player ~deleted-1 was deleted and cannot have attributes")
+		// Even empty ones, which would give them a GameStat and leaderboard entries
+		update.PlayerAttributesMap = map[models.UserID]models.AttributesStatsMap{"~deleted-1": {}}
+		_, err = matchService.UpdateMatch(update)
+		assert.ErrorIs(t, err, models.ErrValidation)
+
+		// Attributes a stored match has for them are not applied either
+		match, err = matchRepo.GetMatch("soccer", "anonymized", "2024-07-01")
+		assert.NoError(t, err)
+		match.PlayerAttributesMap = map[models.UserID]models.AttributesStatsMap{"~deleted-1": {}}
+		_, err = matchRepo.UpdateMatch(match, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, matchService.DeleteMatch("soccer", "anonymized", "2024-07-01"))
+		day, err := models.NewPeriodScope(models.PeriodDay, "2024-07-01")
+		assert.NoError(t, err)
+		for _, scope := range []models.LeaderboardScope{models.AllTimeScope, day} {
+			_, err = gameStatRepo.WithScope(scope).GetGameStat("~deleted-1", "soccer")
+			assert.ErrorIs(t, err, models.ErrNotFound, "scope %q", scope)
+			leaderboard, err := leaderboardRepo.WithScope(scope).GetLeaderboard("soccer", "elo", models.SortDescending)
+			assert.NoError(t, err)
+			assert.NotContains(t, leaderboard.UserIDs(), models.UserID("~deleted-1"))
+		}
+	})
+
+	// Test matches keep their players' GamesPlayed up to date
+	t.Run("GamesPlayed", func(t *testing.T) {
+		newMatch := func(matchID models.MatchID, teamMembers [][]string) *models.Match {
+			return &models.Match{MatchID: matchID, DateID: "2024-07-02", GameID: "soccer", TeamNames: []string{"Team A", "Team B"}, TeamScores: []int{1, 0}, TeamMembers: teamMembers}
+		}
+		gamesPlayed := func(userID models.UserID) []models.GameID {
+			user, err := userRepo.GetUser(userID)
+			assert.NoError(t, err)
+			return user.GamesPlayed
+		}
+		assert.Equal(t, []models.GameID{"pickleball", "pool"}, gamesPlayed("eveexplorer"))
+
+		// Creating a match adds the game
+		before, err := userRepo.GetUser("eveexplorer")
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(newMatch("played1", [][]string{{"user1"}, {"eveexplorer"}}))
+		assert.NoError(t, err)
+		after, err := userRepo.GetUser("eveexplorer")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.GameID{"pickleball", "pool", "soccer"}, after.GamesPlayed)
+		assert.Equal(t, before.Version+1, after.Version)
+		_, err = matchService.CreateMatch(newMatch("played2", [][]string{{"eveexplorer"}, {"user2"}}))
+		assert.NoError(t, err)
+		assert.Equal(t, []models.GameID{"pickleball", "pool", "soccer"}, gamesPlayed("eveexplorer"))
+
+		// A user read before the match can't be written back over it
+		before.Username = "Eve"
+		_, err = userRepo.UpdateUser(before, nil)
+		assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+
+		// The game stays while another match of it is left
+		assert.NoError(t, matchService.DeleteMatch("soccer", "played1", "2024-07-02"))
+		assert.Contains(t, gamesPlayed("eveexplorer"), models.GameID("soccer"))
+
+		// Dropping the player from their last match removes it
+		match, err := matchService.GetMatch("soccer", "played2", "2024-07-02")
+		assert.NoError(t, err)
+		update := newMatch("played2", [][]string{{"user3"}, {"user2"}})
+		update.Version = match.Version
+		_, err = matchService.UpdateMatch(update)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.GameID{"pickleball", "pool"}, gamesPlayed("eveexplorer"))
+
+		// Adding them back adds it again, and deleting the match removes it
+		match, err = matchService.GetMatch("soccer", "played2", "2024-07-02")
+		assert.NoError(t, err)
+		update = newMatch("played2", [][]string{{"eveexplorer"}, {"user2"}})
+		update.Version = match.Version
+		_, err = matchService.UpdateMatch(update)
+		assert.NoError(t, err)
+		assert.Contains(t, gamesPlayed("eveexplorer"), models.GameID("soccer"))
+		assert.NoError(t, matchService.DeleteMatch("soccer", "played2", "2024-07-02"))
+		assert.Equal(t, []models.GameID{"pickleball", "pool"}, gamesPlayed("eveexplorer"))
+
+		// Players with other matches of the game keep it
+		assert.Contains(t, gamesPlayed("user2"), models.GameID("soccer"))
+	})
+
+	// Test attributes are aggregated with their game's mode
+	t.Run("Aggregations", func(t *testing.T) {
+		racing, err := models.NewGame("racing", "Racing", []models.AttributeName{"laps", "best_lap", "streak", "form", "accuracy"}, []models.AttributeName{"best_lap"})
+		assert.NoError(t, err)
+		racing.Aggregations = map[models.AttributeName]models.AggregationMode{
+			"best_lap": models.AggregationMin,
+			"streak":   models.AggregationMax,
+			"form":     models.AggregationLast,
+			"accuracy": models.AggregationAvg,
+		}
+		_, err = gameRepo.CreateGame(racing, nil)
+		assert.NoError(t, err)
+
+		newRace := func(matchID models.MatchID, dateID models.DateID, attributes map[models.UserID]models.AttributesStatsMap) *models.Match {
+			match, err := models.NewMatch(matchID, dateID, "racing", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, attributes)
+			assert.NoError(t, err)
+			return match
+		}
+		gameStat := func(userID models.UserID, scope models.LeaderboardScope) models.AttributesStatsMap {
+			gameStat, err := gameStatRepo.WithScope(scope).GetGameStat(userID, "racing")
+			assert.NoError(t, err)
+			return gameStat.GameAttributes
+		}
+
+		// The later race is stored first, so the last value comes from it
+		_, err = matchService.CreateMatch(newRace("race2", "2024-08-02", map[models.UserID]models.AttributesStatsMap{
+			"user1": {"laps": 12, "best_lap": 92, "streak": 2, "form": 4, "accuracy": 91},
+			"user2": {"laps": 8, "best_lap": 99, "streak": 4, "form": 1},
+		}))
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(newRace("race1", "2024-08-01", map[models.UserID]models.AttributesStatsMap{
+			"user1": {"laps": 10, "best_lap": 95, "streak": 3, "form": 2, "accuracy": 80},
+			"user2": {"laps": 10, "best_lap": 90, "streak": 1, "form": 5, "accuracy": 70},
+		}))
+		assert.NoError(t, err)
+
+		assert.Equal(t, models.AttributesStatsMap{"laps": 22, "best_lap": 92, "streak": 3, "form": 4, "accuracy": 85}, gameStat("user1", models.AllTimeScope))
+		assert.Equal(t, models.AttributesStatsMap{"laps": 18, "best_lap": 90, "streak": 4, "form": 1, "accuracy": 70}, gameStat("user2", models.AllTimeScope))
+		assert.Equal(t, models.AttributesStatsMap{"laps": 10, "best_lap": 95, "streak": 3, "form": 2, "accuracy": 80}, gameStat("user1", "day.2024-08-01"))
+
+		leaderboard, err := leaderboardRepo.GetLeaderboard("racing", "best_lap", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1", "user2"}, leaderboard.UserIDs())
+		assert.Equal(t, models.AttributeStat(92), leaderboard.Entries[0].Value)
+
+		// Updating a minimum recomputes it from the other race
+		race2, err := matchService.GetMatch("racing", "race2", "2024-08-02")
+		assert.NoError(t, err)
+		race2.PlayerAttributesMap["user1"]["best_lap"] = 97
+		_, err = matchService.UpdateMatch(race2)
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(95), gameStat("user1", models.AllTimeScope)["best_lap"])
+		assert.Equal(t, models.AttributeStat(97), gameStat("user1", "day.2024-08-02")["best_lap"])
+		leaderboard, err = leaderboardRepo.GetLeaderboard("racing", "best_lap", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(95), leaderboard.Entries[0].Value)
+
+		// Deleting a race leaves the aggregates of the remaining one
+		assert.NoError(t, matchService.DeleteMatch("racing", "race1", "2024-08-01"))
+		assert.Equal(t, models.AttributesStatsMap{"laps": 12, "best_lap": 97, "streak": 2, "form": 4, "accuracy": 91}, gameStat("user1", models.AllTimeScope))
+		assert.Equal(t, models.AttributesStatsMap{"laps": 8, "best_lap": 99, "streak": 4, "form": 1, "accuracy": 0}, gameStat("user2", models.AllTimeScope))
+		assert.Equal(t, models.AttributesStatsMap{"laps": 0, "best_lap": 0, "streak": 0, "form": 0, "accuracy": 0}, gameStat("user1", "day.2024-08-01"))
+		leaderboard, err = leaderboardRepo.GetLeaderboard("racing", "best_lap", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "user1"}, leaderboard.UserIDs())
+		assert.Equal(t, models.AttributeStat(99), leaderboard.Entries[0].Value)
+	})
+
+	// Test players are ranked from their first match even with values of 0
+	t.Run("FirstValueZero", func(t *testing.T) {
+		darts, err := models.NewGame("darts", "Darts", []models.AttributeName{"bullseyes", "score"}, []models.AttributeName{"bullseyes", "score"})
+		assert.NoError(t, err)
+		_, err = gameRepo.CreateGame(darts, nil)
+		assert.NoError(t, err)
+
+		match, err := models.NewMatch("leg1", "2024-09-01", "darts", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"bullseyes": 0, "score": 5},
+			"user2": {"score": 3},
+		})
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(match)
+		assert.NoError(t, err)
+
+		for _, scope := range []models.LeaderboardScope{models.AllTimeScope, "day.2024-09-01"} {
+			leaderboard, err := leaderboardRepo.WithScope(scope).GetLeaderboard("darts", "bullseyes", models.SortDescending)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []models.UserID{"user1", "user2"}, leaderboard.UserIDs())
+
+			aroundUser, err := leaderboardRepo.WithScope(scope).GetLeaderboardAroundUser("darts", "bullseyes", models.SortDescending, "user2", 0, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, len(aroundUser.Entries))
+		}
+
+		// A second match moves the entries instead of adding new ones
+		match, err = models.NewMatch("leg2", "2024-09-01", "darts", []string{"Team A", "Team B"}, []int{0, 1}, [][]string{{"user1"}, {"user2"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"bullseyes": 1},
+			"user2": {"bullseyes": 0},
+		})
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(match)
+		assert.NoError(t, err)
+		leaderboard, err := leaderboardRepo.GetLeaderboard("darts", "bullseyes", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1", "user2"}, leaderboard.UserIDs())
+		assert.Equal(t, []models.AttributeStat{1, 0}, []models.AttributeStat{leaderboard.Entries[0].Value, leaderboard.Entries[1].Value})
+	})
 
 	// Test DeleteMatch
+}
+
+// failingHistoryMatchRepository fails every GetUserMatches until failures
+// have been used up.
+type failingHistoryMatchRepository struct {
+	repositories.MatchRepository
+	failures int
+}
 
+func (r *failingHistoryMatchRepository) GetUserMatches(userID models.UserID, filter models.UserMatchFilter, limit int, cursor string) (models.UserMatchHistory, error) {
+	if r.failures > 0 {
+		r.failures--
+		return models.UserMatchHistory{}, errors.New("connection reset")
+	}
+	return r.MatchRepository.GetUserMatches(userID, filter, limit, cursor)
 }
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mquan1409/game-api/internal/models"
+	"github.com/mquan1409/game-api/internal/repositories/inmemory"
+	"github.com/mquan1409/game-api/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeasonService(t *testing.T) {
+	// Setup
+	store := inmemory.NewSeededStore()
+
+	seasonRepo := inmemory.NewInMemorySeasonRepository(store)
+	matchRepo := inmemory.NewInMemoryMatchRepository(store)
+	gameRepo := inmemory.NewInMemoryGameRepository(store)
+	userRepo := inmemory.NewInMemoryUserRepository(store)
+	gameStatRepo := inmemory.NewInMemoryGameStatRepository(store)
+	leaderboardRepo := inmemory.NewInMemoryLeaderboardRepository(store)
+	transactionRepo := inmemory.NewInMemoryTransactionRepository(store)
+	seasonService := services.NewSeasonServiceImpl(seasonRepo, gameRepo, gameStatRepo, leaderboardRepo, matchRepo)
+	ratingRepo := inmemory.NewInMemoryRatingRepository(store)
+	matchService := services.NewMatchServiceImpl(matchRepo, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+
+	newMatch := func(matchID models.MatchID, dateID models.DateID, user1Elo, user2Elo models.AttributeStat) *models.Match {
+		match, err := models.NewMatch(matchID, dateID, "soccer", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"user1"}, {"user2"}}, map[models.UserID]models.AttributesStatsMap{
+			"user1": {"elo": user1Elo},
+			"user2": {"elo": user2Elo},
+		})
+		assert.NoError(t, err)
+		return match
+	}
+
+	// Test CreateSeason
+	t.Run("CreateSeason", func(t *testing.T) {
+		season, err := models.NewSeason("soccer", "2024-summer", "Summer 2024", "2024-06-01", "2024-08-31")
+		assert.NoError(t, err)
+		createdSeason, err := seasonService.CreateSeason(season)
+		assert.NoError(t, err)
+		assert.False(t, createdSeason.Closed)
+
+		retrievedSeason, err := seasonService.GetSeason("soccer", "2024-summer")
+		assert.NoError(t, err)
+		assert.Equal(t, "Summer 2024", retrievedSeason.Name)
+
+		// Seasons of a game may not overlap
+		overlapping, err := models.NewSeason("soccer", "2024-late", "Late 2024", "2024-08-01", "2024-12-31")
+		assert.NoError(t, err)
+		_, err = seasonService.CreateSeason(overlapping)
+		assert.ErrorIs(t, err, models.ErrConflict)
+
+		// Unknown games and duplicate IDs are rejected
+		unknownGame, err := models.NewSeason("chess", "2024-summer", "Summer 2024", "2024-06-01", "2024-08-31")
+		assert.NoError(t, err)
+		_, err = seasonService.CreateSeason(unknownGame)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		_, err = seasonService.CreateSeason(season)
+		assert.ErrorIs(t, err, models.ErrConflict)
+
+		autumn, err := models.NewSeason("soccer", "2024-autumn", "Autumn 2024", "2024-09-01", "2024-11-30")
+		assert.NoError(t, err)
+		_, err = seasonService.CreateSeason(autumn)
+		assert.NoError(t, err)
+
+		seasons, err := seasonService.GetSeasonsByGame("soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(seasons))
+	})
+
+	// Test NewSeason validation
+	t.Run("NewSeasonValidation", func(t *testing.T) {
+		_, err := models.NewSeason("soccer", "bad", "Bad", "2024-08-31", "2024-06-01")
+		assert.Error(t, err)
+		_, err = models.NewSeason("soccer", "bad", "Bad", "2024-06-01", "end of summer")
+		assert.Error(t, err)
+		_, err = models.NewSeason("soccer", "", "Bad", "2024-06-01", "2024-08-31")
+		assert.Error(t, err)
+	})
+
+	// Test matches count towards the season containing their DateID
+	t.Run("SeasonLeaderboards", func(t *testing.T) {
+		_, err := matchService.CreateMatch(newMatch("summermatch", "2024-07-01", 2, 5))
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(newMatch("autumnmatch", "2024-09-15", 4, 1))
+		assert.NoError(t, err)
+
+		summer := models.SeasonScope("2024-summer")
+		leaderboard, err := leaderboardRepo.WithScope(summer).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user2", "user1"}, leaderboard.UserIDs())
+
+		gameStat, err := gameStatRepo.WithScope(summer).GetGameStat("user2", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(5), gameStat.GameAttributes["elo"])
+
+		autumn := models.SeasonScope("2024-autumn")
+		leaderboard, err = leaderboardRepo.WithScope(autumn).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.UserID{"user1", "user2"}, leaderboard.UserIDs())
+	})
+
+	// Test closing a season freezes its standings
+	t.Run("CloseSeason", func(t *testing.T) {
+		closedSeason, err := seasonService.CloseSeason("soccer", "2024-summer")
+		assert.NoError(t, err)
+		assert.True(t, closedSeason.Closed)
+
+		summer := models.SeasonScope("2024-summer")
+		before, err := leaderboardRepo.WithScope(summer).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+
+		// New, updated and deleted matches no longer change the season
+		_, err = matchService.CreateMatch(newMatch("latesummermatch", "2024-08-01", 10, 0))
+		assert.NoError(t, err)
+		_, err = matchService.UpdateMatch(newMatch("summermatch", "2024-07-01", 9, 0))
+		assert.NoError(t, err)
+
+		after, err := leaderboardRepo.WithScope(summer).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+
+		// The all-time leaderboard still moves
+		gameStat, err := gameStatRepo.GetGameStat("user1", "soccer")
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(2+9+4+10), gameStat.GameAttributes["elo"])
+
+		// Closed seasons cannot be edited
+		season, err := models.NewSeason("soccer", "2024-summer", "Renamed", "2024-06-01", "2024-08-31")
+		assert.NoError(t, err)
+		_, err = seasonService.UpdateSeason(season)
+		assert.ErrorIs(t, err, models.ErrForbidden)
+	})
+
+	// Test UpdateSeason
+	t.Run("UpdateSeason", func(t *testing.T) {
+		season, err := models.NewSeason("soccer", "2024-autumn", "Fall 2024", "2024-09-01", "2024-11-30")
+		assert.NoError(t, err)
+		updatedSeason, err := seasonService.UpdateSeason(season)
+		assert.NoError(t, err)
+		assert.Equal(t, "Fall 2024", updatedSeason.Name)
+
+		// The dates of a season with matches cannot change, as its standings
+		// count them
+		season, err = models.NewSeason("soccer", "2024-autumn", "Fall 2024", "2024-09-01", "2024-12-15")
+		assert.NoError(t, err)
+		_, err = seasonService.UpdateSeason(season)
+		assert.ErrorIs(t, err, models.ErrConflict)
+
+		// Updates may not overlap another season either
+		season, err = models.NewSeason("soccer", "2024-autumn", "Fall 2024", "2024-08-15", "2024-11-30")
+		assert.NoError(t, err)
+		_, err = seasonService.UpdateSeason(season)
+		assert.Error(t, err)
+
+		// A season without matches can move, but not onto dates with matches
+		season, err = models.NewSeason("soccer", "2025-winter", "Winter 2025", "2025-01-01", "2025-02-28")
+		assert.NoError(t, err)
+		_, err = seasonService.CreateSeason(season)
+		assert.NoError(t, err)
+		_, err = matchService.CreateMatch(newMatch("springmatch", "2025-03-20", 1, 0))
+		assert.NoError(t, err)
+		season.StartDate, season.EndDate = "2025-01-15", "2025-03-15"
+		_, err = seasonService.UpdateSeason(season)
+		assert.NoError(t, err)
+		season.EndDate = "2025-03-31"
+		_, err = seasonService.UpdateSeason(season)
+		assert.ErrorIs(t, err, models.ErrConflict)
+	})
+
+	// Test DeleteSeason
+	t.Run("DeleteSeason", func(t *testing.T) {
+		// GameStats are found without going through the leaderboards
+		autumn := models.SeasonScope("2024-autumn")
+		unranked, err := models.NewGameStat("user3", "soccer", models.AttributesStatsMap{"goals": 1})
+		assert.NoError(t, err)
+		assert.NoError(t, gameStatRepo.WithScope(autumn).CreateGameStat(unranked, nil))
+
+		err = seasonService.DeleteSeason("soccer", "2024-autumn")
+		assert.NoError(t, err)
+
+		_, err = seasonService.GetSeason("soccer", "2024-autumn")
+		assert.Error(t, err)
+
+		leaderboard, err := leaderboardRepo.WithScope(autumn).GetLeaderboard("soccer", "elo", models.SortDescending)
+		assert.NoError(t, err)
+		assert.Empty(t, leaderboard.Entries)
+		_, err = gameStatRepo.WithScope(autumn).GetGameStat("user1", "soccer")
+		assert.Error(t, err)
+		_, err = gameStatRepo.WithScope(autumn).GetGameStat("user3", "soccer")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+}
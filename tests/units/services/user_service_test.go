@@ -1,34 +1,31 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/stretchr/testify/assert"
-	"github.com/mquan1409/game-api/internal/config"
-	"github.com/mquan1409/game-api/internal/utils"
 	"github.com/mquan1409/game-api/internal/models"
-	"github.com/mquan1409/game-api/internal/services"
 	"github.com/mquan1409/game-api/internal/repositories"
+	"github.com/mquan1409/game-api/internal/repositories/inmemory"
+	"github.com/mquan1409/game-api/internal/services"
 )
 
 func TestUserService(t *testing.T) {
-	// Load test configuration
-	cfg := config.LoadConfig("development")
-
 	// Setup
-	db, err := utils.SetupTestDB(&cfg)
-	if err != nil {
-		t.Fatalf("Failed to setup test DB: %v", err)
-	}
+	store := inmemory.NewSeededStore()
 
-	userRepo := repositories.NewDynamoDBUserRepository(db, cfg.TableName)
-	gameStatRepo := repositories.NewDynamoDBGameStatRepository(db, cfg.TableName)
-	userService := services.NewUserServiceImpl(userRepo, gameStatRepo)
-
-	// Scan the entire table before tests
-	beforeScan, err := utils.ScanEntireTable(db, cfg.TableName)
-	if err != nil {
-		t.Fatalf("Failed to scan table before tests: %v", err)
-	}
+	userRepo := inmemory.NewInMemoryUserRepository(store)
+	gameStatRepo := inmemory.NewInMemoryGameStatRepository(store)
+	matchRepo := inmemory.NewInMemoryMatchRepository(store)
+	gameRepo := inmemory.NewInMemoryGameRepository(store)
+	leaderboardRepo := inmemory.NewInMemoryLeaderboardRepository(store)
+	seasonRepo := inmemory.NewInMemorySeasonRepository(store)
+	ratingRepo := inmemory.NewInMemoryRatingRepository(store)
+	transactionRepo := inmemory.NewInMemoryTransactionRepository(store)
+	userService := services.NewUserServiceImpl(userRepo, gameStatRepo, matchRepo, gameRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+	matchService := services.NewMatchServiceImpl(matchRepo, gameRepo, userRepo, gameStatRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
 
 	// Test GetUser
 	t.Run("GetUser", func(t *testing.T) {
@@ -52,7 +49,7 @@ func TestUserService(t *testing.T) {
 
 	// Test GetGameStatByIDs
 	t.Run("GetGameStat", func(t *testing.T) {
-		gameStat, err := userService.GetGameStat("user1", "soccer")
+		gameStat, err := userService.GetGameStat("user1", "soccer", models.AllTimeScope)
 		assert.NoError(t, err)
 		assert.NotNil(t, gameStat)
 		assert.Equal(t, models.UserID("user1"), gameStat.UserID)
@@ -60,9 +57,86 @@ func TestUserService(t *testing.T) {
 		assert.Equal(t, models.AttributeStat(1), gameStat.GameAttributes["goals"])
 	})
 
+	// Test GetGameStats returns every game of the user, narrowed to attributes
+	t.Run("GetGameStats", func(t *testing.T) {
+		gameStats, err := userService.GetGameStats("user1", models.AllTimeScope, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(gameStats))
+		assert.Equal(t, models.GameID("pickleball"), gameStats[0].GameID)
+		assert.Equal(t, models.GameID("pool"), gameStats[1].GameID)
+		assert.Equal(t, models.GameID("soccer"), gameStats[2].GameID)
+		assert.Equal(t, models.AttributeStat(1), gameStats[2].GameAttributes["goals"])
+		assert.Equal(t, models.AttributeStat(20), gameStats[2].GameAttributes["passes_completed"])
+
+		gameStats, err = userService.GetGameStats("user1", models.AllTimeScope, []models.AttributeName{"elo", "goals"})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(gameStats))
+		assert.Equal(t, models.AttributesStatsMap{"elo": 9}, gameStats[0].GameAttributes)
+		assert.Equal(t, models.AttributesStatsMap{"elo": 2, "goals": 1}, gameStats[2].GameAttributes)
+
+		// GameStats of other scopes are left out
+		gameStats, err = userService.GetGameStats("user1", models.LeaderboardScope("day.2000-01-01"), nil)
+		assert.NoError(t, err)
+		assert.Empty(t, gameStats)
+
+		// GameStats of other scopes are read per game the user played
+		day := models.LeaderboardScope("day.2000-01-02")
+		err = gameStatRepo.WithScope(day).CreateGameStat(&models.GameStat{UserID: "user1", GameID: "soccer", GameAttributes: models.AttributesStatsMap{"goals": 2, "elo": 1}}, nil)
+		assert.NoError(t, err)
+		gameStats, err = userService.GetGameStats("user1", day, []models.AttributeName{"goals"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(gameStats))
+		assert.Equal(t, models.GameID("soccer"), gameStats[0].GameID)
+		assert.Equal(t, models.AttributesStatsMap{"goals": 2}, gameStats[0].GameAttributes)
+	})
+
+	// Test GetUserMatches
+	t.Run("GetUserMatches", func(t *testing.T) {
+		history, err := userService.GetUserMatches("user1", models.UserMatchFilter{}, 2, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(history.Matches))
+		assert.Equal(t, models.MatchID("match3"), history.Matches[0].MatchID)
+		assert.Equal(t, models.MatchResultLoss, history.Matches[0].Result)
+		assert.Equal(t, "Team Beta", history.Matches[0].TeamName)
+		assert.Equal(t, models.AttributeStat(5), history.Matches[0].Attributes["dinks"])
+		assert.Equal(t, models.MatchID("match2"), history.Matches[1].MatchID)
+		assert.NotEmpty(t, history.NextCursor)
+
+		// Follow the cursor to the oldest match
+		history, err = userService.GetUserMatches("user1", models.UserMatchFilter{}, 2, history.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(history.Matches))
+		assert.Equal(t, models.MatchID("match1"), history.Matches[0].MatchID)
+		assert.Equal(t, models.MatchResultWin, history.Matches[0].Result)
+		assert.Empty(t, history.NextCursor)
+
+		// Filter by game and by date
+		filter, err := models.NewUserMatchFilter("pool", "", "")
+		assert.NoError(t, err)
+		history, err = userService.GetUserMatches("user1", filter, 10, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(history.Matches))
+		assert.Equal(t, models.GameID("pool"), history.Matches[0].GameID)
+
+		filter, err = models.NewUserMatchFilter("", "2023-06-01", "2023-06-02")
+		assert.NoError(t, err)
+		history, err = userService.GetUserMatches("user1", filter, 10, "")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(history.Matches))
+		assert.Equal(t, models.MatchID("match2"), history.Matches[0].MatchID)
+
+		_, err = models.NewUserMatchFilter("", "2023-06-02", "2023-06-01")
+		assert.Error(t, err)
+		_, err = models.NewUserMatchFilter("", "June 1st", "")
+		assert.Error(t, err)
+
+		_, err = userService.GetUserMatches("user1", filter, 10, "not a cursor")
+		assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+	})
+
 	// Test CreateUser
 	t.Run("CreateUser", func(t *testing.T) {
-		newUser, err := models.NewUser("user6", "TestUser", "test@example.com", []models.GameID{})
+		newUser, err := models.NewUser("user6", "TestUser", "test@example.com", []models.GameID{"soccer"})
 		assert.NoError(t, err)
 		createdUser, err := userService.CreateUser(newUser)
 		assert.NoError(t, err)
@@ -70,11 +144,26 @@ func TestUserService(t *testing.T) {
 		assert.Equal(t, newUser.Username, createdUser.Username)
 		assert.Equal(t, newUser.Email, createdUser.Email)
 
+		// GamesPlayed comes from the user's matches only
+		assert.Empty(t, createdUser.GamesPlayed)
+
 		// Clean up: Delete the created user
 		err = userService.DeleteUser(&createdUser.UserID)
 		assert.NoError(t, err)
 	})
 
+	// Test CreateUser does not overwrite an existing user
+	t.Run("CreateExistingUser", func(t *testing.T) {
+		duplicate, err := models.NewUser("user1", "Impostor", "impostor@example.com", []models.GameID{})
+		assert.NoError(t, err)
+		_, err = userService.CreateUser(duplicate)
+		assert.ErrorIs(t, err, models.ErrConflict)
+
+		user, err := userService.GetUser("user1")
+		assert.NoError(t, err)
+		assert.Equal(t, "AliceWonder", user.Username)
+	})
+
 	// Test UpdateUser
 	t.Run("UpdateUser", func(t *testing.T) {
 		user, err := userService.GetUser("user2")
@@ -90,6 +179,13 @@ func TestUserService(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, updatedUser, result)
 
+		// Updates keep the stored GamesPlayed
+		overwrite, err := models.NewUser(user.UserID, "UpdatedUser", "updated@example.com", []models.GameID{"chess"})
+		assert.NoError(t, err)
+		result, err = userService.UpdateUser(overwrite)
+		assert.NoError(t, err)
+		assert.Equal(t, user.GamesPlayed, result.GamesPlayed)
+
 		// Clean up: Revert the user to original state
 		revertedUser, err := models.NewUser(user.UserID, originalUsername, originalEmail, user.GamesPlayed)
 		assert.NoError(t, err)
@@ -97,6 +193,80 @@ func TestUserService(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	// Test UpdateUser only applies updates based on the stored version
+	t.Run("UpdateUserVersion", func(t *testing.T) {
+		user, err := userService.GetUser("user3")
+		assert.NoError(t, err)
+		version := user.Version
+
+		renamed, err := models.NewUser(user.UserID, "Charlie", user.Email, user.GamesPlayed)
+		assert.NoError(t, err)
+		renamed.Version = version
+		updatedUser, err := userService.UpdateUser(renamed)
+		assert.NoError(t, err)
+		assert.Equal(t, version+1, updatedUser.Version)
+
+		// An update based on the version read before is stale now
+		stale, err := models.NewUser(user.UserID, "Chaplin", user.Email, user.GamesPlayed)
+		assert.NoError(t, err)
+		stale.Version = version
+		_, err = userService.UpdateUser(stale)
+		assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+
+		user, err = userService.GetUser("user3")
+		assert.NoError(t, err)
+		assert.Equal(t, "Charlie", user.Username)
+		assert.Equal(t, version+1, user.Version)
+
+		// Without a version the update applies to the stored one
+		user.Username = "CharlieChaplin"
+		user.Version = 0
+		updatedUser, err = userService.UpdateUser(user)
+		assert.NoError(t, err)
+		assert.Equal(t, version+2, updatedUser.Version)
+	})
+
+	// Test UpdateUser without a version retries when a match changes
+	// GamesPlayed between its read and its write
+	t.Run("UpdateUserRacingGamePlayed", func(t *testing.T) {
+		racingRepo := &racingUserRepository{UserRepository: userRepo, gameID: "chess", races: 1}
+		racingService := services.NewUserServiceImpl(racingRepo, gameStatRepo, matchRepo, gameRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+
+		user, err := userService.GetUser("user2")
+		assert.NoError(t, err)
+		version := user.Version
+
+		renamed, err := models.NewUser(user.UserID, "Bobby", user.Email, user.GamesPlayed)
+		assert.NoError(t, err)
+		updatedUser, err := racingService.UpdateUser(renamed)
+		assert.NoError(t, err)
+		assert.Equal(t, version+2, updatedUser.Version)
+
+		user, err = userService.GetUser("user2")
+		assert.NoError(t, err)
+		assert.Equal(t, "Bobby", user.Username)
+		assert.Contains(t, user.GamesPlayed, models.GameID("chess"))
+
+		// A versioned update still fails on the changed version
+		racingRepo.races = 1
+		stale, err := models.NewUser(user.UserID, "Robert", user.Email, user.GamesPlayed)
+		assert.NoError(t, err)
+		stale.Version = user.Version
+		_, err = racingService.UpdateUser(stale)
+		assert.ErrorIs(t, err, models.ErrPreconditionFailed)
+	})
+
+	// Test UpdateUser does not create a missing user
+	t.Run("UpdateMissingUser", func(t *testing.T) {
+		missing, err := models.NewUser("nobody", "Nobody", "nobody@example.com", []models.GameID{})
+		assert.NoError(t, err)
+		_, err = userService.UpdateUser(missing)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+
+		_, err = userService.GetUser("nobody")
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+
 	// Test DeleteUser
 	t.Run("DeleteUser", func(t *testing.T) {
 		newUser, err := models.NewUser("tempuser", "TempUser", "temp@example.com", []models.GameID{})
@@ -110,16 +280,145 @@ func TestUserService(t *testing.T) {
 		// Verify user is deleted
 		_, err = userService.GetUser(createdUser.UserID)
 		assert.Error(t, err)
+
+		err = userService.DeleteUser(&createdUser.UserID)
+		assert.ErrorIs(t, err, models.ErrNotFound)
 	})
 
-	// Scan the entire table after tests
-	afterScan, err := utils.ScanEntireTable(db, cfg.TableName)
-	if err != nil {
-		t.Fatalf("Failed to scan table after tests: %v", err)
+	// Test DeleteUser removes the user's stats and leaderboard entries and
+	// anonymizes their matches
+	t.Run("DeleteUserCascades", func(t *testing.T) {
+		dayScope, err := models.NewPeriodScope(models.PeriodDay, "2023-06-01")
+		assert.NoError(t, err)
+		dayStat := &models.GameStat{UserID: "user3", GameID: "soccer", GameAttributes: models.AttributesStatsMap{"elo": 1}}
+		assert.NoError(t, gameStatRepo.WithScope(dayScope).UpdateGameStat(dayStat, nil))
+		assert.NoError(t, leaderboardRepo.WithScope(dayScope).AddLeaderboardItem("soccer", "user3", "elo", 1, nil))
+
+		userID := models.UserID("user3")
+		assert.NoError(t, userService.DeleteUser(&userID))
+
+		_, err = userService.GetUser(userID)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		for _, scope := range []models.LeaderboardScope{models.AllTimeScope, dayScope} {
+			for _, gameID := range []models.GameID{"soccer", "pool"} {
+				_, err = userService.GetGameStat(userID, gameID, scope)
+				assert.ErrorIs(t, err, models.ErrNotFound, "%s in scope %q", gameID, scope)
+
+				leaderboard, err := leaderboardRepo.WithScope(scope).GetLeaderboard(gameID, "elo", models.SortDescending)
+				assert.NoError(t, err)
+				for _, entry := range leaderboard.Entries {
+					assert.NotEqual(t, userID, entry.UserID)
+				}
+			}
+		}
+
+		// The match keeps its result for the other players
+		match, err := matchRepo.GetMatch("soccer", "match1", "2023-06-01")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"user1", "user2"}, {"~deleted-1", "dianadancer"}}, match.TeamMembers)
+		assert.NotContains(t, match.PlayerAttributesMap, userID)
+		assert.Contains(t, match.PlayerAttributesMap, models.UserID("dianadancer"))
+
+		history, err := userService.GetUserMatches(userID, models.UserMatchFilter{}, 10, "")
+		assert.NoError(t, err)
+		assert.Empty(t, history.Matches)
+		history, err = userService.GetUserMatches("dianadancer", models.UserMatchFilter{GameID: "soccer"}, 10, "")
+		assert.NoError(t, err)
+		assert.Len(t, history.Matches, 1)
+
+		// Other players' stats are untouched
+		gameStat, err := userService.GetGameStat("dianadancer", "soccer", models.AllTimeScope)
+		assert.NoError(t, err)
+		assert.Equal(t, models.AttributeStat(22), gameStat.GameAttributes["passes_completed"])
+	})
+
+	// Test a DeleteUser that fails part way deletes everything when retried
+	t.Run("DeleteUserRetry", func(t *testing.T) {
+		newUser, err := models.NewUser("retryuser", "RetryUser", "retry@example.com", []models.GameID{})
+		assert.NoError(t, err)
+		_, err = userService.CreateUser(newUser)
+		assert.NoError(t, err)
+
+		dates := []models.DateID{"2024-01-10", "2024-03-20"}
+		for i, date := range dates {
+			match, err := models.NewMatch(models.MatchID(fmt.Sprintf("retry%d", i)), date, "soccer", []string{"Team A", "Team B"}, []int{1, 0}, [][]string{{"retryuser"}, {"user1"}}, map[models.UserID]models.AttributesStatsMap{
+				"retryuser": {"elo": 2, "goals": 1},
+				"user1":     {"elo": 1},
+			})
+			assert.NoError(t, err)
+			_, err = matchService.CreateMatch(match)
+			assert.NoError(t, err)
+		}
+		scopes := []models.LeaderboardScope{models.AllTimeScope}
+		for _, date := range dates {
+			periodScopes, err := models.PeriodScopes(date)
+			assert.NoError(t, err)
+			scopes = append(scopes, periodScopes...)
+		}
+		for _, scope := range scopes {
+			_, err = userService.GetGameStat("retryuser", "soccer", scope)
+			assert.NoError(t, err, "scope %q", scope)
+		}
+
+		// Anonymizing the second match fails
+		failingService := services.NewUserServiceImpl(userRepo, gameStatRepo, &failingMatchRepository{MatchRepository: matchRepo, updates: 1}, gameRepo, leaderboardRepo, seasonRepo, ratingRepo, transactionRepo)
+		userID := models.UserID("retryuser")
+		assert.Error(t, failingService.DeleteUser(&userID))
+		_, err = userService.GetUser(userID)
+		assert.NoError(t, err)
+
+		assert.NoError(t, userService.DeleteUser(&userID))
+		_, err = userService.GetUser(userID)
+		assert.ErrorIs(t, err, models.ErrNotFound)
+		for _, scope := range scopes {
+			_, err = userService.GetGameStat(userID, "soccer", scope)
+			assert.ErrorIs(t, err, models.ErrNotFound, "scope %q", scope)
+			for _, attr := range []models.AttributeName{"elo", "goals"} {
+				leaderboard, err := leaderboardRepo.WithScope(scope).GetLeaderboard("soccer", attr, models.SortDescending)
+				assert.NoError(t, err)
+				for _, entry := range leaderboard.Entries {
+					assert.NotEqual(t, userID, entry.UserID, "%s in scope %q", attr, scope)
+				}
+			}
+		}
+		for i, date := range dates {
+			match, err := matchRepo.GetMatch("soccer", models.MatchID(fmt.Sprintf("retry%d", i)), date)
+			assert.NoError(t, err)
+			assert.NotContains(t, match.PlayerAttributesMap, userID)
+		}
+	})
+}
+
+// failingMatchRepository fails every UpdateMatch after the first updates.
+type failingMatchRepository struct {
+	repositories.MatchRepository
+	updates int
+}
+
+func (r *failingMatchRepository) UpdateMatch(match *models.Match, tx *dynamodb.TransactWriteItemsInput) (*models.Match, error) {
+	if r.updates == 0 {
+		return nil, errors.New("connection reset")
 	}
+	r.updates--
+	return r.MatchRepository.UpdateMatch(match, tx)
+}
 
-	// Compare before and after scans
-	if !t.Failed() {
-		assert.Equal(t, beforeScan, afterScan, "The database state has changed after running tests")
+// racingUserRepository adds gameID to the GamesPlayed of the user read by each
+// of the first races GetUser calls right after reading them.
+type racingUserRepository struct {
+	repositories.UserRepository
+	gameID models.GameID
+	races  int
+}
+
+func (r *racingUserRepository) GetUser(id models.UserID) (*models.User, error) {
+	user, err := r.UserRepository.GetUser(id)
+	if err != nil || r.races == 0 {
+		return user, err
+	}
+	r.races--
+	if err := r.UserRepository.AddGamePlayed(id, r.gameID, nil); err != nil {
+		return nil, err
 	}
+	return user, nil
 }